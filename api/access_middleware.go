@@ -62,13 +62,13 @@ func (m *AccessMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	accessToken := r.Header.Get("Authorization")
 	accessToken = strings.TrimPrefix(accessToken, "Bearer ")
 
-	token, err := extensions.Token(accessToken, m.App.DB)
+	token, err := extensions.Token(r.Context(), accessToken, m.App.DB)
 	if err != nil {
 		m.App.HandleError(w, http.StatusUnauthorized, "", err)
 		return
 	}
 
-	msg, status, err := extensions.Authenticate(token, &models.OSCredentials{}, m.App.DB)
+	msg, status, err := extensions.Authenticate(r.Context(), token, &models.OSCredentials{}, m.App.DB)
 	if err != nil {
 		logger.WithError(err).Error("error fetching googleapis")
 		m.App.HandleError(w, http.StatusInternalServerError, "Error fetching googleapis", err)
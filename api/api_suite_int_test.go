@@ -13,6 +13,7 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/spf13/viper"
 	"github.com/topfreegames/mystack-controller/api"
+	"github.com/topfreegames/mystack-controller/models"
 	oTesting "github.com/topfreegames/mystack-controller/testing"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
@@ -26,7 +27,7 @@ import (
 var clientset kubernetes.Interface
 var app *api.App
 var conn *sqlx.DB
-var db *sqlx.Tx
+var db *oTesting.TxDB
 var config *viper.Viper
 
 func TestApi(t *testing.T) {
@@ -56,8 +57,9 @@ var _ = BeforeEach(func() {
 	var err error
 	clientset = fake.NewSimpleClientset()
 	app.Clientset = clientset
-	db, err = conn.Beginx()
+	tx, err := conn.Beginx()
 	Expect(err).NotTo(HaveOccurred())
+	db = &oTesting.TxDB{Tx: tx}
 	app.DB = db
 })
 
@@ -65,7 +67,7 @@ var _ = AfterEach(func() {
 	err := db.Rollback()
 	Expect(err).NotTo(HaveOccurred())
 	db = nil
-	app.DB = conn
+	app.DB = models.NewDB(conn)
 })
 
 var _ = AfterSuite(func() {
@@ -11,23 +11,23 @@ package api_test
 import (
 	"fmt"
 	"github.com/topfreegames/mystack-controller/api"
+	"github.com/topfreegames/mystack-controller/models"
 	mTest "github.com/topfreegames/mystack-controller/testing"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
-	"database/sql"
 	"github.com/Sirupsen/logrus"
-	"github.com/jmoiron/sqlx"
 	"github.com/spf13/viper"
 	"gopkg.in/DATA-DOG/go-sqlmock.v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	"testing"
+	"time"
 )
 
 var app *api.App
-var db *sql.DB
+var db *mTest.MockDB
 var mock sqlmock.Sqlmock
 var config *viper.Viper
 var clientset kubernetes.Interface
@@ -53,10 +53,12 @@ var _ = BeforeSuite(func() {
 		Debug:               false,
 		Logger:              l,
 		EmailDomain:         config.GetStringSlice("oauth.acceptedDomains"),
+		AdminEmails:         config.GetStringSlice("admin.emails"),
 		Clientset:           clientset,
 		DeploymentReadiness: &mTest.MockReadiness{},
 		JobReadiness:        &mTest.MockReadiness{},
 		K8sDomain:           "mystack.com",
+		Progress:            models.NewProgressHub(),
 	}
 	app.ConfigureServer()
 })
@@ -65,9 +67,9 @@ var _ = BeforeEach(func() {
 	var err error
 	clientset = fake.NewSimpleClientset()
 	app.Clientset = clientset
-	db, mock, err = sqlmock.New()
+	db, mock, err = mTest.NewMockDB()
 	Expect(err).NotTo(HaveOccurred())
-	app.DB = sqlx.NewDb(db, "postgres")
+	app.DB = db
 })
 
 var _ = AfterEach(func() {
@@ -75,3 +77,27 @@ var _ = AfterEach(func() {
 	err := mock.ExpectationsWereMet()
 	Expect(err).NotTo(HaveOccurred())
 })
+
+//expectStack mocks the stack saved when the cluster was created with yamlStr
+func expectStack(yamlStr string) {
+	expectStackWithState(yamlStr, "running")
+}
+
+//expectStackWithState mocks the stack saved with the cluster, on state
+func expectStackWithState(yamlStr, state string) {
+	mock.
+		ExpectQuery("^SELECT (.+) FROM stacks WHERE (.+)$").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "owner_email", "username", "namespace", "cluster_name", "yaml", "state", "created_at", "updated_at",
+		}).AddRow(
+			"0b1a4c1e-6c4e-4f4b-a6c7-6f0e8d3b2a10", "user@example.com", "user", "mystack-user-mycustomapps-a62c7ed6aa",
+			"myCustomApps", yamlStr, state, time.Now(), time.Now(),
+		))
+}
+
+//expectNoStack mocks a cluster created before stacks were saved
+func expectNoStack() {
+	mock.
+		ExpectQuery("^SELECT (.+) FROM stacks WHERE (.+)$").
+		WillReturnError(fmt.Errorf("sql: no rows in result set"))
+}
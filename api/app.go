@@ -35,10 +35,13 @@ type App struct {
 	Router              *mux.Router
 	Server              *http.Server
 	EmailDomain         []string
+	AdminEmails         []string
 	K8sDomain           string
 	Clientset           kubernetes.Interface
 	DeploymentReadiness models.Readiness
 	JobReadiness        models.Readiness
+	Executor            models.Executor
+	Progress            *models.ProgressHub
 }
 
 //NewApp ctor
@@ -50,17 +53,24 @@ func NewApp(
 	logger logrus.FieldLogger,
 	clientset kubernetes.Interface,
 ) (*App, error) {
+	deploymentReadiness, jobReadiness, err := models.NewReadiness(config)
+	if err != nil {
+		return nil, err
+	}
+
 	a := &App{
 		Config:              config,
 		Address:             fmt.Sprintf("%s:%d", host, port),
 		Debug:               debug,
 		Logger:              logger,
 		EmailDomain:         config.GetStringSlice("oauth.acceptedDomains"),
+		AdminEmails:         config.GetStringSlice("admin.emails"),
 		Clientset:           clientset,
-		DeploymentReadiness: &models.DeploymentReadiness{},
-		JobReadiness:        &models.JobReadiness{},
+		DeploymentReadiness: deploymentReadiness,
+		JobReadiness:        jobReadiness,
+		Progress:            models.NewProgressHub(),
 	}
-	err := a.configureApp()
+	err = a.configureApp()
 	if err != nil {
 		return nil, err
 	}
@@ -88,6 +98,13 @@ func (a *App) getRouter() *mux.Router {
 		&VersionMiddleware{},
 	)).Methods("GET").Name("oauth")
 
+	r.Handle("/clusters", Chain(
+		&ClusterHandler{App: a, Method: "list"},
+		&LoggingMiddleware{App: a},
+		&VersionMiddleware{},
+		NewAccessMiddleware(a),
+	)).Methods("GET").Name("clusters")
+
 	r.Handle("/clusters/{name}/create", Chain(
 		&ClusterHandler{App: a, Method: "create"},
 		&LoggingMiddleware{App: a},
@@ -102,6 +119,48 @@ func (a *App) getRouter() *mux.Router {
 		NewAccessMiddleware(a),
 	)).Methods("DELETE").Name("cluster")
 
+	r.Handle("/clusters/{name}/update", Chain(
+		&ClusterHandler{App: a, Method: "update"},
+		&LoggingMiddleware{App: a},
+		&VersionMiddleware{},
+		NewAccessMiddleware(a),
+	)).Methods("PUT").Name("cluster")
+
+	r.Handle("/clusters/{name}/extend", Chain(
+		&ClusterHandler{App: a, Method: "extend"},
+		&LoggingMiddleware{App: a},
+		&VersionMiddleware{},
+		NewAccessMiddleware(a),
+	)).Methods("PUT").Name("cluster")
+
+	r.Handle("/clusters/{name}/sleep", Chain(
+		&ClusterHandler{App: a, Method: "sleep"},
+		&LoggingMiddleware{App: a},
+		&VersionMiddleware{},
+		NewAccessMiddleware(a),
+	)).Methods("POST").Name("cluster")
+
+	r.Handle("/clusters/{name}/wake", Chain(
+		&ClusterHandler{App: a, Method: "wake"},
+		&LoggingMiddleware{App: a},
+		&VersionMiddleware{},
+		NewAccessMiddleware(a),
+	)).Methods("POST").Name("cluster")
+
+	r.Handle("/clusters/{name}/resume", Chain(
+		&ClusterHandler{App: a, Method: "resume"},
+		&LoggingMiddleware{App: a},
+		&VersionMiddleware{},
+		NewAccessMiddleware(a),
+	)).Methods("POST").Name("cluster")
+
+	r.Handle("/clusters/{name}/events", Chain(
+		&ClusterHandler{App: a, Method: "events"},
+		&LoggingMiddleware{App: a},
+		&VersionMiddleware{},
+		NewAccessMiddleware(a),
+	)).Methods("GET").Name("cluster")
+
 	r.Handle("/clusters/{name}/apps", Chain(
 		&ClusterHandler{App: a, Method: "apps"},
 		&LoggingMiddleware{App: a},
@@ -109,6 +168,48 @@ func (a *App) getRouter() *mux.Router {
 		NewAccessMiddleware(a),
 	)).Methods("GET").Name("cluster")
 
+	r.Handle("/clusters/{name}/apps/{app}/restart", Chain(
+		&ClusterAppHandler{App: a, Method: "restart"},
+		&LoggingMiddleware{App: a},
+		&VersionMiddleware{},
+		NewAccessMiddleware(a),
+	)).Methods("PUT").Name("cluster-app")
+
+	r.Handle("/clusters/{name}/apps/{app}/image", Chain(
+		&ClusterAppHandler{App: a, Method: "image"},
+		&LoggingMiddleware{App: a},
+		&VersionMiddleware{},
+		NewAccessMiddleware(a),
+	)).Methods("PUT").Name("cluster-app")
+
+	r.Handle("/clusters/{name}/apps/{app}/scale", Chain(
+		&ClusterAppHandler{App: a, Method: "scale"},
+		&LoggingMiddleware{App: a},
+		&VersionMiddleware{},
+		NewAccessMiddleware(a),
+	)).Methods("PUT").Name("cluster-app")
+
+	r.Handle("/clusters/{name}/apps/{app}/logs", Chain(
+		&ClusterAppHandler{App: a, Method: "logs"},
+		&LoggingMiddleware{App: a},
+		&VersionMiddleware{},
+		NewAccessMiddleware(a),
+	)).Methods("GET").Name("cluster-app")
+
+	r.Handle("/clusters/{name}/apps/{app}/exec", Chain(
+		&ClusterAppHandler{App: a, Method: "exec"},
+		&LoggingMiddleware{App: a},
+		&VersionMiddleware{},
+		NewAccessMiddleware(a),
+	)).Methods("GET").Name("cluster-app")
+
+	r.Handle("/clusters/{name}/jobs/{job}/logs", Chain(
+		&ClusterJobHandler{App: a, Method: "logs"},
+		&LoggingMiddleware{App: a},
+		&VersionMiddleware{},
+		NewAccessMiddleware(a),
+	)).Methods("GET").Name("cluster-job")
+
 	r.Handle("/clusters/{name}/services", Chain(
 		&ClusterHandler{App: a, Method: "services"},
 		&LoggingMiddleware{App: a},
@@ -116,6 +217,35 @@ func (a *App) getRouter() *mux.Router {
 		NewAccessMiddleware(a),
 	)).Methods("GET").Name("cluster")
 
+	r.Handle("/clusters/{name}/status", Chain(
+		&ClusterHandler{App: a, Method: "status"},
+		&LoggingMiddleware{App: a},
+		&VersionMiddleware{},
+		NewAccessMiddleware(a),
+	)).Methods("GET").Name("cluster")
+
+	r.Handle("/stacks", Chain(
+		&StackHandler{App: a},
+		&LoggingMiddleware{App: a},
+		&VersionMiddleware{},
+		NewAccessMiddleware(a),
+	)).Methods("GET").Name("stacks")
+
+	r.Handle("/operations/{id}", Chain(
+		&OperationHandler{App: a},
+		&LoggingMiddleware{App: a},
+		&VersionMiddleware{},
+		NewAccessMiddleware(a),
+	)).Methods("GET").Name("operation")
+
+	r.Handle("/cluster-configs/validate", Chain(
+		&ClusterConfigHandler{App: a, Method: "validate"},
+		&VersionMiddleware{},
+		&LoggingMiddleware{App: a},
+		NewAccessMiddleware(a),
+		&PayloadMiddleware{App: a},
+	)).Methods("POST").Name("cluster-config")
+
 	r.Handle("/cluster-configs/{name}/create", Chain(
 		&ClusterConfigHandler{App: a, Method: "create"},
 		&VersionMiddleware{},
@@ -139,6 +269,41 @@ func (a *App) getRouter() *mux.Router {
 		&PayloadMiddleware{App: a},
 	)).Methods("PUT").Name("cluster-config")
 
+	r.Handle("/cluster-configs/{name}/revisions", Chain(
+		&ClusterConfigHandler{App: a, Method: "revisions"},
+		&LoggingMiddleware{App: a},
+		&VersionMiddleware{},
+		NewAccessMiddleware(a),
+	)).Methods("GET").Name("cluster-config")
+
+	r.Handle("/cluster-configs/{name}/revisions/{revision}", Chain(
+		&ClusterConfigHandler{App: a, Method: "revision"},
+		&LoggingMiddleware{App: a},
+		&VersionMiddleware{},
+		NewAccessMiddleware(a),
+	)).Methods("GET").Name("cluster-config")
+
+	r.Handle("/cluster-configs/{name}/revisions/{revision}/rollback", Chain(
+		&ClusterConfigHandler{App: a, Method: "rollback"},
+		&LoggingMiddleware{App: a},
+		&VersionMiddleware{},
+		NewAccessMiddleware(a),
+	)).Methods("PUT").Name("cluster-config")
+
+	r.Handle("/cluster-configs/{name}/diff", Chain(
+		&ClusterConfigHandler{App: a, Method: "diff"},
+		&LoggingMiddleware{App: a},
+		&VersionMiddleware{},
+		NewAccessMiddleware(a),
+	)).Methods("GET").Name("cluster-config")
+
+	r.Handle("/cluster-configs/{name}/render", Chain(
+		&ClusterConfigHandler{App: a, Method: "render"},
+		&LoggingMiddleware{App: a},
+		&VersionMiddleware{},
+		NewAccessMiddleware(a),
+	)).Methods("GET").Name("cluster-config")
+
 	r.Handle("/cluster-configs", Chain(
 		&ClusterConfigHandler{App: a, Method: "list"},
 		&LoggingMiddleware{App: a},
@@ -182,7 +347,7 @@ func (a *App) configureDatabase() error {
 		return err
 	}
 
-	a.DB = db
+	a.DB = models.NewDB(db)
 	return nil
 }
 
@@ -259,6 +424,16 @@ func (a *App) ListenAndServe() (io.Closer, error) {
 	)
 	go a.listenTCP(port)
 
+	interval := a.Config.GetDuration("kubernetes.stacks.reaper-interval")
+	if interval > 0 {
+		go a.runReaper(interval)
+	}
+
+	interval = a.Config.GetDuration("kubernetes.stacks.schedule-interval")
+	if interval > 0 {
+		go a.runScheduler(interval)
+	}
+
 	listener, err := net.Listen("tcp", a.Address)
 	if err != nil {
 		return nil, err
@@ -273,6 +448,15 @@ func (a *App) ListenAndServe() (io.Closer, error) {
 	return listener, nil
 }
 
+func (a *App) isAdmin(email string) bool {
+	for _, admin := range a.AdminEmails {
+		if email == admin {
+			return true
+		}
+	}
+	return false
+}
+
 func (a *App) verifyEmailDomain(email string) bool {
 	l := a.Logger.WithField("acceptedDomain", a.EmailDomain)
 	l.Debugf("verifying email domain")
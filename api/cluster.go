@@ -8,11 +8,20 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/Sirupsen/logrus"
+	"github.com/topfreegames/mystack-controller/errors"
 	"github.com/topfreegames/mystack-controller/models"
+	"k8s.io/client-go/kubernetes"
 )
 
 //ClusterHandler handles cluster creation and deletion
@@ -31,6 +40,22 @@ func (c *ClusterHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		c.getApps(w, r)
 	case "services":
 		c.getServices(w, r)
+	case "status":
+		c.getStatus(w, r)
+	case "update":
+		c.update(w, r)
+	case "list":
+		c.list(w, r)
+	case "extend":
+		c.extend(w, r)
+	case "sleep":
+		c.sleep(w, r)
+	case "wake":
+		c.wake(w, r)
+	case "resume":
+		c.resume(w, r)
+	case "events":
+		c.events(w, r)
 	}
 }
 
@@ -42,41 +67,282 @@ func (c *ClusterHandler) create(w http.ResponseWriter, r *http.Request) {
 	log(logger, "Creating cluster for user %s", username)
 	clusterName := GetClusterName(r)
 
-	cluster, err := models.NewCluster(
-		c.App.DB,
-		username,
-		clusterName,
-		c.App.DeploymentReadiness,
-		c.App.JobReadiness,
-		c.App.Config,
-	)
+	options, err := readClusterOptions(r)
+	if err != nil {
+		c.App.HandleError(w, http.StatusBadRequest, "error reading body", err)
+		return
+	}
+
+	stack, err := c.stackToResume(email, clusterName)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "create cluster error", err)
+		return
+	}
+
+	var cluster *models.Cluster
+	if stack != nil {
+		log(logger, "Cluster already exists, resuming its creation")
+		if len(options.Overrides) > 0 && !reflect.DeepEqual(options.Overrides, stack.Overrides) {
+			err := errors.NewGenericError(
+				"create cluster error",
+				fmt.Errorf("cluster '%s' was created with other overrides", clusterName),
+			)
+			c.App.HandleError(w, Status(err), "create cluster error", err)
+			return
+		}
+		if len(options.Parameters) > 0 && !reflect.DeepEqual(options.Parameters, stack.Parameters) {
+			err := errors.NewGenericError(
+				"create cluster error",
+				fmt.Errorf("cluster '%s' was created with other parameters", clusterName),
+			)
+			c.App.HandleError(w, Status(err), "create cluster error", err)
+			return
+		}
+		cluster, err = models.NewClusterFromStack(
+			stack,
+			c.App.DeploymentReadiness,
+			c.App.JobReadiness,
+			c.App.Config,
+		)
+	} else {
+		cluster, err = models.NewClusterWithOptions(
+			c.App.DB,
+			username,
+			clusterName,
+			options,
+			c.App.DeploymentReadiness,
+			c.App.JobReadiness,
+			c.App.Config,
+		)
+	}
 	if err != nil {
 		c.App.HandleError(w, Status(err), "create cluster error", err)
 		return
 	}
 	log(logger, "Created cluster %#v", cluster)
 
-	err = cluster.Create(c.App.Logger, c.App.Clientset)
+	if keepOnFailure := r.URL.Query().Get("keepOnFailure"); len(keepOnFailure) > 0 {
+		cluster.KeepOnFailure, err = strconv.ParseBool(keepOnFailure)
+		if err != nil {
+			err := errors.NewGenericError(
+				"create cluster error",
+				fmt.Errorf("invalid keepOnFailure: %s", keepOnFailure),
+			)
+			c.App.HandleError(w, Status(err), "create cluster error", err)
+			return
+		}
+	}
+
+	err = cluster.Claim(c.App.Clientset)
 	if err != nil {
 		c.App.HandleError(w, Status(err), "create cluster error", err)
 		return
 	}
 
-	domains, err := cluster.Apps(c.App.Config, c.App.Clientset, c.App.K8sDomain)
+	if stack != nil {
+		err = stack.SetState(models.StackCreating)
+	} else {
+		stack, err = models.NewStack(c.App.DB, email, cluster)
+	}
 	if err != nil {
-		c.App.HandleError(w, Status(err), "get apps error", err)
+		c.releaseClaim(logger, cluster)
+		c.App.HandleError(w, Status(err), "create cluster error", err)
+		return
+	}
+
+	operation, err := models.NewOperation(c.App.DB, models.OperationCreate, clusterName, username)
+	if err != nil {
+		c.releaseClaim(logger, cluster)
+		c.App.HandleError(w, Status(err), "create cluster error", err)
 		return
 	}
+	cluster.Tracker = operation
+	cluster.Progress = c.App.Progress.Start(cluster.Namespace, models.OperationCreate)
+
+	//operation is updated by the creation from now on
+	operationID, status := operation.ID, operation.Status
+	go runCreate(logger, c.App.Clientset, cluster, operation, stack)
 
-	response := map[string]map[string][]string{"domains": domains}
+	response := map[string]string{
+		"operationId": operationID,
+		"status":      status,
+	}
 	bts, err := json.Marshal(&response)
 	if err != nil {
 		c.App.HandleError(w, Status(err), "create cluster error", err)
 		return
 	}
 
-	WriteBytes(w, http.StatusOK, bts)
-	log(logger, "Cluster successfully created for user %s", username)
+	WriteBytes(w, http.StatusAccepted, bts)
+	log(logger, "Cluster creation started for user %s", username)
+}
+
+//readClusterOptions returns the options of the create request body,
+//which has the app overrides and the parameter values
+func readClusterOptions(r *http.Request) (*models.ClusterOptions, error) {
+	options := &models.ClusterOptions{}
+	if r.Body == nil {
+		return options, nil
+	}
+
+	bts, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(bts)) == 0 {
+		return options, nil
+	}
+
+	err = json.Unmarshal(bts, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return options, nil
+}
+
+//stackToResume returns the stack of a cluster whose namespace already exists,
+//so creating it again completes what is missing with the config it started with
+//It is nil if the namespace doesn't exist or was created before stacks were saved
+//Namespaces and stacks of someone else are never resumed
+func (c *ClusterHandler) stackToResume(email, clusterName string) (*models.Stack, error) {
+	username := usernameFromEmail(email)
+	namespace := models.ClusterNamespace(username, clusterName)
+	if !models.NamespaceExists(c.App.Clientset, namespace) {
+		return nil, nil
+	}
+
+	err := models.CheckNamespaceOwner(c.App.Clientset, namespace, username, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	stack, err := loadStack(c.App.DB, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	err = stack.CheckOwner(email)
+	if err != nil {
+		return nil, err
+	}
+
+	return stack, nil
+}
+
+//releaseClaim gives up the creation lease of a cluster whose creation won't start
+func (c *ClusterHandler) releaseClaim(logger logrus.FieldLogger, cluster *models.Cluster) {
+	err := cluster.ReleaseClaim(c.App.Clientset)
+	if err != nil && logger != nil {
+		logger.WithError(err).Errorf("failed to release creation lease of %s", cluster.Namespace)
+	}
+}
+
+//deployedCluster returns the deployed cluster clusterName of the user with email
+//It fails if the namespace or the stack of the cluster belong to someone else
+func (a *App) deployedCluster(
+	email, clusterName string,
+	deploymentReadiness, jobReadiness models.Readiness,
+) (*models.Cluster, error) {
+	cluster, err := models.NewDeployedCluster(
+		a.DB,
+		usernameFromEmail(email),
+		clusterName,
+		deploymentReadiness,
+		jobReadiness,
+		a.Config,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cluster.CheckOwner(a.Clientset, email)
+	if err != nil {
+		return nil, err
+	}
+
+	return cluster, nil
+}
+
+//namespaceOnlyCluster is used to delete clusters whose config can't be loaded anymore
+func namespaceOnlyCluster(username, clusterName string) *models.Cluster {
+	return &models.Cluster{
+		Username:    username,
+		ClusterName: clusterName,
+		Namespace:   models.ClusterNamespace(username, clusterName),
+	}
+}
+
+//loadStack returns the stack running on namespace
+//It is nil for clusters created before stacks were saved
+func loadStack(db models.DB, namespace string) (*models.Stack, error) {
+	stack, err := models.LoadStack(db, namespace)
+	if err != nil && strings.Contains(err.Error(), "no rows in result set") {
+		return nil, nil
+	}
+
+	return stack, err
+}
+
+//runCreate creates the cluster in background and saves the operation result
+func runCreate(
+	logger logrus.FieldLogger,
+	clientset kubernetes.Interface,
+	cluster *models.Cluster,
+	operation *models.Operation,
+	stack *models.Stack,
+) {
+	err := cluster.Create(logger, clientset)
+	if err != nil && logger != nil {
+		logger.WithError(err).Errorf("failed to create cluster for user %s", cluster.Username)
+	}
+
+	finishCreation(logger, cluster, operation, stack, err)
+}
+
+//runResume resumes a failed creation in background and saves the operation result
+func runResume(
+	logger logrus.FieldLogger,
+	clientset kubernetes.Interface,
+	cluster *models.Cluster,
+	operation *models.Operation,
+	stack *models.Stack,
+) {
+	err := cluster.Resume(logger, clientset)
+	if err != nil && logger != nil {
+		logger.WithError(err).Errorf("failed to resume cluster for user %s", cluster.Username)
+	}
+
+	finishCreation(logger, cluster, operation, stack, err)
+}
+
+//finishCreation saves the result of creating the cluster on its operation and stack
+func finishCreation(
+	logger logrus.FieldLogger,
+	cluster *models.Cluster,
+	operation *models.Operation,
+	stack *models.Stack,
+	err error,
+) {
+	cluster.Progress.Done(err)
+
+	finishErr := operation.Finish(err)
+	if finishErr != nil && logger != nil {
+		logger.WithError(finishErr).Errorf("failed to save operation %s", operation.ID)
+	}
+
+	state := models.StackRunning
+	if err != nil {
+		state = models.StackFailed
+	}
+	stateErr := stack.SetState(state)
+	if stateErr != nil && logger != nil {
+		logger.WithError(stateErr).Errorf("failed to save stack %s", stack.ID)
+	}
+
+	if err == nil {
+		log(logger, "Cluster successfully created for user %s", cluster.Username)
+	}
 }
 
 func (c *ClusterHandler) deleteCluster(w http.ResponseWriter, r *http.Request) {
@@ -87,22 +353,30 @@ func (c *ClusterHandler) deleteCluster(w http.ResponseWriter, r *http.Request) {
 	log(logger, "Deleting cluster for user %s", username)
 	clusterName := GetClusterName(r)
 
-	cluster, err := models.NewCluster(
-		c.App.DB,
-		username,
+	cluster, err := c.App.deployedCluster(
+		email,
 		clusterName,
 		c.App.DeploymentReadiness,
 		c.App.JobReadiness,
-		c.App.Config,
 	)
 	if err != nil && strings.Contains(err.Error(), "no rows in result set") {
-		cluster = &models.Cluster{Username: username}
-	} else if err != nil {
+		cluster = namespaceOnlyCluster(username, clusterName)
+		err = cluster.CheckOwner(c.App.Clientset, email)
+	}
+	if err != nil {
 		c.App.HandleError(w, Status(err), "retrieve cluster error", err)
 		return
 	}
 
+	cluster.Progress = c.App.Progress.Start(cluster.Namespace, models.OperationDelete)
 	err = cluster.Delete(c.App.Clientset)
+	cluster.Progress.Done(err)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "delete cluster error", err)
+		return
+	}
+
+	err = models.RemoveStack(c.App.DB, cluster.Namespace)
 	if err != nil {
 		c.App.HandleError(w, Status(err), "delete cluster error", err)
 		return
@@ -120,20 +394,19 @@ func (c *ClusterHandler) getApps(w http.ResponseWriter, r *http.Request) {
 	log(logger, "Cluster apps for user %s", username)
 	clusterName := GetClusterName(r)
 
-	cluster, err := models.NewCluster(c.App.DB, username, clusterName, nil, nil, c.App.Config)
+	cluster, err := c.App.deployedCluster(email, clusterName, nil, nil)
 	if err != nil {
 		c.App.HandleError(w, Status(err), "get apps error", err)
 		return
 	}
 
-	domains, err := cluster.Apps(c.App.Config, c.App.Clientset, c.App.K8sDomain)
+	apps, err := cluster.Apps(c.App.Config, c.App.Clientset, c.App.K8sDomain)
 	if err != nil {
 		c.App.HandleError(w, Status(err), "get apps error", err)
 		return
 	}
 
-	response := map[string]map[string][]string{"domains": domains}
-	bts, err := json.Marshal(response)
+	bts, err := json.Marshal(apps)
 	if err != nil {
 		c.App.HandleError(w, Status(err), "get apps error", err)
 		return
@@ -151,7 +424,7 @@ func (c *ClusterHandler) getServices(w http.ResponseWriter, r *http.Request) {
 	log(logger, "Cluster services for user %s", username)
 	clusterName := GetClusterName(r)
 
-	cluster, err := models.NewCluster(c.App.DB, username, clusterName, nil, nil, c.App.Config)
+	cluster, err := c.App.deployedCluster(email, clusterName, nil, nil)
 	if err != nil {
 		c.App.HandleError(w, Status(err), "get services error", err)
 		return
@@ -173,3 +446,335 @@ func (c *ClusterHandler) getServices(w http.ResponseWriter, r *http.Request) {
 	WriteBytes(w, http.StatusOK, bts)
 	log(logger, "Cluster services gotten for user %s", username)
 }
+
+func (c *ClusterHandler) getStatus(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	email := emailFromCtx(r.Context())
+	username := usernameFromEmail(email)
+
+	log(logger, "Cluster status for user %s", username)
+	clusterName := GetClusterName(r)
+
+	cluster, err := c.App.deployedCluster(email, clusterName, nil, nil)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "get status error", err)
+		return
+	}
+
+	status, err := cluster.Status(c.App.Clientset)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "get status error", err)
+		return
+	}
+
+	bts, err := json.Marshal(status)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "get status error", err)
+		return
+	}
+
+	WriteBytes(w, http.StatusOK, bts)
+	log(logger, "Cluster status gotten for user %s", username)
+}
+
+func (c *ClusterHandler) update(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	email := emailFromCtx(r.Context())
+	username := usernameFromEmail(email)
+
+	log(logger, "Updating cluster for user %s", username)
+	clusterName := GetClusterName(r)
+
+	yamlStr, err := models.ResolvedClusterConfigDetails(r.Context(), c.App.DB, clusterName)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "update cluster error", err)
+		return
+	}
+
+	stack, err := loadStack(c.App.DB, models.ClusterNamespace(username, clusterName))
+	if err != nil {
+		c.App.HandleError(w, Status(err), "update cluster error", err)
+		return
+	}
+	err = stack.CheckOwner(email)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "update cluster error", err)
+		return
+	}
+
+	cluster, err := models.NewClusterFromYamlWithOptions(
+		yamlStr,
+		stack.Options(),
+		username,
+		clusterName,
+		c.App.DeploymentReadiness,
+		c.App.JobReadiness,
+		c.App.Config,
+	)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "update cluster error", err)
+		return
+	}
+
+	err = cluster.CheckOwner(c.App.Clientset, email)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "update cluster error", err)
+		return
+	}
+
+	update, err := cluster.Update(logger, c.App.Clientset)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "update cluster error", err)
+		return
+	}
+
+	if stack != nil {
+		err = stack.SetYaml(cluster.ConfigYaml)
+		if err != nil {
+			c.App.HandleError(w, Status(err), "update cluster error", err)
+			return
+		}
+	}
+
+	bts, err := json.Marshal(update)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "update cluster error", err)
+		return
+	}
+
+	WriteBytes(w, http.StatusOK, bts)
+	log(logger, "Cluster updated for user %s", username)
+}
+
+func (c *ClusterHandler) list(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	email := emailFromCtx(r.Context())
+	username := usernameFromEmail(email)
+
+	log(logger, "Listing clusters for user %s", username)
+
+	clusters, err := models.ListClusters(c.App.Clientset, username)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "list clusters error", err)
+		return
+	}
+
+	response := map[string][]string{"clusters": clusters}
+	bts, err := json.Marshal(response)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "list clusters error", err)
+		return
+	}
+
+	WriteBytes(w, http.StatusOK, bts)
+	log(logger, "Clusters listed for user %s", username)
+}
+
+func (c *ClusterHandler) extend(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	email := emailFromCtx(r.Context())
+	username := usernameFromEmail(email)
+
+	log(logger, "Extending cluster for user %s", username)
+	clusterName := GetClusterName(r)
+
+	var ttl time.Duration
+	bts, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		c.App.HandleError(w, http.StatusBadRequest, "error reading body", err)
+		return
+	}
+	if len(bts) > 0 {
+		body := make(map[string]string)
+		err = json.Unmarshal(bts, &body)
+		if err != nil {
+			c.App.HandleError(w, http.StatusBadRequest, "error reading body", err)
+			return
+		}
+
+		if len(body["ttl"]) > 0 {
+			ttl, err = time.ParseDuration(body["ttl"])
+			if err != nil || ttl <= 0 {
+				err = errors.NewGenericError("extend cluster error", fmt.Errorf("invalid ttl: %s", body["ttl"]))
+				c.App.HandleError(w, Status(err), "extend cluster error", err)
+				return
+			}
+		}
+	}
+
+	cluster, err := c.App.deployedCluster(email, clusterName, nil, nil)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "extend cluster error", err)
+		return
+	}
+
+	expiresAt, err := cluster.Extend(c.App.Clientset, ttl)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "extend cluster error", err)
+		return
+	}
+
+	response := map[string]time.Time{"expiresAt": expiresAt}
+	bts, err = json.Marshal(response)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "extend cluster error", err)
+		return
+	}
+
+	WriteBytes(w, http.StatusOK, bts)
+	log(logger, "Cluster extended for user %s", username)
+}
+
+func (c *ClusterHandler) sleep(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	email := emailFromCtx(r.Context())
+	username := usernameFromEmail(email)
+
+	log(logger, "Putting cluster to sleep for user %s", username)
+	clusterName := GetClusterName(r)
+
+	cluster, err := c.App.deployedCluster(email, clusterName, nil, nil)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "sleep cluster error", err)
+		return
+	}
+
+	err = cluster.Sleep(logger, c.App.Clientset)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "sleep cluster error", err)
+		return
+	}
+
+	Write(w, http.StatusOK, `{"status": "ok"}`)
+	log(logger, "Cluster is sleeping for user %s", username)
+}
+
+func (c *ClusterHandler) wake(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	email := emailFromCtx(r.Context())
+	username := usernameFromEmail(email)
+
+	log(logger, "Waking cluster for user %s", username)
+	clusterName := GetClusterName(r)
+
+	cluster, err := c.App.deployedCluster(
+		email,
+		clusterName,
+		c.App.DeploymentReadiness,
+		c.App.JobReadiness,
+	)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "wake cluster error", err)
+		return
+	}
+
+	err = cluster.Wake(logger, c.App.Clientset)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "wake cluster error", err)
+		return
+	}
+
+	Write(w, http.StatusOK, `{"status": "ok"}`)
+	log(logger, "Cluster is awake for user %s", username)
+}
+
+func (c *ClusterHandler) resume(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	email := emailFromCtx(r.Context())
+	username := usernameFromEmail(email)
+
+	log(logger, "Resuming cluster for user %s", username)
+	clusterName := GetClusterName(r)
+
+	stack, err := models.LoadStack(c.App.DB, models.ClusterNamespace(username, clusterName))
+	if err != nil {
+		c.App.HandleError(w, Status(err), "resume cluster error", err)
+		return
+	}
+
+	err = stack.CheckOwner(email)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "resume cluster error", err)
+		return
+	}
+
+	if stack.State != models.StackFailed {
+		err := errors.NewGenericError(
+			"resume cluster error",
+			fmt.Errorf("cluster '%s' is %s, only failed clusters can be resumed", clusterName, stack.State),
+		)
+		c.App.HandleError(w, Status(err), "resume cluster error", err)
+		return
+	}
+
+	cluster, err := models.NewClusterFromStack(
+		stack,
+		c.App.DeploymentReadiness,
+		c.App.JobReadiness,
+		c.App.Config,
+	)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "resume cluster error", err)
+		return
+	}
+
+	err = cluster.CheckOwner(c.App.Clientset, email)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "resume cluster error", err)
+		return
+	}
+
+	failedStep, err := models.FailedStep(c.App.Clientset, cluster.Namespace)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "resume cluster error", err)
+		return
+	}
+	if len(failedStep) == 0 {
+		err := errors.NewGenericError(
+			"resume cluster error",
+			fmt.Errorf("cluster '%s' has no failed step to resume", clusterName),
+		)
+		c.App.HandleError(w, Status(err), "resume cluster error", err)
+		return
+	}
+
+	err = cluster.Claim(c.App.Clientset)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "resume cluster error", err)
+		return
+	}
+
+	err = stack.SetState(models.StackCreating)
+	if err != nil {
+		c.releaseClaim(logger, cluster)
+		c.App.HandleError(w, Status(err), "resume cluster error", err)
+		return
+	}
+
+	operation, err := models.NewOperation(c.App.DB, models.OperationResume, clusterName, username)
+	if err != nil {
+		c.releaseClaim(logger, cluster)
+		c.App.HandleError(w, Status(err), "resume cluster error", err)
+		return
+	}
+	cluster.Tracker = operation
+	cluster.Progress = c.App.Progress.Start(cluster.Namespace, models.OperationResume)
+
+	//operation is updated by the creation from now on
+	operationID, status := operation.ID, operation.Status
+	go runResume(logger, c.App.Clientset, cluster, operation, stack)
+
+	response := map[string]string{
+		"operationId": operationID,
+		"status":      status,
+		"failedStep":  failedStep,
+	}
+	bts, err := json.Marshal(&response)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "resume cluster error", err)
+		return
+	}
+
+	WriteBytes(w, http.StatusAccepted, bts)
+	log(logger, "Cluster resume started for user %s", username)
+}
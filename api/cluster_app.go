@@ -0,0 +1,192 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/topfreegames/mystack-controller/errors"
+	"github.com/topfreegames/mystack-controller/models"
+)
+
+//ClusterAppHandler handles changes on a single app of a running cluster
+type ClusterAppHandler struct {
+	App    *App
+	Method string
+}
+
+type appChange struct {
+	Image    string `json:"image"`
+	Tag      string `json:"tag"`
+	Replicas *int   `json:"replicas"`
+}
+
+func (c *ClusterAppHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch c.Method {
+	case "logs":
+		c.logs(w, r)
+	case "exec":
+		c.exec(w, r)
+	default:
+		c.change(w, r)
+	}
+}
+
+func (c *ClusterAppHandler) change(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	email := emailFromCtx(r.Context())
+	username := usernameFromEmail(email)
+	clusterName := GetClusterName(r)
+	appName := GetAppName(r)
+
+	log(logger, "Changing app %s of cluster %s for user %s", appName, clusterName, username)
+
+	change := &appChange{}
+	if c.Method != "restart" {
+		err := json.NewDecoder(r.Body).Decode(change)
+		if err != nil {
+			c.App.HandleError(w, http.StatusBadRequest, "error reading body", err)
+			return
+		}
+	}
+
+	cluster, err := c.App.deployedCluster(
+		email,
+		clusterName,
+		c.App.DeploymentReadiness,
+		c.App.JobReadiness,
+	)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "change app error", err)
+		return
+	}
+
+	deployment, err := cluster.Deployment(appName)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "change app error", err)
+		return
+	}
+
+	switch c.Method {
+	case "restart":
+		_, err = deployment.Restart(c.App.Clientset)
+	case "image":
+		image, imageErr := change.image(deployment.Image)
+		if imageErr != nil {
+			c.App.HandleError(w, Status(imageErr), "change app error", imageErr)
+			return
+		}
+		_, err = deployment.SetImage(c.App.Clientset, image)
+	case "scale":
+		if change.Replicas == nil {
+			err = errors.NewGenericError("scale deployment error", fmt.Errorf("replicas is required"))
+			break
+		}
+		_, err = deployment.Scale(c.App.Clientset, *change.Replicas)
+	}
+	if err != nil {
+		c.App.HandleError(w, Status(err), "change app error", err)
+		return
+	}
+
+	log(logger, "Waiting rollout of app %s", appName)
+	err = c.App.DeploymentReadiness.WaitForCompletion(c.App.Clientset, []*models.Deployment{deployment})
+	if err != nil {
+		c.App.HandleError(w, Status(err), "change app error", err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"name":  deployment.Name,
+		"image": deployment.Image,
+	}
+	if c.Method == "scale" {
+		response["replicas"] = deployment.Replicas
+	}
+	bts, err := json.Marshal(response)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "change app error", err)
+		return
+	}
+
+	WriteBytes(w, http.StatusOK, bts)
+	log(logger, "App %s of cluster %s changed for user %s", appName, clusterName, username)
+}
+
+func (c *ClusterAppHandler) logs(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	email := emailFromCtx(r.Context())
+	username := usernameFromEmail(email)
+	clusterName := GetClusterName(r)
+	appName := GetAppName(r)
+
+	log(logger, "Getting logs of app %s of cluster %s for user %s", appName, clusterName, username)
+
+	options, err := logOptions(r)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "get logs error", err)
+		return
+	}
+
+	cluster, err := c.App.deployedCluster(
+		email,
+		clusterName,
+		c.App.DeploymentReadiness,
+		c.App.JobReadiness,
+	)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "get logs error", err)
+		return
+	}
+
+	deployment, err := cluster.Deployment(appName)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "get logs error", err)
+		return
+	}
+
+	writer := &flushWriter{w: w}
+	err = deployment.WriteLogs(r.Context(), c.App.Clientset, options, writer)
+	if err != nil {
+		if !writer.written {
+			c.App.HandleError(w, Status(err), "get logs error", err)
+			return
+		}
+		if logger != nil {
+			logger.WithError(err).Error("failed to stream app logs")
+		}
+		return
+	}
+
+	log(logger, "Finished logs of app %s of cluster %s for user %s", appName, clusterName, username)
+}
+
+//image returns the new image, either informed or the current one with another tag
+func (a *appChange) image(current string) (string, error) {
+	if len(a.Image) > 0 && len(a.Tag) > 0 {
+		return "", errors.NewGenericError("change image error", fmt.Errorf("inform either image or tag"))
+	}
+
+	if len(a.Image) > 0 {
+		return a.Image, nil
+	}
+
+	if len(a.Tag) == 0 {
+		return "", errors.NewGenericError("change image error", fmt.Errorf("image or tag is required"))
+	}
+
+	repository := current
+	if idx := strings.LastIndex(current, ":"); idx > strings.LastIndex(current, "/") {
+		repository = current[:idx]
+	}
+
+	return fmt.Sprintf("%s:%s", repository, a.Tag), nil
+}
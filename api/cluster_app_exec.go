@@ -0,0 +1,172 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/gorilla/websocket"
+	"github.com/topfreegames/mystack-controller/errors"
+	"github.com/topfreegames/mystack-controller/models"
+)
+
+//Clients authenticate with the bearer token, not cookies,
+//so requests from other origins can't use the session of the user
+var execUpgrader = websocket.Upgrader{
+	Subprotocols: []string{models.ExecProtocol},
+	CheckOrigin:  func(r *http.Request) bool { return true },
+}
+
+//execOptions reads command, container and tty from the query string
+//The command runs on the app container by default
+func execOptions(r *http.Request, appName string) (*models.ExecOptions, error) {
+	query := r.URL.Query()
+	options := &models.ExecOptions{
+		Container: appName,
+		Command:   query["command"],
+		TTY:       true,
+	}
+
+	if len(options.Command) == 0 {
+		options.Command = []string{"sh"}
+	}
+
+	if container := query.Get("container"); len(container) > 0 {
+		options.Container = container
+	}
+
+	if tty := query.Get("tty"); len(tty) > 0 {
+		value, err := strconv.ParseBool(tty)
+		if err != nil {
+			return nil, errors.NewGenericError("exec error", fmt.Errorf("invalid tty: %s", tty))
+		}
+		options.TTY = value
+	}
+
+	return options, nil
+}
+
+func (c *ClusterAppHandler) exec(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	email := emailFromCtx(r.Context())
+	username := usernameFromEmail(email)
+	clusterName := GetClusterName(r)
+	appName := GetAppName(r)
+
+	log(logger, "Executing on app %s of cluster %s for user %s", appName, clusterName, username)
+
+	if c.App.Executor == nil {
+		err := errors.NewGenericError("exec error", fmt.Errorf("exec is not enabled"))
+		c.App.HandleError(w, http.StatusNotImplemented, "exec error", err)
+		return
+	}
+
+	options, err := execOptions(r, appName)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "exec error", err)
+		return
+	}
+
+	cluster, err := c.App.deployedCluster(
+		email,
+		clusterName,
+		c.App.DeploymentReadiness,
+		c.App.JobReadiness,
+	)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "exec error", err)
+		return
+	}
+
+	deployment, err := cluster.Deployment(appName)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "exec error", err)
+		return
+	}
+
+	pod, err := deployment.RunningPod(c.App.Clientset)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "exec error", err)
+		return
+	}
+
+	remote, err := c.App.Executor.Exec(pod.Namespace, pod.Name, options)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "exec error", err)
+		return
+	}
+	defer remote.Close()
+
+	client, err := execUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		if logger != nil {
+			logger.WithError(err).Error("failed to upgrade exec connection")
+		}
+		return
+	}
+	defer client.Close()
+
+	log(logger, "Bridging exec on pod %s", pod.Name)
+	bridgeExec(logger, client, remote)
+	log(logger, "Finished exec on app %s of cluster %s for user %s", appName, clusterName, username)
+}
+
+//bridgeExec copies the output of the pod to the client
+//and the stdin and terminal resizes of the client to the pod
+//It returns when either side closes its connection
+func bridgeExec(logger logrus.FieldLogger, client, remote *websocket.Conn) {
+	var once sync.Once
+	done := make(chan struct{})
+	finish := func() {
+		once.Do(func() { close(done) })
+	}
+
+	go func() {
+		defer finish()
+		for {
+			_, data, err := remote.ReadMessage()
+			if err != nil {
+				client.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+
+			err = client.WriteMessage(websocket.BinaryMessage, data)
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer finish()
+		for {
+			_, data, err := client.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			if len(data) == 0 || (data[0] != models.StdinChannel && data[0] != models.ResizeChannel) {
+				if logger != nil {
+					logger.Warn("dropping exec message on invalid channel")
+				}
+				continue
+			}
+
+			err = remote.WriteMessage(websocket.BinaryMessage, data)
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	<-done
+}
@@ -0,0 +1,256 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/topfreegames/mystack-controller/api"
+	"github.com/topfreegames/mystack-controller/models"
+
+	"github.com/gorilla/websocket"
+	mTest "github.com/topfreegames/mystack-controller/testing"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+var _ = Describe("ClusterApp", func() {
+	var (
+		recorder    *httptest.ResponseRecorder
+		clusterName = "myCustomApps"
+		handler     *ClusterAppHandler
+		yaml1       = `
+services:
+  test0:
+    image: svc1
+    port: 5000
+apps:
+  test1:
+    image: registry.example.com:5000/app1:v1
+    port: 5000
+`
+	)
+
+	createCluster := func() {
+		mock.
+			ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+			WithArgs(clusterName).
+			WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+
+		cluster, err := models.NewCluster(app.DB, "user", clusterName, &mTest.MockReadiness{}, &mTest.MockReadiness{}, config)
+		Expect(err).NotTo(HaveOccurred())
+		err = cluster.Create(app.Logger, app.Clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		expectStack(yaml1)
+	}
+
+	serve := func(method, appName, body string) {
+		handler.Method = method
+		route := fmt.Sprintf("/clusters/%s/apps/%s/%s", clusterName, appName, method)
+		request, err := http.NewRequest("PUT", route, strings.NewReader(body))
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx := NewContextWithEmail(request.Context(), "user@example.com")
+		handler.ServeHTTP(recorder, request.WithContext(ctx))
+	}
+
+	BeforeEach(func() {
+		recorder = httptest.NewRecorder()
+		handler = &ClusterAppHandler{App: app}
+	})
+
+	It("should restart app", func() {
+		createCluster()
+		serve("restart", "test1", "")
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		deploy, err := clientset.ExtensionsV1beta1().Deployments("mystack-user-mycustomapps-a62c7ed6aa").Get("test1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deploy.Spec.Template.Annotations).To(HaveKey("mystack/restartedAt"))
+	})
+
+	It("should change image tag", func() {
+		createCluster()
+		serve("image", "test1", `{"tag": "my-branch"}`)
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		bodyJSON := make(map[string]interface{})
+		json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+		Expect(bodyJSON["image"]).To(Equal("registry.example.com:5000/app1:my-branch"))
+
+		deploy, err := clientset.ExtensionsV1beta1().Deployments("mystack-user-mycustomapps-a62c7ed6aa").Get("test1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deploy.Spec.Template.Spec.Containers[0].Image).To(Equal("registry.example.com:5000/app1:my-branch"))
+	})
+
+	It("should scale service", func() {
+		createCluster()
+		serve("scale", "test0", `{"replicas": 2}`)
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		deploy, err := clientset.ExtensionsV1beta1().Deployments("mystack-user-mycustomapps-a62c7ed6aa").Get("test0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*deploy.Spec.Replicas).To(BeEquivalentTo(2))
+	})
+
+	It("should return 422 if replicas is missing", func() {
+		createCluster()
+		serve("scale", "test0", `{}`)
+
+		Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+	})
+
+	It("should return 404 if app doesn't exist", func() {
+		expectNoStack()
+		mock.
+			ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+			WithArgs(clusterName).
+			WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+		serve("restart", "unknown", "")
+
+		bodyJSON := make(map[string]string)
+		json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+		Expect(bodyJSON["description"]).To(Equal("app 'unknown' not found"))
+		Expect(recorder.Code).To(Equal(http.StatusNotFound))
+	})
+	Describe("logs", func() {
+		serveLogs := func(appName, query string) map[string]string {
+			handler.Method = "logs"
+			route := fmt.Sprintf("/clusters/%s/apps/%s/logs%s", clusterName, appName, query)
+			request, err := http.NewRequest("GET", route, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			handler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			bodyJSON := make(map[string]string)
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			return bodyJSON
+		}
+
+		It("should return 404 if app has no started pods", func() {
+			createCluster()
+			bodyJSON := serveLogs("test1", "?follow=true&tail=10")
+
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			Expect(bodyJSON["description"]).To(Equal("pods of app 'test1' not found"))
+		})
+
+		It("should return 404 if app doesn't exist", func() {
+			expectStack(yaml1)
+			bodyJSON := serveLogs("unknown", "")
+
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			Expect(bodyJSON["description"]).To(Equal("app 'unknown' not found"))
+		})
+
+		It("should return 422 if since is invalid", func() {
+			bodyJSON := serveLogs("test1", "?since=yesterday")
+
+			Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(bodyJSON["description"]).To(Equal("invalid since: yesterday"))
+		})
+	})
+	Describe("exec", func() {
+		var executor *mTest.MockExecutor
+
+		createPod := func(phase v1.PodPhase) {
+			_, err := clientset.CoreV1().Pods("mystack-user-mycustomapps-a62c7ed6aa").Create(&v1.Pod{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      "test1-1",
+					Namespace: "mystack-user-mycustomapps-a62c7ed6aa",
+					Labels:    map[string]string{"app": "test1"},
+				},
+				Status: v1.PodStatus{Phase: phase},
+			})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		BeforeEach(func() {
+			handler.Method = "exec"
+			executor = &mTest.MockExecutor{
+				Handler: func(conn *websocket.Conn) {
+					for {
+						_, data, err := conn.ReadMessage()
+						if err != nil {
+							return
+						}
+						if data[0] == models.StdinChannel {
+							data[0] = models.StdoutChannel
+							conn.WriteMessage(websocket.BinaryMessage, data)
+						}
+					}
+				},
+			}
+			app.Executor = executor
+		})
+
+		AfterEach(func() {
+			executor.Close()
+			app.Executor = nil
+		})
+
+		It("should bridge stdin and stdout of the app pod", func() {
+			createCluster()
+			createPod(v1.PodRunning)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctx := NewContextWithEmail(r.Context(), "user@example.com")
+				handler.ServeHTTP(w, r.WithContext(ctx))
+			}))
+			defer server.Close()
+
+			url := fmt.Sprintf(
+				"ws%s/clusters/%s/apps/test1/exec?command=redis-cli",
+				strings.TrimPrefix(server.URL, "http"),
+				clusterName,
+			)
+			conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+			Expect(err).NotTo(HaveOccurred())
+			defer conn.Close()
+
+			err = conn.WriteMessage(websocket.BinaryMessage, []byte{models.ResizeChannel, '{', '}'})
+			Expect(err).NotTo(HaveOccurred())
+			err = conn.WriteMessage(websocket.BinaryMessage, []byte{models.StdinChannel, 'h', 'i'})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, data, err := conn.ReadMessage()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(Equal([]byte{models.StdoutChannel, 'h', 'i'}))
+
+			Expect(executor.Pod).To(Equal("test1-1"))
+			Expect(executor.Options.Container).To(Equal("test1"))
+			Expect(executor.Options.Command).To(Equal([]string{"redis-cli"}))
+			Expect(executor.Options.TTY).To(BeTrue())
+		})
+
+		It("should return 404 if app has no running pod", func() {
+			createCluster()
+			createPod(v1.PodPending)
+			route := fmt.Sprintf("/clusters/%s/apps/test1/exec", clusterName)
+			request, err := http.NewRequest("GET", route, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			handler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			bodyJSON := make(map[string]string)
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			Expect(bodyJSON["description"]).To(Equal("running pod of app 'test1' not found"))
+		})
+	})
+})
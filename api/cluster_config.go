@@ -9,8 +9,11 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
+	"github.com/topfreegames/mystack-controller/errors"
 	"github.com/topfreegames/mystack-controller/models"
 	"net/http"
+	"strconv"
 )
 
 //ClusterConfigHandler handles cluster creation and deletion
@@ -36,6 +39,24 @@ func (c *ClusterConfigHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 	case "update":
 		c.update(w, r)
 		break
+	case "revisions":
+		c.revisions(w, r)
+		break
+	case "revision":
+		c.revision(w, r)
+		break
+	case "diff":
+		c.diff(w, r)
+		break
+	case "rollback":
+		c.rollback(w, r)
+		break
+	case "validate":
+		c.validate(w, r)
+		break
+	case "render":
+		c.render(w, r)
+		break
 	}
 }
 
@@ -45,8 +66,9 @@ func (c *ClusterConfigHandler) create(w http.ResponseWriter, r *http.Request) {
 
 	log(logger, "Creating cluster config '%s'", clusterName)
 	clusterConfig := clusterConfigFromCtx(r.Context())
+	email := emailFromCtx(r.Context())
 
-	err := models.WriteClusterConfig(c.App.DB, clusterName, clusterConfig)
+	err := models.WriteClusterConfig(r.Context(), c.App.DB, clusterName, clusterConfig, email, c.App.Config)
 	if err != nil {
 		c.App.HandleError(w, Status(err), "writing cluster config error", err)
 		return
@@ -61,7 +83,7 @@ func (c *ClusterConfigHandler) remove(w http.ResponseWriter, r *http.Request) {
 	clusterName := GetClusterName(r)
 
 	log(logger, "Deleting cluster config '%s'", clusterName)
-	err := models.RemoveClusterConfig(c.App.DB, clusterName)
+	err := models.RemoveClusterConfig(r.Context(), c.App.DB, clusterName)
 	if err != nil {
 		c.App.HandleError(w, Status(err), "removing cluster config error", err)
 		return
@@ -75,7 +97,7 @@ func (c *ClusterConfigHandler) list(w http.ResponseWriter, r *http.Request) {
 	logger := loggerFromContext(r.Context())
 
 	log(logger, "Getting list of cluster configs")
-	names, err := models.ListClusterConfig(c.App.DB)
+	names, err := models.ListClusterConfig(r.Context(), c.App.DB)
 	if err != nil {
 		c.App.HandleError(w, Status(err), "listing cluster configs error", err)
 		return
@@ -98,7 +120,26 @@ func (c *ClusterConfigHandler) info(w http.ResponseWriter, r *http.Request) {
 	clusterName := GetClusterName(r)
 
 	log(logger, "Getting yaml of cluster config '%s'", clusterName)
-	yamlStr, err := models.ClusterConfigDetails(c.App.DB, clusterName)
+	resolved := false
+	var err error
+	if value := r.URL.Query().Get("resolved"); len(value) > 0 {
+		resolved, err = strconv.ParseBool(value)
+		if err != nil {
+			err := errors.NewGenericError(
+				"cluster configs details error",
+				fmt.Errorf("invalid resolved: %s", value),
+			)
+			c.App.HandleError(w, Status(err), "cluster configs details error", err)
+			return
+		}
+	}
+
+	var yamlStr string
+	if resolved {
+		yamlStr, err = models.ResolvedClusterConfigDetails(r.Context(), c.App.DB, clusterName)
+	} else {
+		yamlStr, err = models.ClusterConfigDetails(r.Context(), c.App.DB, clusterName)
+	}
 	if err != nil {
 		c.App.HandleError(w, Status(err), "cluster configs details error", err)
 		return
@@ -0,0 +1,61 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/topfreegames/mystack-controller/models"
+)
+
+//render writes the Kubernetes objects the cluster config creates for user,
+//the user logged in if none is informed
+func (c *ClusterConfigHandler) render(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	clusterName := GetClusterName(r)
+	query := r.URL.Query()
+
+	username := query.Get("user")
+	if len(username) == 0 {
+		username = usernameFromEmail(emailFromCtx(r.Context()))
+	} else if err := validateUsername(username); err != nil {
+		c.App.HandleError(w, Status(err), "render cluster config error", err)
+		return
+	}
+
+	parameters, err := models.ParseParameterValues(query.Get("parameters"))
+	if err != nil {
+		c.App.HandleError(w, Status(err), "render cluster config error", err)
+		return
+	}
+
+	log(logger, "Rendering cluster config '%s' for user %s", clusterName, username)
+	cluster, err := models.NewClusterWithOptions(
+		c.App.DB,
+		username,
+		clusterName,
+		&models.ClusterOptions{Parameters: parameters},
+		nil, nil,
+		c.App.Config,
+	)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "render cluster config error", err)
+		return
+	}
+
+	manifests, err := cluster.Render()
+	if err != nil {
+		c.App.HandleError(w, Status(err), "render cluster config error", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-yaml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(manifests)
+	log(logger, "Cluster config '%s' successfully rendered", clusterName)
+}
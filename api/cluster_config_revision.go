@@ -0,0 +1,137 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/topfreegames/mystack-controller/errors"
+	"github.com/topfreegames/mystack-controller/models"
+)
+
+//parseRevision returns the revision number of value, named name on the request
+func parseRevision(name, value string) (int, error) {
+	revision, err := strconv.Atoi(value)
+	if err != nil || revision <= 0 {
+		return 0, errors.NewGenericError(
+			"cluster config revision error",
+			fmt.Errorf("invalid %s: %s", name, value),
+		)
+	}
+
+	return revision, nil
+}
+
+func (c *ClusterConfigHandler) revisions(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	clusterName := GetClusterName(r)
+
+	log(logger, "Listing revisions of cluster config '%s'", clusterName)
+	revisions, err := models.ListClusterConfigRevisions(r.Context(), c.App.DB, clusterName)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "listing cluster config revisions error", err)
+		return
+	}
+
+	response := map[string][]*models.ClusterConfigRevision{
+		"revisions": revisions,
+	}
+	bts, err := json.Marshal(response)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "listing cluster config revisions error", err)
+		return
+	}
+	WriteBytes(w, http.StatusOK, bts)
+	log(logger, "Successfully listed revisions of cluster config '%s'", clusterName)
+}
+
+func (c *ClusterConfigHandler) revision(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	clusterName := GetClusterName(r)
+
+	revision, err := parseRevision("revision", GetRevision(r))
+	if err != nil {
+		c.App.HandleError(w, Status(err), "cluster config revision error", err)
+		return
+	}
+
+	log(logger, "Getting revision %d of cluster config '%s'", revision, clusterName)
+	configRevision, err := models.LoadClusterConfigRevision(r.Context(), c.App.DB, clusterName, revision)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "cluster config revision error", err)
+		return
+	}
+
+	bts, err := json.Marshal(configRevision)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "cluster config revision error", err)
+		return
+	}
+	WriteBytes(w, http.StatusOK, bts)
+	log(logger, "Successfully got revision %d of cluster config '%s'", revision, clusterName)
+}
+
+func (c *ClusterConfigHandler) diff(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	clusterName := GetClusterName(r)
+	query := r.URL.Query()
+
+	from, err := parseRevision("from", query.Get("from"))
+	if err != nil {
+		c.App.HandleError(w, Status(err), "cluster config diff error", err)
+		return
+	}
+	to, err := parseRevision("to", query.Get("to"))
+	if err != nil {
+		c.App.HandleError(w, Status(err), "cluster config diff error", err)
+		return
+	}
+
+	log(logger, "Diffing revisions %d and %d of cluster config '%s'", from, to, clusterName)
+	diff, err := models.DiffClusterConfigRevisions(r.Context(), c.App.DB, clusterName, from, to)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "cluster config diff error", err)
+		return
+	}
+
+	response := map[string]string{
+		"diff": diff,
+	}
+	bts, err := json.Marshal(response)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "cluster config diff error", err)
+		return
+	}
+	WriteBytes(w, http.StatusOK, bts)
+	log(logger, "Successfully diffed revisions of cluster config '%s'", clusterName)
+}
+
+func (c *ClusterConfigHandler) rollback(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	clusterName := GetClusterName(r)
+	email := emailFromCtx(r.Context())
+
+	revision, err := parseRevision("revision", GetRevision(r))
+	if err != nil {
+		c.App.HandleError(w, Status(err), "rollback cluster config error", err)
+		return
+	}
+
+	log(logger, "Rolling back cluster config '%s' to revision %d", clusterName, revision)
+	err = models.RollbackClusterConfig(r.Context(), c.App.DB, clusterName, revision, email, c.App.Config)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "rollback cluster config error", err)
+		return
+	}
+
+	Write(w, http.StatusOK, `{"status": "ok"}`)
+	log(logger, "Cluster config '%s' successfully rolled back to revision %d", clusterName, revision)
+}
@@ -0,0 +1,157 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/topfreegames/mystack-controller/api"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var _ = Describe("ClusterConfigRevision", func() {
+	var (
+		recorder             *httptest.ResponseRecorder
+		clusterConfigHandler *ClusterConfigHandler
+		clusterName          = "myCustomApps"
+		yamlV1               = "apps:\n  app1:\n    image: app1:v1\n"
+		yamlV2               = "apps:\n  app1:\n    image: app1:v2\n"
+	)
+
+	expectRevision := func(revision int, yamlStr string) {
+		mock.
+			ExpectQuery("^SELECT (.+) FROM cluster_config_revisions WHERE (.+)$").
+			WithArgs(clusterName, revision).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"cluster_name", "revision", "yaml", "author_email", "created_at",
+			}).AddRow(clusterName, revision, yamlStr, "user@example.com", time.Now()))
+	}
+
+	BeforeEach(func() {
+		recorder = httptest.NewRecorder()
+		clusterConfigHandler = &ClusterConfigHandler{App: app}
+	})
+
+	Describe("GET /cluster-configs/{name}/revisions", func() {
+		It("should list revisions", func() {
+			mock.
+				ExpectQuery("^SELECT (.+) FROM cluster_config_revisions WHERE (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{
+					"cluster_name", "revision", "author_email", "created_at",
+				}).AddRow(clusterName, 1, "user@example.com", time.Now()))
+
+			clusterConfigHandler.Method = "revisions"
+			request, err := http.NewRequest("GET", fmt.Sprintf("/cluster-configs/%s/revisions", clusterName), nil)
+			Expect(err).NotTo(HaveOccurred())
+			clusterConfigHandler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			bodyJSON := make(map[string][]map[string]interface{})
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			Expect(bodyJSON["revisions"]).To(HaveLen(1))
+			Expect(bodyJSON["revisions"][0]["revision"]).To(BeEquivalentTo(1))
+			Expect(bodyJSON["revisions"][0]["authorEmail"]).To(Equal("user@example.com"))
+		})
+	})
+
+	Describe("GET /cluster-configs/{name}/revisions/{revision}", func() {
+		BeforeEach(func() {
+			clusterConfigHandler.Method = "revision"
+		})
+
+		It("should return yaml of revision", func() {
+			expectRevision(2, yamlV2)
+
+			request, err := http.NewRequest("GET", fmt.Sprintf("/cluster-configs/%s/revisions/2", clusterName), nil)
+			Expect(err).NotTo(HaveOccurred())
+			clusterConfigHandler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			bodyJSON := make(map[string]interface{})
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			Expect(bodyJSON["yaml"]).To(Equal(yamlV2))
+		})
+
+		It("should return 404 if revision doesn't exist", func() {
+			mock.
+				ExpectQuery("^SELECT (.+) FROM cluster_config_revisions WHERE (.+)$").
+				WithArgs(clusterName, 5).
+				WillReturnError(fmt.Errorf("sql: no rows in result set"))
+
+			request, err := http.NewRequest("GET", fmt.Sprintf("/cluster-configs/%s/revisions/5", clusterName), nil)
+			Expect(err).NotTo(HaveOccurred())
+			clusterConfigHandler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("should return 422 if revision is not a number", func() {
+			request, err := http.NewRequest("GET", fmt.Sprintf("/cluster-configs/%s/revisions/last", clusterName), nil)
+			Expect(err).NotTo(HaveOccurred())
+			clusterConfigHandler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+			bodyJSON := make(map[string]string)
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			Expect(bodyJSON["description"]).To(Equal("invalid revision: last"))
+		})
+	})
+
+	Describe("GET /cluster-configs/{name}/diff", func() {
+		It("should return diff of revisions", func() {
+			expectRevision(1, yamlV1)
+			expectRevision(2, yamlV2)
+
+			clusterConfigHandler.Method = "diff"
+			request, err := http.NewRequest("GET", fmt.Sprintf("/cluster-configs/%s/diff?from=1&to=2", clusterName), nil)
+			Expect(err).NotTo(HaveOccurred())
+			clusterConfigHandler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			bodyJSON := make(map[string]string)
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			Expect(bodyJSON["diff"]).To(ContainSubstring("-    image: app1:v1\n+    image: app1:v2\n"))
+		})
+	})
+
+	Describe("PUT /cluster-configs/{name}/revisions/{revision}/rollback", func() {
+		It("should roll back to revision", func() {
+			mock.ExpectBegin()
+			expectRevision(1, yamlV1)
+			mock.
+				ExpectExec("^UPDATE clusters SET yaml = (.+) WHERE name = (.+)$").
+				WithArgs(yamlV1, clusterName).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.
+				ExpectExec("^INSERT INTO cluster_config_revisions(.+)$").
+				WithArgs(clusterName, yamlV1, "user@example.com", clusterName).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.
+				ExpectQuery("^SELECT name, yaml FROM clusters WHERE (.+)$").
+				WillReturnRows(sqlmock.NewRows([]string{"name", "yaml"}))
+			mock.ExpectCommit()
+
+			clusterConfigHandler.Method = "rollback"
+			request, err := http.NewRequest("PUT", fmt.Sprintf("/cluster-configs/%s/revisions/1/rollback", clusterName), nil)
+			Expect(err).NotTo(HaveOccurred())
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterConfigHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(Equal(`{"status": "ok"}`))
+		})
+	})
+})
@@ -15,10 +15,12 @@ import (
 	. "github.com/topfreegames/mystack-controller/api"
 
 	"fmt"
+	"github.com/topfreegames/mystack-controller/models"
 	mTest "github.com/topfreegames/mystack-controller/testing"
 	"gopkg.in/DATA-DOG/go-sqlmock.v1"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 )
 
 var _ = Describe("ClusterConfig", func() {
@@ -95,10 +97,16 @@ apps:
 		})
 
 		It("should return status 200 when creating valid cluster config", func() {
+			mock.ExpectBegin()
 			mock.
 				ExpectExec("^INSERT INTO clusters\\(name, yaml\\) VALUES\\((.+)\\)$").
 				WithArgs(clusterName, yaml1).
 				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.
+				ExpectExec("^INSERT INTO cluster_config_revisions(.+)$").
+				WithArgs(clusterName, yaml1, "", clusterName).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
 
 			ctx := NewContextWithClusterConfig(request.Context(), yaml1)
 			clusterConfigHandler.ServeHTTP(recorder, request.WithContext(ctx))
@@ -109,10 +117,16 @@ apps:
 		})
 
 		It("should return status 200 when creating valid cluster config with volume", func() {
+			mock.ExpectBegin()
 			mock.
 				ExpectExec("^INSERT INTO clusters\\(name, yaml\\) VALUES\\((.+)\\)$").
 				WithArgs(clusterName, yamlWithVolume).
 				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.
+				ExpectExec("^INSERT INTO cluster_config_revisions(.+)$").
+				WithArgs(clusterName, yamlWithVolume, "", clusterName).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
 
 			ctx := NewContextWithClusterConfig(request.Context(), yamlWithVolume)
 			clusterConfigHandler.ServeHTTP(recorder, request.WithContext(ctx))
@@ -123,10 +137,12 @@ apps:
 		})
 
 		It("should return status 409 when creating cluster config with known name", func() {
+			mock.ExpectBegin()
 			mock.
 				ExpectExec("^INSERT INTO clusters\\(name, yaml\\) VALUES\\((.+)\\)$").
 				WithArgs(clusterName, yaml1).
 				WillReturnError(fmt.Errorf(`pq: duplicate key value violates unique constraint "clusters_name_key"`))
+			mock.ExpectRollback()
 
 			ctx := NewContextWithClusterConfig(request.Context(), yaml1)
 			clusterConfigHandler.ServeHTTP(recorder, request.WithContext(ctx))
@@ -158,6 +174,28 @@ iam
 			Expect(bodyJSON["error"]).To(Equal("parse yaml error"))
 		})
 
+		It("should return status 400 with every problem when creating cluster config with problems", func() {
+			invalidYaml := `
+apps:
+  app1:
+    ports:
+      - http
+`
+
+			ctx := NewContextWithClusterConfig(request.Context(), invalidYaml)
+			clusterConfigHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+			bodyJSON := make(map[string]interface{})
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			Expect(bodyJSON["code"]).To(Equal("MST-004"))
+			Expect(bodyJSON["error"]).To(Equal("invalid cluster config"))
+			Expect(bodyJSON["details"]).To(Equal([]interface{}{
+				map[string]interface{}{"path": "apps.app1.image", "message": "image is required"},
+				map[string]interface{}{"path": "apps.app1.ports[0]", "message": "invalid port 'http'"},
+			}))
+		})
+
 		It("should return status 422 when creating empty cluster config", func() {
 			yamlReader := mTest.JSONFor(map[string]interface{}{
 				"yaml": "",
@@ -233,10 +271,15 @@ iam
 		})
 
 		It("should return 200 when removing existing cluster", func() {
+			mock.ExpectBegin()
+			mock.
+				ExpectQuery("^SELECT name, yaml FROM clusters WHERE (.+)$").
+				WillReturnRows(sqlmock.NewRows([]string{"name", "yaml"}))
 			mock.
 				ExpectExec("DELETE FROM clusters").
 				WithArgs(clusterName).
 				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
 
 			Expect(err).NotTo(HaveOccurred())
 			clusterConfigHandler.ServeHTTP(recorder, request)
@@ -246,11 +289,32 @@ iam
 			Expect(recorder.Code).To(Equal(http.StatusOK))
 		})
 
+		It("should return 422 when removing cluster config extended by others", func() {
+			mock.ExpectBegin()
+			mock.
+				ExpectQuery("^SELECT name, yaml FROM clusters WHERE (.+)$").
+				WillReturnRows(sqlmock.NewRows([]string{"name", "yaml"}).
+					AddRow("variant", fmt.Sprintf("extends: %s\n", clusterName)))
+			mock.ExpectRollback()
+
+			clusterConfigHandler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+			bodyJSON := make(map[string]string)
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			Expect(bodyJSON["description"]).To(Equal(fmt.Sprintf("cluster config '%s' is extended by 'variant'", clusterName)))
+		})
+
 		It("should return 404 when removing non existing cluster", func() {
+			mock.ExpectBegin()
+			mock.
+				ExpectQuery("^SELECT name, yaml FROM clusters WHERE (.+)$").
+				WillReturnRows(sqlmock.NewRows([]string{"name", "yaml"}))
 			mock.
 				ExpectExec("DELETE FROM clusters").
 				WithArgs(clusterName).
 				WillReturnError(fmt.Errorf("sql: no rows in result set"))
+			mock.ExpectRollback()
 
 			clusterConfigHandler.ServeHTTP(recorder, request)
 
@@ -358,5 +422,182 @@ iam
 			Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
 			Expect(recorder.Code).To(Equal(http.StatusNotFound))
 		})
+
+		It("should return config merged with the config it extends", func() {
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name(.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(`
+extends: base
+apps:
+  test4:
+    image: app4
+`))
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name(.+)$").
+				WithArgs("base").
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+
+			request, err = http.NewRequest("GET", fmt.Sprintf("%s?resolved=true", route), nil)
+			Expect(err).NotTo(HaveOccurred())
+			clusterConfigHandler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			bodyJSON := make(map[string]string)
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			clusterConfig, err := models.ParseYaml(bodyJSON["yaml"])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(clusterConfig.Services).To(HaveKey("test0"))
+			Expect(clusterConfig.Apps).To(HaveLen(4))
+			Expect(clusterConfig.Apps["test4"].Image).To(Equal("app4"))
+			Expect(clusterConfig.Extends).To(BeEmpty())
+		})
+
+		It("should return 422 if resolved is invalid", func() {
+			request, err = http.NewRequest("GET", fmt.Sprintf("%s?resolved=maybe", route), nil)
+			Expect(err).NotTo(HaveOccurred())
+			clusterConfigHandler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+		})
+	})
+
+	Describe("POST /cluster-configs/validate", func() {
+		var (
+			request *http.Request
+			err     error
+			route   = "/cluster-configs/validate"
+		)
+
+		BeforeEach(func() {
+			request, err = http.NewRequest("POST", route, nil)
+			Expect(err).NotTo(HaveOccurred())
+			clusterConfigHandler.Method = "validate"
+		})
+
+		AfterEach(func() {
+			err = mock.ExpectationsWereMet()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should return valid without writing cluster config", func() {
+			ctx := NewContextWithClusterConfig(request.Context(), yamlWithVolume)
+			clusterConfigHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(MatchJSON(`{"valid": true, "problems": []}`))
+		})
+
+		It("should return every problem with its path", func() {
+			invalidYaml := `
+services:
+  postgres:
+    image: postgres
+    links:
+      - app1
+apps:
+  app1:
+    image: app1
+    volumeMount:
+      name: data
+`
+
+			ctx := NewContextWithClusterConfig(request.Context(), invalidYaml)
+			clusterConfigHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(MatchJSON(`{
+				"valid": false,
+				"problems": [
+					{"path": "services.postgres.links[0]", "message": "services can't link to app 'app1'"},
+					{"path": "apps.app1.volumeMount.name", "message": "unknown volume 'data'"},
+					{"path": "apps.app1.volumeMount.mountPath", "message": "mountPath is required"}
+				]
+			}`))
+		})
+
+		It("should return 500 if extended config can't be read", func() {
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs("base").
+				WillReturnError(fmt.Errorf("connection refused"))
+
+			ctx := NewContextWithClusterConfig(request.Context(), "extends: base\n")
+			clusterConfigHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+		})
+	})
+
+	Describe("GET /cluster-configs/{name}/render", func() {
+		var (
+			request     *http.Request
+			err         error
+			clusterName = "myCustomApps"
+			route       = fmt.Sprintf("/cluster-configs/%s/render", clusterName)
+		)
+
+		BeforeEach(func() {
+			clusterConfigHandler.Method = "render"
+		})
+
+		AfterEach(func() {
+			err = mock.ExpectationsWereMet()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should return Kubernetes manifests for user", func() {
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yamlWithVolume))
+
+			request, err = http.NewRequest("GET", fmt.Sprintf("%s?user=derp", route), nil)
+			Expect(err).NotTo(HaveOccurred())
+			clusterConfigHandler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Header().Get("Content-Type")).To(Equal("application/x-yaml"))
+			manifests := recorder.Body.String()
+			Expect(manifests).To(HavePrefix("---\napiVersion: v1\nkind: Namespace\n"))
+			Expect(manifests).To(ContainSubstring("name: mystack-derp-mycustomapps-7c203a8c7a"))
+			Expect(manifests).To(ContainSubstring("kind: PersistentVolumeClaim"))
+			Expect(manifests).To(ContainSubstring("kind: Deployment"))
+			Expect(manifests).To(ContainSubstring("kind: Service"))
+			Expect(manifests).To(ContainSubstring("image: postgres:1.0"))
+		})
+
+		It("should return 404 if cluster config doesn't exist", func() {
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnError(fmt.Errorf("sql: no rows in result set"))
+
+			request, err = http.NewRequest("GET", route, nil)
+			Expect(err).NotTo(HaveOccurred())
+			clusterConfigHandler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("should return 422 if user is invalid", func() {
+			request, err = http.NewRequest("GET", fmt.Sprintf("%s?user=%s", route, url.QueryEscape("derp\n  evil: true")), nil)
+			Expect(err).NotTo(HaveOccurred())
+			clusterConfigHandler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+			bodyJSON := make(map[string]string)
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			Expect(bodyJSON["description"]).To(Equal(`invalid user: "derp\n  evil: true"`))
+		})
+
+		It("should return 422 if parameters are invalid", func() {
+			request, err = http.NewRequest("GET", fmt.Sprintf("%s?parameters=invalid", route), nil)
+			Expect(err).NotTo(HaveOccurred())
+			clusterConfigHandler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+		})
 	})
 })
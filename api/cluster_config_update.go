@@ -10,20 +10,12 @@ func (c *ClusterConfigHandler) update(w http.ResponseWriter, r *http.Request) {
 	logger := loggerFromContext(r.Context())
 	clusterName := GetClusterName(r)
 	clusterConfig := clusterConfigFromCtx(r.Context())
+	email := emailFromCtx(r.Context())
 
 	log(logger, "Updating config '%s'", clusterName)
-
-	log(logger, "Deleting cluster config '%s'", clusterName)
-	err := models.RemoveClusterConfig(c.App.DB, clusterName)
-	if err != nil {
-		c.App.HandleError(w, Status(err), "removing cluster config error", err)
-		return
-	}
-
-	log(logger, "Recreating cluster config '%s'", clusterName)
-	err = models.WriteClusterConfig(c.App.DB, clusterName, clusterConfig)
+	err := models.UpdateClusterConfig(r.Context(), c.App.DB, clusterName, clusterConfig, email, c.App.Config)
 	if err != nil {
-		c.App.HandleError(w, Status(err), "writing cluster config error", err)
+		c.App.HandleError(w, Status(err), "updating cluster config error", err)
 		return
 	}
 
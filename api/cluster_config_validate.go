@@ -0,0 +1,40 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/topfreegames/mystack-controller/models"
+)
+
+func (c *ClusterConfigHandler) validate(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	clusterName := r.URL.Query().Get("name")
+	clusterConfig := clusterConfigFromCtx(r.Context())
+
+	log(logger, "Validating cluster config")
+	problems, err := models.ValidateClusterConfig(r.Context(), c.App.DB, clusterName, clusterConfig, c.App.Config)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "validating cluster config error", err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"valid":    len(problems) == 0,
+		"problems": problems,
+	}
+	bts, err := json.Marshal(response)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "validating cluster config error", err)
+		return
+	}
+	WriteBytes(w, http.StatusOK, bts)
+	log(logger, "Cluster config validated with %d problems", len(problems))
+}
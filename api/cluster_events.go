@@ -0,0 +1,124 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/topfreegames/mystack-controller/errors"
+	"github.com/topfreegames/mystack-controller/models"
+)
+
+//sseKeepAlive is how often a comment is sent so proxies don't close idle streams
+const sseKeepAlive = 15 * time.Second
+
+//events streams as Server-Sent Events the progress of the cluster creation or deletion
+//The stream ends after the last event of the operation
+//Clients that reconnect with Last-Event-ID receive the events they missed
+func (c *ClusterHandler) events(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	email := emailFromCtx(r.Context())
+	username := usernameFromEmail(email)
+	clusterName := GetClusterName(r)
+	namespace := models.ClusterNamespace(username, clusterName)
+
+	log(logger, "Streaming events of cluster %s for user %s", clusterName, username)
+
+	err := models.CheckNamespaceOwner(c.App.Clientset, namespace, username, clusterName)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "stream events error", err)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		err := errors.NewGenericError("stream events error", fmt.Errorf("streaming is not supported"))
+		c.App.HandleError(w, http.StatusInternalServerError, "stream events error", err)
+		return
+	}
+
+	var lastID int64
+	if lastEventID := r.Header.Get("Last-Event-ID"); len(lastEventID) > 0 {
+		lastID, err = strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil {
+			err := errors.NewGenericError(
+				"stream events error",
+				fmt.Errorf("invalid Last-Event-ID: %s", lastEventID),
+			)
+			c.App.HandleError(w, Status(err), "stream events error", err)
+			return
+		}
+	}
+
+	missed, events := c.App.Progress.Subscribe(namespace, lastID)
+	defer c.App.Progress.Unsubscribe(namespace, events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for _, event := range missed {
+		writeProgressEvent(w, event)
+		lastID = event.ID
+		if event.Done {
+			flusher.Flush()
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				//the subscription fell behind, what it lost is still kept by the hub
+				for _, event := range c.App.Progress.Missed(namespace, lastID) {
+					writeProgressEvent(w, event)
+				}
+				flusher.Flush()
+				return
+			}
+			writeProgressEvent(w, event)
+			flusher.Flush()
+			lastID = event.ID
+			if event.Done {
+				log(logger, "Finished events of cluster %s for user %s", clusterName, username)
+				return
+			}
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeProgressEvent(w io.Writer, event *models.ProgressEvent) {
+	bts, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	name := "progress"
+	if event.Done {
+		name = "done"
+	}
+
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, name, bts)
+}
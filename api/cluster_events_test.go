@@ -0,0 +1,97 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package api_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/topfreegames/mystack-controller/api"
+	"github.com/topfreegames/mystack-controller/models"
+)
+
+var _ = Describe("ClusterEvents", func() {
+	var (
+		recorder    *httptest.ResponseRecorder
+		clusterName = "myCustomApps"
+		namespace   = "mystack-user-mycustomapps-a62c7ed6aa"
+		handler     *ClusterHandler
+	)
+
+	serve := func(lastEventID string) {
+		route := fmt.Sprintf("/clusters/%s/events", clusterName)
+		request, err := http.NewRequest("GET", route, nil)
+		Expect(err).NotTo(HaveOccurred())
+		if len(lastEventID) > 0 {
+			request.Header.Set("Last-Event-ID", lastEventID)
+		}
+
+		ctx := NewContextWithEmail(request.Context(), "user@example.com")
+		handler.ServeHTTP(recorder, request.WithContext(ctx))
+	}
+
+	BeforeEach(func() {
+		recorder = httptest.NewRecorder()
+		handler = &ClusterHandler{App: app, Method: "events"}
+		app.Progress = models.NewProgressHub()
+	})
+
+	It("should stream events of the running operation until it is done", func() {
+		progress := app.Progress.Start(namespace, models.OperationCreate)
+		progress.StartStep(models.StepNamespace)
+
+		go func() {
+			defer GinkgoRecover()
+			time.Sleep(100 * time.Millisecond)
+			progress.FinishStep(nil)
+			progress.Done(nil)
+		}()
+
+		serve("")
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Type")).To(Equal("text/event-stream"))
+		body := recorder.Body.String()
+		Expect(body).To(ContainSubstring(`id: 1
+event: progress
+data: {"id":1,"operation":"create","step":"namespace","status":"started"`))
+		Expect(body).To(ContainSubstring(`id: 2
+event: progress
+data: {"id":2,"operation":"create","step":"namespace","status":"succeeded"`))
+		Expect(body).To(ContainSubstring(`id: 3
+event: done
+data: {"id":3,"operation":"create","status":"succeeded"`))
+	})
+
+	It("should send missed events after Last-Event-ID", func() {
+		progress := app.Progress.Start(namespace, models.OperationDelete)
+		progress.StartStep(models.StepDeleteApps)
+		progress.FinishStep(nil)
+		progress.Done(fmt.Errorf("delete cluster reached timeout: user"))
+
+		serve("2")
+
+		body := recorder.Body.String()
+		Expect(body).NotTo(ContainSubstring("id: 2\n"))
+		Expect(body).To(ContainSubstring(`id: 3
+event: done
+data: {"id":3,"operation":"delete","status":"failed"`))
+		Expect(body).To(ContainSubstring(`"error":"delete cluster reached timeout: user","done":true}`))
+	})
+
+	It("should return 422 if Last-Event-ID is invalid", func() {
+		serve("abc")
+
+		Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+	})
+})
@@ -84,7 +84,7 @@ apps:
 			Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
 			bodyJSON := make(map[string]map[string][]string)
 			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
-			Expect(bodyJSON["domains"]["test1"]).To(Equal([]string{"test1.mystack-user.mystack.com"}))
+			Expect(bodyJSON["domains"]["test1"]).To(Equal([]string{"test1.mystack-user-mycustomapps-a62c7ed6aa.mystack.com"}))
 		})
 
 		It("should create clusterName without setup", func() {
@@ -103,7 +103,7 @@ apps:
 			Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
 			bodyJSON := make(map[string]map[string][]string)
 			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
-			Expect(bodyJSON["domains"]["test1"]).To(Equal([]string{"test1.mystack-user.mystack.com"}))
+			Expect(bodyJSON["domains"]["test1"]).To(Equal([]string{"test1.mystack-user-mycustomapps-a62c7ed6aa.mystack.com"}))
 		})
 
 		It("should return error 404 when create non existing clusterName", func() {
@@ -0,0 +1,75 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/topfreegames/mystack-controller/models"
+)
+
+//ClusterJobHandler handles the setup and post-setup jobs of a cluster
+type ClusterJobHandler struct {
+	App    *App
+	Method string
+}
+
+func (c *ClusterJobHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch c.Method {
+	case "logs":
+		c.logs(w, r)
+	}
+}
+
+func (c *ClusterJobHandler) logs(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	email := emailFromCtx(r.Context())
+	username := usernameFromEmail(email)
+	clusterName := GetClusterName(r)
+	jobName := GetJobName(r)
+
+	log(logger, "Getting logs of job %s of cluster %s for user %s", jobName, clusterName, username)
+
+	options, err := logOptions(r)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "get logs error", err)
+		return
+	}
+
+	cluster, err := c.App.deployedCluster(
+		email,
+		clusterName,
+		c.App.DeploymentReadiness,
+		c.App.JobReadiness,
+	)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "get logs error", err)
+		return
+	}
+
+	job, err := cluster.GetJob(jobName)
+	if err != nil {
+		c.App.HandleError(w, Status(err), "get logs error", err)
+		return
+	}
+
+	writer := &flushWriter{w: w}
+	err = job.WriteJobLogs(r.Context(), c.App.Clientset, options, writer)
+	if err != nil {
+		if !writer.written {
+			c.App.HandleError(w, Status(err), "get logs error", err)
+			return
+		}
+		if logger != nil {
+			logger.WithError(err).Error("failed to stream job logs")
+		}
+		return
+	}
+
+	log(logger, "Finished logs of job %s of cluster %s for user %s", jobName, clusterName, username)
+}
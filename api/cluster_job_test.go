@@ -0,0 +1,77 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/topfreegames/mystack-controller/api"
+)
+
+var _ = Describe("ClusterJob", func() {
+	var (
+		recorder    *httptest.ResponseRecorder
+		clusterName = "myCustomApps"
+		handler     *ClusterJobHandler
+		yaml1       = `
+setup:
+  image: setup-img
+apps:
+  test1:
+    image: app1
+    port: 5000
+`
+	)
+
+	serve := func(jobName, query string) map[string]string {
+		route := fmt.Sprintf("/clusters/%s/jobs/%s/logs%s", clusterName, jobName, query)
+		request, err := http.NewRequest("GET", route, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx := NewContextWithEmail(request.Context(), "user@example.com")
+		handler.ServeHTTP(recorder, request.WithContext(ctx))
+
+		bodyJSON := make(map[string]string)
+		json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+		return bodyJSON
+	}
+
+	BeforeEach(func() {
+		recorder = httptest.NewRecorder()
+		handler = &ClusterJobHandler{App: app, Method: "logs"}
+	})
+
+	It("should return 404 if job doesn't exist", func() {
+		expectStack(yaml1)
+		bodyJSON := serve("post-setup", "")
+
+		Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		Expect(bodyJSON["description"]).To(Equal("job 'post-setup' not found"))
+	})
+
+	It("should return 404 if job has no pods", func() {
+		expectStack(yaml1)
+		bodyJSON := serve("setup", "?follow=true")
+
+		Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		Expect(bodyJSON["description"]).To(Equal("pods of job 'setup' not found"))
+	})
+
+	It("should return 422 if tail is invalid", func() {
+		bodyJSON := serve("setup", "?tail=abc")
+
+		Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(bodyJSON["description"]).To(Equal("invalid tail: abc"))
+	})
+})
@@ -18,14 +18,28 @@ import (
 
 	mTest "github.com/topfreegames/mystack-controller/testing"
 	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/pkg/api/resource"
 	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
 	"k8s.io/client-go/pkg/fields"
 	"k8s.io/client-go/pkg/labels"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"time"
 )
 
+//blockingReadiness waits until release is closed
+type blockingReadiness struct {
+	release chan bool
+}
+
+func (b *blockingReadiness) WaitForCompletion(kubernetes.Interface, interface{}) error {
+	<-b.release
+	return nil
+}
+
 var _ = Describe("Cluster", func() {
 
 	var (
@@ -112,6 +126,28 @@ apps:
 		clusterHandler = &ClusterHandler{App: app}
 	})
 
+	Describe("GET /clusters", func() {
+		It("should list clusters of the user", func() {
+			clusterHandler.Method = "list"
+			err := models.CreateNamespace(clientset, "user", "backend")
+			Expect(err).NotTo(HaveOccurred())
+			err = models.CreateNamespace(clientset, "user", "analytics")
+			Expect(err).NotTo(HaveOccurred())
+			err = models.CreateNamespace(clientset, "other", "backend")
+			Expect(err).NotTo(HaveOccurred())
+
+			request, err := http.NewRequest("GET", "/clusters", nil)
+			Expect(err).NotTo(HaveOccurred())
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			bodyJSON := make(map[string][]string)
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			Expect(bodyJSON["clusters"]).To(ConsistOf("backend", "analytics"))
+		})
+	})
+
 	Describe("PUT /clusters/{name}/create", func() {
 
 		var (
@@ -136,15 +172,140 @@ apps:
 				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
 				WithArgs(clusterName).
 				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+			mock.
+				ExpectExec("^INSERT INTO stacks(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.
+				ExpectExec("^INSERT INTO operations(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
 
 			ctx := NewContextWithEmail(request.Context(), "user@example.com")
 			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
 
 			Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
-			Expect(recorder.Code).To(Equal(http.StatusOK))
-			bodyJSON := make(map[string]map[string][]string)
+			Expect(recorder.Code).To(Equal(http.StatusAccepted))
+			bodyJSON := make(map[string]string)
 			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
-			Expect(bodyJSON["domains"]["test1"]).To(Equal([]string{"test1.mystack-user.mystack.com"}))
+			Expect(bodyJSON["operationId"]).NotTo(BeEmpty())
+			Expect(bodyJSON["status"]).To(Equal("running"))
+
+			Eventually(func() error {
+				_, err := clientset.ExtensionsV1beta1().Deployments("mystack-user-mycustomapps-a62c7ed6aa").Get("test1")
+				return err
+			}).Should(Succeed())
+		})
+
+		It("should create cluster with image and env overrides", func() {
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+			mock.
+				ExpectExec("^INSERT INTO stacks(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.
+				ExpectExec("^INSERT INTO operations(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			body := `{"apps": {"test1": {"image": "app1:feature", "env": {"FEATURE": "on"}}}}`
+			request, err = http.NewRequest("PUT", route, strings.NewReader(body))
+			Expect(err).NotTo(HaveOccurred())
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusAccepted))
+			Eventually(func() error {
+				_, err := clientset.ExtensionsV1beta1().Deployments("mystack-user-mycustomapps-a62c7ed6aa").Get("test1")
+				return err
+			}).Should(Succeed())
+
+			deploy, err := clientset.ExtensionsV1beta1().Deployments("mystack-user-mycustomapps-a62c7ed6aa").Get("test1")
+			Expect(err).NotTo(HaveOccurred())
+			container := deploy.Spec.Template.Spec.Containers[0]
+			Expect(container.Image).To(Equal("app1:feature"))
+			Expect(container.Env).To(ContainElement(v1.EnvVar{Name: "FEATURE", Value: "on"}))
+		})
+
+		It("should return 422 if override is not of an app of the cluster config", func() {
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+
+			body := `{"apps": {"unknown": {"image": "app1:feature"}}}`
+			request, err = http.NewRequest("PUT", route, strings.NewReader(body))
+			Expect(err).NotTo(HaveOccurred())
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+			bodyJSON := make(map[string]string)
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			Expect(bodyJSON["description"]).To(Equal("app 'unknown' is not on cluster config"))
+		})
+
+		It("should create cluster with parameter values", func() {
+			yamlStr := `
+parameters:
+  tag:
+    default: v1
+apps:
+  test1:
+    image: app1:{{ .tag }}
+    ports:
+      - 5000:5000
+`
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yamlStr))
+			mock.
+				ExpectExec("^INSERT INTO stacks(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.
+				ExpectExec("^INSERT INTO operations(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			request, err = http.NewRequest("PUT", route, strings.NewReader(`{"parameters": {"tag": "v2"}}`))
+			Expect(err).NotTo(HaveOccurred())
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusAccepted))
+			Eventually(func() error {
+				_, err := clientset.ExtensionsV1beta1().Deployments("mystack-user-mycustomapps-a62c7ed6aa").Get("test1")
+				return err
+			}).Should(Succeed())
+
+			deploy, err := clientset.ExtensionsV1beta1().Deployments("mystack-user-mycustomapps-a62c7ed6aa").Get("test1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deploy.Spec.Template.Spec.Containers[0].Image).To(Equal("app1:v2"))
+		})
+
+		It("should return 422 if parameter is not declared on cluster config", func() {
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+
+			request, err = http.NewRequest("PUT", route, strings.NewReader(`{"parameters": {"tag": "v2"}}`))
+			Expect(err).NotTo(HaveOccurred())
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+			bodyJSON := make(map[string]string)
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			Expect(bodyJSON["description"]).To(Equal("parameter 'tag' is not declared on cluster config"))
+		})
+
+		It("should return 400 if body is not valid JSON", func() {
+			request, err = http.NewRequest("PUT", route, strings.NewReader("{apps"))
+			Expect(err).NotTo(HaveOccurred())
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest))
 		})
 
 		It("should create existing clusterName without setup", func() {
@@ -152,15 +313,27 @@ apps:
 				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
 				WithArgs(clusterName).
 				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yamlWithoutSetup))
+			mock.
+				ExpectExec("^INSERT INTO stacks(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.
+				ExpectExec("^INSERT INTO operations(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
 
 			ctx := NewContextWithEmail(request.Context(), "user@example.com")
 			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
 
 			Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
-			Expect(recorder.Code).To(Equal(http.StatusOK))
-			bodyJSON := make(map[string]map[string][]string)
+			Expect(recorder.Code).To(Equal(http.StatusAccepted))
+			bodyJSON := make(map[string]string)
 			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
-			Expect(bodyJSON["domains"]["test1"]).To(Equal([]string{"test1.mystack-user.mystack.com"}))
+			Expect(bodyJSON["operationId"]).NotTo(BeEmpty())
+			Expect(bodyJSON["status"]).To(Equal("running"))
+
+			Eventually(func() error {
+				_, err := clientset.ExtensionsV1beta1().Deployments("mystack-user-mycustomapps-a62c7ed6aa").Get("test1")
+				return err
+			}).Should(Succeed())
 		})
 
 		It("should create existing clusterName with volume", func() {
@@ -168,15 +341,27 @@ apps:
 				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
 				WithArgs(clusterName).
 				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yamlWithVolume))
+			mock.
+				ExpectExec("^INSERT INTO stacks(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.
+				ExpectExec("^INSERT INTO operations(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
 
 			ctx := NewContextWithEmail(request.Context(), "user@example.com")
 			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
 
 			Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
-			Expect(recorder.Code).To(Equal(http.StatusOK))
-			bodyJSON := make(map[string]map[string][]string)
+			Expect(recorder.Code).To(Equal(http.StatusAccepted))
+			bodyJSON := make(map[string]string)
 			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
-			Expect(bodyJSON["domains"]["app1"]).To(Equal([]string{"app1.mystack-user.mystack.com"}))
+			Expect(bodyJSON["operationId"]).NotTo(BeEmpty())
+			Expect(bodyJSON["status"]).To(Equal("running"))
+
+			Eventually(func() error {
+				_, err := clientset.ExtensionsV1beta1().Deployments("mystack-user-mycustomapps-a62c7ed6aa").Get("app1")
+				return err
+			}).Should(Succeed())
 		})
 
 		It("should create cluster with requests and limits", func() {
@@ -184,17 +369,27 @@ apps:
 				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
 				WithArgs(clusterName).
 				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yamlWithLimitsAndResources))
+			mock.
+				ExpectExec("^INSERT INTO stacks(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.
+				ExpectExec("^INSERT INTO operations(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
 
 			ctx := NewContextWithEmail(request.Context(), "user@example.com")
 			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
 
-			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Code).To(Equal(http.StatusAccepted))
 
-			deploys, err := clientset.ExtensionsV1beta1().Deployments("mystack-user").List(v1.ListOptions{
-				LabelSelector: labels.Set{"mystack/routable": "true"}.AsSelector().String(),
-				FieldSelector: fields.Everything().String(),
-			})
-			Expect(err).NotTo(HaveOccurred())
+			var deploys *v1beta1.DeploymentList
+			Eventually(func() []v1beta1.Deployment {
+				deploys, err = clientset.ExtensionsV1beta1().Deployments("mystack-user-mycustomapps-a62c7ed6aa").List(v1.ListOptions{
+					LabelSelector: labels.Set{"mystack/routable": "true"}.AsSelector().String(),
+					FieldSelector: fields.Everything().String(),
+				})
+				Expect(err).NotTo(HaveOccurred())
+				return deploys.Items
+			}).ShouldNot(BeEmpty())
 
 			k8sDeploy := deploys.Items[0]
 			limitCPU, _ := resource.ParseQuantity("20m")
@@ -212,17 +407,27 @@ apps:
 				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
 				WithArgs(clusterName).
 				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yamlWithLimits))
+			mock.
+				ExpectExec("^INSERT INTO stacks(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.
+				ExpectExec("^INSERT INTO operations(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
 
 			ctx := NewContextWithEmail(request.Context(), "user@example.com")
 			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
 
-			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Code).To(Equal(http.StatusAccepted))
 
-			deploys, err := clientset.ExtensionsV1beta1().Deployments("mystack-user").List(v1.ListOptions{
-				LabelSelector: labels.Set{"mystack/routable": "true"}.AsSelector().String(),
-				FieldSelector: fields.Everything().String(),
-			})
-			Expect(err).NotTo(HaveOccurred())
+			var deploys *v1beta1.DeploymentList
+			Eventually(func() []v1beta1.Deployment {
+				deploys, err = clientset.ExtensionsV1beta1().Deployments("mystack-user-mycustomapps-a62c7ed6aa").List(v1.ListOptions{
+					LabelSelector: labels.Set{"mystack/routable": "true"}.AsSelector().String(),
+					FieldSelector: fields.Everything().String(),
+				})
+				Expect(err).NotTo(HaveOccurred())
+				return deploys.Items
+			}).ShouldNot(BeEmpty())
 
 			k8sDeploy := deploys.Items[0]
 			limitCPU, _ := resource.ParseQuantity("20m")
@@ -235,19 +440,31 @@ apps:
 			Expect(k8sDeploy.Spec.Template.Spec.Containers[0].Resources.Requests["memory"]).To(Equal(requestMemory))
 		})
 
-		It("should not create cluster twice", func() {
+		It("should not create cluster twice at the same time", func() {
 			mock.
 				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
 				WithArgs(clusterName).
 				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
 			mock.
-				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
-				WithArgs(clusterName).
-				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+				ExpectExec("^INSERT INTO stacks(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.
+				ExpectExec("^INSERT INTO operations(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			readiness := &blockingReadiness{release: make(chan bool)}
+			defer close(readiness.release)
+			app.DeploymentReadiness = readiness
+			defer func() { app.DeploymentReadiness = &mTest.MockReadiness{} }()
 
 			ctx := NewContextWithEmail(request.Context(), "user@example.com")
 			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+			Expect(recorder.Code).To(Equal(http.StatusAccepted))
+			Eventually(func() (bool, error) {
+				return models.CreationRunning(clientset, "mystack-user-mycustomapps-a62c7ed6aa")
+			}).Should(BeTrue())
 
+			expectStackWithState(yaml1, "creating")
 			recorder = httptest.NewRecorder()
 			request, _ = http.NewRequest("PUT", route, nil)
 			ctx = NewContextWithEmail(request.Context(), "user@example.com")
@@ -257,11 +474,37 @@ apps:
 			bodyJSON := make(map[string]string)
 			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
 			Expect(bodyJSON["code"]).To(Equal("MST-004"))
-			Expect(bodyJSON["description"]).To(Equal("namespace for user 'user' already exists"))
+			Expect(bodyJSON["description"]).To(Equal("namespace for user 'user' already exists and is being created"))
 			Expect(bodyJSON["error"]).To(Equal("create cluster error"))
 			Expect(recorder.Code).To(Equal(http.StatusConflict))
 		})
 
+		It("should complete creation of a partially created cluster", func() {
+			expectStackWithState(yaml1, "creating")
+			mock.
+				ExpectExec("^UPDATE stacks(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.
+				ExpectExec("^INSERT INTO operations(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			cluster, err := models.NewClusterFromYaml(yaml1, "user", clusterName, &mTest.MockReadiness{}, &mTest.MockReadiness{}, config)
+			Expect(err).NotTo(HaveOccurred())
+			err = models.CreateNamespace(clientset, "user", clusterName)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = cluster.SvcDeployments[0].Deploy(clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusAccepted))
+			Eventually(func() error {
+				_, err := clientset.ExtensionsV1beta1().Deployments("mystack-user-mycustomapps-a62c7ed6aa").Get("test1")
+				return err
+			}).Should(Succeed())
+		})
+
 		It("should return error 404 when create non existing clusterName", func() {
 			mock.
 				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
@@ -279,6 +522,103 @@ apps:
 			Expect(bodyJSON["description"]).To(Equal("sql: no rows in result set"))
 			Expect(bodyJSON["error"]).To(Equal("database error"))
 		})
+
+		It("should return error 422 with invalid keepOnFailure", func() {
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+
+			request, err = http.NewRequest("PUT", fmt.Sprintf("%s?keepOnFailure=maybe", route), nil)
+			Expect(err).NotTo(HaveOccurred())
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+			bodyJSON := make(map[string]string)
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			Expect(bodyJSON["description"]).To(Equal("invalid keepOnFailure: maybe"))
+		})
+	})
+
+	Describe("POST /clusters/{name}/resume", func() {
+
+		var (
+			err       error
+			request   *http.Request
+			namespace = "mystack-user-mycustomapps-a62c7ed6aa"
+		)
+
+		BeforeEach(func() {
+			clusterHandler.Method = "resume"
+			request, err = http.NewRequest("POST", fmt.Sprintf("/clusters/%s/resume", clusterName), nil)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should resume cluster from failed step", func() {
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+			expectStackWithState(yaml1, "failed")
+			mock.
+				ExpectExec("^UPDATE stacks(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.
+				ExpectExec("^INSERT INTO operations(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			cluster, err := models.NewCluster(app.DB, "user", clusterName, &mTest.MockReadiness{}, &mTest.MockReadiness{}, config)
+			Expect(err).NotTo(HaveOccurred())
+			err = cluster.Create(app.Logger, app.Clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = clientset.ExtensionsV1beta1().Deployments(namespace).Delete("test1", &v1.DeleteOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			ns, err := clientset.CoreV1().Namespaces().Get(namespace)
+			Expect(err).NotTo(HaveOccurred())
+			ns.Annotations = map[string]string{"mystack/failedStep": models.StepAppDeployments}
+			_, err = clientset.CoreV1().Namespaces().Update(ns)
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusAccepted))
+			bodyJSON := make(map[string]string)
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			Expect(bodyJSON["operationId"]).NotTo(BeEmpty())
+			Expect(bodyJSON["failedStep"]).To(Equal(models.StepAppDeployments))
+
+			Eventually(func() error {
+				_, err := clientset.ExtensionsV1beta1().Deployments(namespace).Get("test1")
+				return err
+			}).Should(Succeed())
+			Eventually(func() (string, error) {
+				return models.FailedStep(clientset, namespace)
+			}).Should(BeEmpty())
+		})
+
+		It("should return error 422 if cluster creation didn't fail", func() {
+			expectStack(yaml1)
+
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+			bodyJSON := make(map[string]string)
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			Expect(bodyJSON["description"]).To(Equal("cluster 'myCustomApps' is running, only failed clusters can be resumed"))
+		})
+
+		It("should return error 404 if cluster has no stack", func() {
+			expectNoStack()
+
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
 	})
 
 	Describe("PUT /clusters/{name}/delete", func() {
@@ -305,10 +645,10 @@ apps:
 				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
 				WithArgs(clusterName).
 				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+			expectStack(yaml1)
 			mock.
-				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
-				WithArgs(clusterName).
-				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+				ExpectExec("^UPDATE stacks(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
 
 			cluster, err := models.NewCluster(app.DB, "user", clusterName, &mTest.MockReadiness{}, &mTest.MockReadiness{}, config)
 			Expect(err).NotTo(HaveOccurred())
@@ -328,10 +668,10 @@ apps:
 				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
 				WithArgs(clusterName).
 				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yamlWithVolume))
+			expectStack(yamlWithVolume)
 			mock.
-				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
-				WithArgs(clusterName).
-				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yamlWithVolume))
+				ExpectExec("^UPDATE stacks(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
 
 			cluster, err := models.NewCluster(app.DB, "user", clusterName, &mTest.MockReadiness{}, &mTest.MockReadiness{}, config)
 			Expect(err).NotTo(HaveOccurred())
@@ -347,6 +687,7 @@ apps:
 		})
 
 		It("should return error 404 when deleting non existing cluster", func() {
+			expectNoStack()
 			mock.
 				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
 				WithArgs(clusterName).
@@ -364,15 +705,64 @@ apps:
 			Expect(recorder.Code).To(Equal(http.StatusNotFound))
 		})
 
+		It("should return error 403 when deleting cluster of another user with the same username", func() {
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+			expectStack(yaml1)
+
+			cluster, err := models.NewCluster(app.DB, "user", clusterName, &mTest.MockReadiness{}, &mTest.MockReadiness{}, config)
+			Expect(err).NotTo(HaveOccurred())
+			err = cluster.Create(app.Logger, app.Clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx := NewContextWithEmail(request.Context(), "user@other.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusForbidden))
+			Expect(models.NamespaceExists(clientset, cluster.Namespace)).To(BeTrue())
+		})
+
+		It("should return error 403 when namespace belongs to another cluster", func() {
+			namespace := models.ClusterNamespace("user", clusterName)
+			_, err := clientset.CoreV1().Namespaces().Create(&v1.Namespace{
+				ObjectMeta: v1.ObjectMeta{
+					Name:        namespace,
+					Labels:      map[string]string{"mystack/owner": "user"},
+					Annotations: map[string]string{"mystack/cluster": "otherCluster"},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			expectNoStack()
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusForbidden))
+			bodyJSON := make(map[string]string)
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			Expect(bodyJSON["code"]).To(Equal("MST-002"))
+			Expect(models.NamespaceExists(clientset, namespace)).To(BeTrue())
+		})
+
 		It("should delete cluster even if cluster config doesn't exist anymore", func() {
 			mock.
 				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
 				WithArgs(clusterName).
 				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+			expectNoStack()
 			mock.
 				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
 				WithArgs(clusterName).
 				WillReturnError(fmt.Errorf("sql: no rows in result set"))
+			mock.
+				ExpectExec("^UPDATE stacks(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
 
 			cluster, err := models.NewCluster(app.DB, "user", clusterName, &mTest.MockReadiness{}, &mTest.MockReadiness{}, config)
 			Expect(err).NotTo(HaveOccurred())
@@ -388,6 +778,301 @@ apps:
 		})
 	})
 
+	Describe("PUT /clusters/{name}/extend", func() {
+
+		var (
+			err     error
+			request *http.Request
+			route   = fmt.Sprintf("/clusters/%s/extend", clusterName)
+		)
+
+		BeforeEach(func() {
+			clusterHandler.Method = "extend"
+		})
+
+		It("should extend cluster by informed ttl", func() {
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+			expectStack(yaml1)
+
+			cluster, err := models.NewCluster(app.DB, "user", clusterName, &mTest.MockReadiness{}, &mTest.MockReadiness{}, config)
+			Expect(err).NotTo(HaveOccurred())
+			err = cluster.Create(app.Logger, app.Clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			request, err = http.NewRequest("PUT", route, strings.NewReader(`{"ttl": "240h"}`))
+			Expect(err).NotTo(HaveOccurred())
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			bodyJSON := make(map[string]time.Time)
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			Expect(bodyJSON["expiresAt"]).To(BeTemporally("~", time.Now().Add(240*time.Hour), time.Minute))
+
+			expiresAt, err := models.ClusterExpiration(clientset, "mystack-user-mycustomapps-a62c7ed6aa")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*expiresAt).To(BeTemporally("~", bodyJSON["expiresAt"], time.Second))
+		})
+
+		It("should return status 422 if ttl is invalid", func() {
+			request, err = http.NewRequest("PUT", route, strings.NewReader(`{"ttl": "forever"}`))
+			Expect(err).NotTo(HaveOccurred())
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+			bodyJSON := make(map[string]string)
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			Expect(bodyJSON["description"]).To(Equal("invalid ttl: forever"))
+		})
+
+		It("should return status 404 if namespace doesn't exist", func() {
+			expectNoStack()
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+
+			request, err = http.NewRequest("PUT", route, nil)
+			Expect(err).NotTo(HaveOccurred())
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("POST /clusters/{name}/sleep", func() {
+
+		var (
+			err     error
+			request *http.Request
+		)
+
+		It("should put cluster to sleep and wake it", func() {
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+			expectStack(yaml1)
+			expectStack(yaml1)
+
+			cluster, err := models.NewCluster(app.DB, "user", clusterName, &mTest.MockReadiness{}, &mTest.MockReadiness{}, config)
+			Expect(err).NotTo(HaveOccurred())
+			err = cluster.Create(app.Logger, app.Clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			clusterHandler.Method = "sleep"
+			request, err = http.NewRequest("POST", fmt.Sprintf("/clusters/%s/sleep", clusterName), nil)
+			Expect(err).NotTo(HaveOccurred())
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(Equal(`{"status": "ok"}`))
+			deploy, err := clientset.ExtensionsV1beta1().Deployments("mystack-user-mycustomapps-a62c7ed6aa").Get("test1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*deploy.Spec.Replicas).To(BeEquivalentTo(0))
+
+			recorder = httptest.NewRecorder()
+			clusterHandler.Method = "wake"
+			request, err = http.NewRequest("POST", fmt.Sprintf("/clusters/%s/wake", clusterName), nil)
+			Expect(err).NotTo(HaveOccurred())
+			ctx = NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			deploy, err = clientset.ExtensionsV1beta1().Deployments("mystack-user-mycustomapps-a62c7ed6aa").Get("test1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*deploy.Spec.Replicas).To(BeEquivalentTo(1))
+		})
+
+		It("should return status 409 if cluster is being created", func() {
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+			expectStack(yaml1)
+
+			cluster, err := models.NewCluster(app.DB, "user", clusterName, &mTest.MockReadiness{}, &mTest.MockReadiness{}, config)
+			Expect(err).NotTo(HaveOccurred())
+			err = cluster.Create(app.Logger, app.Clientset)
+			Expect(err).NotTo(HaveOccurred())
+			err = cluster.Claim(app.Clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			clusterHandler.Method = "sleep"
+			request, err = http.NewRequest("POST", fmt.Sprintf("/clusters/%s/sleep", clusterName), nil)
+			Expect(err).NotTo(HaveOccurred())
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusConflict))
+			bodyJSON := make(map[string]string)
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			Expect(bodyJSON["description"]).To(Equal("namespace for user 'user' already exists and is being created"))
+			deploy, err := clientset.ExtensionsV1beta1().Deployments("mystack-user-mycustomapps-a62c7ed6aa").Get("test1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*deploy.Spec.Replicas).To(BeEquivalentTo(1))
+		})
+
+		It("should return status 404 if namespace doesn't exist", func() {
+			expectNoStack()
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+
+			clusterHandler.Method = "sleep"
+			request, err = http.NewRequest("POST", fmt.Sprintf("/clusters/%s/sleep", clusterName), nil)
+			Expect(err).NotTo(HaveOccurred())
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("GET /clusters/{name}/status", func() {
+		It("should use the config the cluster was created with", func() {
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+			expectStack(yaml1)
+
+			cluster, err := models.NewCluster(app.DB, "user", clusterName, &mTest.MockReadiness{}, &mTest.MockReadiness{}, config)
+			Expect(err).NotTo(HaveOccurred())
+			err = cluster.Create(app.Logger, app.Clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			clusterHandler.Method = "status"
+			request, err := http.NewRequest("GET", fmt.Sprintf("/clusters/%s/status", clusterName), nil)
+			Expect(err).NotTo(HaveOccurred())
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			status := &models.ClusterStatus{}
+			err = json.Unmarshal(recorder.Body.Bytes(), status)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status.Apps).To(HaveKey("test1"))
+			Expect(status.Services).To(HaveKey("test0"))
+		})
+	})
+
+	Describe("PUT /clusters/{name}/update", func() {
+
+		var (
+			err     error
+			request *http.Request
+			route   = fmt.Sprintf("/clusters/%s/update", clusterName)
+		)
+
+		BeforeEach(func() {
+			clusterHandler.Method = "update"
+			request, err = http.NewRequest("PUT", route, nil)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should update only what changed", func() {
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(`
+setup:
+  image: setup-img
+services:
+  test0:
+    image: svc1
+    port: 5000
+apps:
+  test1:
+    image: app1:v2
+    port: 5000
+  test2:
+    image: app2
+    port: 5000
+`))
+			expectStack(yaml1)
+			mock.
+				ExpectExec("^UPDATE stacks(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			cluster, err := models.NewCluster(app.DB, "user", clusterName, &mTest.MockReadiness{}, &mTest.MockReadiness{}, config)
+			Expect(err).NotTo(HaveOccurred())
+			err = cluster.Create(app.Logger, app.Clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			bodyJSON := make(map[string][]string)
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			Expect(bodyJSON["created"]).To(ConsistOf("deployment/test2", "service/test2"))
+			Expect(bodyJSON["updated"]).To(ConsistOf("deployment/test1"))
+			Expect(bodyJSON["unchanged"]).To(ConsistOf("deployment/test0", "service/test0", "service/test1"))
+			Expect(bodyJSON["deleted"]).To(BeEmpty())
+
+			deploy, err := clientset.ExtensionsV1beta1().Deployments("mystack-user-mycustomapps-a62c7ed6aa").Get("test1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deploy.Spec.Template.Spec.Containers[0].Image).To(Equal("app1:v2"))
+		})
+
+		It("should return status 409 if cluster is being created", func() {
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+			expectStack(yaml1)
+
+			cluster, err := models.NewCluster(app.DB, "user", clusterName, &mTest.MockReadiness{}, &mTest.MockReadiness{}, config)
+			Expect(err).NotTo(HaveOccurred())
+			err = cluster.Create(app.Logger, app.Clientset)
+			Expect(err).NotTo(HaveOccurred())
+			err = cluster.Claim(app.Clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			Expect(recorder.Code).To(Equal(http.StatusConflict))
+			bodyJSON := make(map[string]string)
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			Expect(bodyJSON["description"]).To(Equal("namespace for user 'user' already exists and is being created"))
+			Expect(bodyJSON["error"]).To(Equal("update cluster error"))
+		})
+
+		It("should return status 404 if namespace doesn't exist", func() {
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+			expectNoStack()
+
+			ctx := NewContextWithEmail(request.Context(), "user@example.com")
+			clusterHandler.ServeHTTP(recorder, request.WithContext(ctx))
+
+			bodyJSON := make(map[string]string)
+			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+			Expect(bodyJSON["description"]).To(Equal("namespace for user 'user' not found"))
+			Expect(bodyJSON["error"]).To(Equal("update cluster error"))
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
 	Describe("GET /clusters/{name}/apps", func() {
 		var (
 			err     error
@@ -411,10 +1096,7 @@ apps:
 				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
 				WithArgs(clusterName).
 				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
-			mock.
-				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
-				WithArgs(clusterName).
-				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+			expectStack(yaml1)
 
 			cluster, err := models.NewCluster(app.DB, "user", clusterName, &mTest.MockReadiness{}, &mTest.MockReadiness{}, config)
 			Expect(err).NotTo(HaveOccurred())
@@ -426,9 +1108,10 @@ apps:
 
 			Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
 			Expect(recorder.Code).To(Equal(http.StatusOK))
-			bodyJSON := make(map[string]map[string][]string)
-			json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
-			Expect(bodyJSON["domains"]["test1"]).To(Equal([]string{"test1.mystack-user.mystack.com"}))
+			bodyJSON := &models.ClusterApps{}
+			json.Unmarshal(recorder.Body.Bytes(), bodyJSON)
+			Expect(bodyJSON.Domains["test1"]).To(Equal([]string{"test1.mystack-user-mycustomapps-a62c7ed6aa.mystack.com"}))
+			Expect(bodyJSON.Sleeping).To(BeFalse())
 		})
 
 		It("should return status 404 if namespace doesn't exist", func() {
@@ -436,6 +1119,7 @@ apps:
 				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
 				WithArgs(clusterName).
 				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+			expectNoStack()
 			mock.
 				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
 				WithArgs(clusterName).
@@ -480,10 +1164,7 @@ apps:
 				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
 				WithArgs(clusterName).
 				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
-			mock.
-				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
-				WithArgs(clusterName).
-				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+			expectStack(yaml1)
 
 			cluster, err := models.NewCluster(app.DB, "user", clusterName, &mTest.MockReadiness{}, &mTest.MockReadiness{}, config)
 			Expect(err).NotTo(HaveOccurred())
@@ -505,6 +1186,7 @@ apps:
 				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
 				WithArgs(clusterName).
 				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+			expectNoStack()
 			mock.
 				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
 				WithArgs(clusterName).
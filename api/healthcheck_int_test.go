@@ -14,6 +14,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/topfreegames/mystack-controller/metadata"
+	"github.com/topfreegames/mystack-controller/models"
 	oTesting "github.com/topfreegames/mystack-controller/testing"
 
 	"net/http"
@@ -57,7 +58,7 @@ var _ = Describe("Healthcheck", func() {
 			Expect(err).NotTo(HaveOccurred())
 
 			conn.Close()
-			app.DB = conn
+			app.DB = models.NewDB(conn)
 
 			app.Router.ServeHTTP(recorder, request)
 			Expect(recorder.Code).To(Equal(500))
@@ -8,11 +8,16 @@
 package api
 
 import (
+	"fmt"
 	"github.com/Sirupsen/logrus"
 	"github.com/gorilla/mux"
 	"github.com/topfreegames/mystack-controller/errors"
+	"github.com/topfreegames/mystack-controller/models"
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 //Write to the response and with the status code
@@ -47,6 +52,13 @@ func Status(err error) int {
 		return http.StatusBadRequest
 	case *errors.GenericError:
 		return http.StatusUnprocessableEntity
+	case *errors.AccessError:
+		if strings.Contains(err.Error(), "doesn't belong to") {
+			return http.StatusForbidden
+		}
+		if strings.Contains(err.Error(), "is not an admin") {
+			return http.StatusForbidden
+		}
 	case *errors.KubernetesError:
 		if strings.Contains(err.Error(), "not found") {
 			return http.StatusNotFound
@@ -74,12 +86,113 @@ func GetClusterName(r *http.Request) string {
 	return clusterName
 }
 
+//GetAppName gets the app name from URL from request
+func GetAppName(r *http.Request) string {
+	appName := mux.Vars(r)["app"]
+
+	if len(appName) == 0 {
+		parts := strings.Split(r.URL.String(), "/")
+		appName = parts[4]
+	}
+
+	return appName
+}
+
+//GetJobName gets the job name from URL from request
+func GetJobName(r *http.Request) string {
+	jobName := mux.Vars(r)["job"]
+
+	if len(jobName) == 0 {
+		parts := strings.Split(r.URL.String(), "/")
+		jobName = parts[4]
+	}
+
+	return jobName
+}
+
+//GetRevision gets the cluster config revision from URL from request
+func GetRevision(r *http.Request) string {
+	revision := mux.Vars(r)["revision"]
+
+	if len(revision) == 0 {
+		parts := strings.Split(r.URL.String(), "/")
+		revision = parts[4]
+	}
+
+	return revision
+}
+
+//logOptions reads follow, tail and since from the query string
+func logOptions(r *http.Request) (*models.LogOptions, error) {
+	options := &models.LogOptions{}
+	query := r.URL.Query()
+
+	if follow := query.Get("follow"); len(follow) > 0 {
+		value, err := strconv.ParseBool(follow)
+		if err != nil {
+			return nil, errors.NewGenericError("get logs error", fmt.Errorf("invalid follow: %s", follow))
+		}
+		options.Follow = value
+	}
+
+	if tail := query.Get("tail"); len(tail) > 0 {
+		value, err := strconv.ParseInt(tail, 10, 64)
+		if err != nil || value < 0 {
+			return nil, errors.NewGenericError("get logs error", fmt.Errorf("invalid tail: %s", tail))
+		}
+		options.TailLines = &value
+	}
+
+	if since := query.Get("since"); len(since) > 0 {
+		duration, err := time.ParseDuration(since)
+		if err != nil || duration <= 0 {
+			return nil, errors.NewGenericError("get logs error", fmt.Errorf("invalid since: %s", since))
+		}
+		seconds := int64(duration.Seconds())
+		options.SinceSeconds = &seconds
+	}
+
+	return options, nil
+}
+
+//flushWriter sends every write to the client right away
+//so followed logs aren't held in the response buffer
+type flushWriter struct {
+	w       http.ResponseWriter
+	written bool
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	if !f.written {
+		f.w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		f.w.WriteHeader(http.StatusOK)
+		f.written = true
+	}
+
+	n, err := f.w.Write(p)
+	if flusher, ok := f.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
+
 func usernameFromEmail(email string) string {
 	username := strings.Split(email, "@")[0]
 	username = strings.Replace(username, ".", "-", -1)
 	return username
 }
 
+//usernameRegex matches the usernames that can be saved on the mystack/owner label
+var usernameRegex = regexp.MustCompile("^[a-zA-Z0-9]([-_a-zA-Z0-9]*[a-zA-Z0-9])?$")
+
+//validateUsername checks a username informed on the request instead of read from the email
+func validateUsername(username string) error {
+	if len(username) > 63 || !usernameRegex.MatchString(username) {
+		return errors.NewGenericError("invalid user", fmt.Errorf("invalid user: %q", username))
+	}
+	return nil
+}
+
 func log(logger logrus.FieldLogger, format string, args ...interface{}) {
 	if logger != nil {
 		if len(args) == 0 {
@@ -83,7 +83,7 @@ func (l *LoginHandler) exchangeAccess(w http.ResponseWriter, r *http.Request) {
 	}
 
 	logger.Infof("authenticating email")
-	email, status, err := extensions.Authenticate(token, &models.OSCredentials{}, l.App.DB)
+	email, status, err := extensions.Authenticate(r.Context(), token, &models.OSCredentials{}, l.App.DB)
 	if err != nil {
 		logger.WithError(err).Error("failed to authenticate")
 		return
@@ -101,7 +101,7 @@ func (l *LoginHandler) exchangeAccess(w http.ResponseWriter, r *http.Request) {
 	}
 
 	logger.Info("saving token on database")
-	err = extensions.SaveToken(token, email, token.AccessToken, l.App.DB)
+	err = extensions.SaveToken(r.Context(), token, email, token.AccessToken, l.App.DB)
 	if err != nil {
 		logger.WithError(err).Error("failed to save token on database")
 		l.App.HandleError(w, http.StatusBadRequest, "", err)
@@ -0,0 +1,53 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/topfreegames/mystack-controller/errors"
+	"github.com/topfreegames/mystack-controller/models"
+)
+
+//OperationHandler handles background operations status
+type OperationHandler struct {
+	App *App
+}
+
+//ServeHTTP method
+func (o *OperationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	email := emailFromCtx(r.Context())
+	username := usernameFromEmail(email)
+	id := mux.Vars(r)["id"]
+
+	log(logger, "Getting operation %s", id)
+	operation, err := models.LoadOperation(o.App.DB, id)
+	if err != nil {
+		o.App.HandleError(w, Status(err), "get operation error", err)
+		return
+	}
+
+	if operation.Username != username {
+		err := errors.NewDatabaseError(fmt.Errorf("sql: no rows in result set"))
+		o.App.HandleError(w, Status(err), "get operation error", err)
+		return
+	}
+
+	bts, err := json.Marshal(operation)
+	if err != nil {
+		o.App.HandleError(w, Status(err), "get operation error", err)
+		return
+	}
+
+	WriteBytes(w, http.StatusOK, bts)
+	log(logger, "Operation %s gotten", id)
+}
@@ -7,6 +7,7 @@ package api
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -30,21 +31,54 @@ func Proxy(remoteConn, clientConn net.Conn, logger logrus.FieldLogger) {
 	go CopyConn(remoteConn, clientConn)
 }
 
-func Read(conn net.Conn, logger logrus.FieldLogger) (string, string, error) {
+//Handshake is the first message sent by the client on port forward
+//Cluster can be omitted if the user is running a single cluster
+type Handshake struct {
+	Token   string `json:"token"`
+	Service string `json:"service"`
+	Cluster string `json:"cluster"`
+}
+
+func Read(conn net.Conn, logger logrus.FieldLogger) (*Handshake, error) {
 	buf, err := bufio.NewReader(conn).ReadBytes('\n')
 	if err != nil {
-		return "", "", fmt.Errorf("error reading handshake: %s", err)
+		return nil, fmt.Errorf("error reading handshake: %s", err)
 	}
 
-	obj := make(map[string]interface{})
-	err = json.Unmarshal(buf, &obj)
+	handshake := &Handshake{}
+	err = json.Unmarshal(buf, handshake)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
 	logger.Debug("received message from tcp socket")
 
-	return obj["token"].(string), obj["service"].(string), nil
+	return handshake, nil
+}
+
+//clusterNamespace returns the namespace of the cluster the client wants to connect to
+//It fails if the namespace belongs to another user
+func (a *App) clusterNamespace(username string, handshake *Handshake) (string, error) {
+	clusterName := handshake.Cluster
+	if len(clusterName) == 0 {
+		clusters, err := models.ListClusters(a.Clientset, username)
+		if err != nil {
+			return "", err
+		}
+
+		if len(clusters) != 1 {
+			return "", fmt.Errorf("cluster is required, user is running %d clusters", len(clusters))
+		}
+		clusterName = clusters[0]
+	}
+
+	namespace := models.ClusterNamespace(username, clusterName)
+	err := models.CheckNamespaceOwner(a.Clientset, namespace, username, clusterName)
+	if err != nil {
+		return "", err
+	}
+
+	return namespace, nil
 }
 
 func (a *App) listenTCP(url string) {
@@ -60,20 +94,21 @@ func (a *App) listenTCP(url string) {
 			a.Logger.Fatalf("error: failed to accept listener: %v", err)
 		}
 
-		accessToken, service, err := Read(conn, a.Logger)
+		handshake, err := Read(conn, a.Logger)
 		if err != nil {
 			fmt.Fprintf(conn, "error reading handshake message: %s", err)
 			continue
 		}
+		service := handshake.Service
 
-		token, err := extensions.Token(accessToken, a.DB)
+		token, err := extensions.Token(context.Background(), handshake.Token, a.DB)
 		if err != nil {
 			fmt.Fprintf(conn, "error: connection was not authenticated")
 			continue
 		}
 		a.Logger.Infof("validated token")
 
-		email, _, err := extensions.Authenticate(token, &models.OSCredentials{}, a.DB)
+		email, _, err := extensions.Authenticate(context.Background(), token, &models.OSCredentials{}, a.DB)
 		if err != nil {
 			fmt.Fprintf(conn, "connection was not authenticated: %s", err)
 			continue
@@ -88,13 +123,19 @@ func (a *App) listenTCP(url string) {
 
 		conn.Write([]byte("successfull authentication"))
 
-		port, err := models.ServicePort(a.Clientset, service, username)
+		namespace, err := a.clusterNamespace(username, handshake)
+		if err != nil {
+			fmt.Fprintf(conn, "cluster not found: %s", err)
+			continue
+		}
+
+		port, err := models.ServicePort(a.Clientset, service, namespace)
 		if err != nil {
 			fmt.Fprintf(conn, "service doesn't exist: %s", err)
 			continue
 		}
 
-		remoteAddr := fmt.Sprintf("%s.mystack-%s:%d", service, username, port)
+		remoteAddr := fmt.Sprintf("%s.%s:%d", service, namespace, port)
 		remoteConn, err := net.Dial("tcp", remoteAddr)
 		if err != nil {
 			a.Logger.Fatalf("Dial failed: %v", err)
@@ -44,10 +44,11 @@ var _ = Describe("PortForward", func() {
 			bts = append(bts, '\n')
 			go connA.Write(bts)
 
-			token, service, err := Read(connB, l)
+			handshake, err := Read(connB, l)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(token).To(Equal("i-am-a-token"))
-			Expect(service).To(Equal("svc1"))
+			Expect(handshake.Token).To(Equal("i-am-a-token"))
+			Expect(handshake.Service).To(Equal("svc1"))
+			Expect(handshake.Cluster).To(BeEmpty())
 		})
 	})
 })
@@ -0,0 +1,91 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package api
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/topfreegames/mystack-controller/models"
+)
+
+func (a *App) runReaper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		err := a.ReapExpiredClusters()
+		if err != nil {
+			a.Logger.WithError(err).Error("failed to reap expired clusters")
+		}
+	}
+}
+
+//ReapExpiredClusters deletes the clusters whose ttl has passed
+//Every deleted cluster is saved as a reap operation
+func (a *App) ReapExpiredClusters() error {
+	logger := a.Logger.WithFields(logrus.Fields{
+		"source":    "api/reaper.go",
+		"operation": "ReapExpiredClusters",
+	})
+
+	expiredClusters, err := models.ExpiredClusters(a.Clientset, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, expired := range expiredClusters {
+		l := logger.WithFields(logrus.Fields{
+			"namespace": expired.Namespace,
+			"expiresAt": expired.ExpiresAt,
+		})
+
+		if len(expired.Username) == 0 || len(expired.ClusterName) == 0 {
+			l.Warn("skipping namespace without owner or cluster name")
+			continue
+		}
+
+		err := a.reap(l, expired)
+		if err != nil {
+			l.WithError(err).Error("failed to reap cluster")
+			continue
+		}
+		l.Info("reaped expired cluster")
+	}
+
+	return nil
+}
+
+func (a *App) reap(logger logrus.FieldLogger, expired *models.ExpiredCluster) error {
+	operation, err := models.NewOperation(a.DB, models.OperationReap, expired.ClusterName, expired.Username)
+	if err != nil {
+		return err
+	}
+
+	cluster, err := models.NewDeployedCluster(a.DB, expired.Username, expired.ClusterName, nil, nil, a.Config)
+	if err != nil {
+		logger.WithError(err).Warn("couldn't load cluster config, deleting only the namespace")
+		cluster = namespaceOnlyCluster(expired.Username, expired.ClusterName)
+	}
+	//Namespaces keyed before the current naming are still reaped
+	cluster.Namespace = expired.Namespace
+
+	cluster.Progress = a.Progress.Start(cluster.Namespace, models.OperationReap)
+	err = cluster.Delete(a.Clientset)
+	cluster.Progress.Done(err)
+	if err == nil {
+		err = models.RemoveStack(a.DB, cluster.Namespace)
+	}
+
+	finishErr := operation.Finish(err)
+	if finishErr != nil {
+		logger.WithError(finishErr).Errorf("failed to save operation %s", operation.ID)
+	}
+
+	return err
+}
@@ -0,0 +1,103 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package api_test
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/topfreegames/mystack-controller/models"
+
+	mTest "github.com/topfreegames/mystack-controller/testing"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var _ = Describe("Reaper", func() {
+	var (
+		clusterName = "myCustomApps"
+		yaml1       = `
+services:
+  test0:
+    image: svc1
+    port: 5000
+apps:
+  test1:
+    image: app1
+    port: 5000
+`
+	)
+
+	createCluster := func(expiresAt time.Time) {
+		mock.
+			ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+			WithArgs(clusterName).
+			WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
+
+		cluster, err := models.NewCluster(app.DB, "user", clusterName, &mTest.MockReadiness{}, &mTest.MockReadiness{}, config)
+		Expect(err).NotTo(HaveOccurred())
+		err = cluster.Create(app.Logger, app.Clientset)
+		Expect(err).NotTo(HaveOccurred())
+		err = models.SetClusterExpiration(app.Clientset, cluster.Namespace, expiresAt)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	Describe("ReapExpiredClusters", func() {
+		It("should delete expired clusters", func() {
+			createCluster(time.Now().Add(-time.Minute))
+
+			mock.
+				ExpectExec("^INSERT INTO operations(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			expectStack(yaml1)
+			mock.
+				ExpectExec("^UPDATE stacks(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.
+				ExpectExec("^UPDATE operations(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			err := app.ReapExpiredClusters()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(models.NamespaceExists(clientset, "mystack-user-mycustomapps-a62c7ed6aa")).To(BeFalse())
+		})
+
+		It("should delete expired cluster even if cluster config doesn't exist anymore", func() {
+			createCluster(time.Now().Add(-time.Minute))
+
+			mock.
+				ExpectExec("^INSERT INTO operations(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			expectNoStack()
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnError(fmt.Errorf("sql: no rows in result set"))
+			mock.
+				ExpectExec("^UPDATE stacks(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.
+				ExpectExec("^UPDATE operations(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			err := app.ReapExpiredClusters()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(models.NamespaceExists(clientset, "mystack-user-mycustomapps-a62c7ed6aa")).To(BeFalse())
+		})
+
+		It("should keep clusters that didn't expire", func() {
+			createCluster(time.Now().Add(time.Hour))
+
+			err := app.ReapExpiredClusters()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(models.NamespaceExists(clientset, "mystack-user-mycustomapps-a62c7ed6aa")).To(BeTrue())
+		})
+	})
+})
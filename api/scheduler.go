@@ -0,0 +1,73 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package api
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/topfreegames/mystack-controller/models"
+)
+
+func (a *App) runScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		err := a.ApplySchedules()
+		if err != nil {
+			a.Logger.WithError(err).Error("failed to apply cluster schedules")
+		}
+	}
+}
+
+//ApplySchedules sleeps or wakes the running clusters that have a schedule
+func (a *App) ApplySchedules() error {
+	logger := a.Logger.WithFields(logrus.Fields{
+		"source":    "api/scheduler.go",
+		"operation": "ApplySchedules",
+	})
+
+	clusters, err := models.RunningClusters(a.Clientset)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, running := range clusters {
+		l := logger.WithField("namespace", running.Namespace)
+
+		cluster, err := models.NewDeployedCluster(
+			a.DB,
+			running.Username,
+			running.ClusterName,
+			a.DeploymentReadiness,
+			a.JobReadiness,
+			a.Config,
+		)
+		if err != nil {
+			l.WithError(err).Warn("couldn't load cluster config, skipping schedule")
+			continue
+		}
+		if cluster.Namespace != running.Namespace {
+			l.Warn("namespace isn't keyed as its cluster, skipping schedule")
+			continue
+		}
+
+		state, err := cluster.ApplySchedule(l, a.Clientset, now)
+		if err != nil {
+			l.WithError(err).Error("failed to apply cluster schedule")
+			continue
+		}
+		if len(state) > 0 {
+			l.Infof("cluster is %s by schedule", state)
+		}
+	}
+
+	return nil
+}
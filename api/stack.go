@@ -0,0 +1,68 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/topfreegames/mystack-controller/errors"
+	"github.com/topfreegames/mystack-controller/models"
+)
+
+//StackHandler lists the stacks deployed for every user
+//Only the emails on admin.emails can list them
+type StackHandler struct {
+	App *App
+}
+
+//ServeHTTP method
+func (s *StackHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	query := r.URL.Query()
+
+	email := emailFromCtx(r.Context())
+	if !s.App.isAdmin(email) {
+		err := errors.NewAccessError("list stacks error", fmt.Errorf("user '%s' is not an admin", email))
+		s.App.HandleError(w, Status(err), "list stacks error", err)
+		return
+	}
+
+	filter := &models.StackFilter{
+		OwnerEmail:  query.Get("owner"),
+		ClusterName: query.Get("cluster"),
+		State:       query.Get("state"),
+	}
+	switch filter.State {
+	case "", models.StackCreating, models.StackRunning, models.StackFailed, models.StackDeleted:
+	default:
+		err := errors.NewGenericError("list stacks error", fmt.Errorf("invalid state: %s", filter.State))
+		s.App.HandleError(w, Status(err), "list stacks error", err)
+		return
+	}
+
+	log(logger, "Listing stacks")
+	stacks, err := models.ListStacks(s.App.DB, filter)
+	if err != nil {
+		s.App.HandleError(w, Status(err), "list stacks error", err)
+		return
+	}
+
+	response := map[string][]*models.Stack{
+		"stacks": stacks,
+	}
+	bts, err := json.Marshal(response)
+	if err != nil {
+		s.App.HandleError(w, Status(err), "list stacks error", err)
+		return
+	}
+
+	WriteBytes(w, http.StatusOK, bts)
+	log(logger, "Successfully listed stacks")
+}
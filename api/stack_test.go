@@ -0,0 +1,81 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/topfreegames/mystack-controller/api"
+	"github.com/topfreegames/mystack-controller/models"
+)
+
+var _ = Describe("Stack", func() {
+	var (
+		recorder *httptest.ResponseRecorder
+		handler  *StackHandler
+		yaml1    = `
+apps:
+  test1:
+    image: app1
+    port: 5000
+`
+	)
+
+	serveAs := func(email, query string) {
+		request, err := http.NewRequest("GET", "/stacks"+query, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx := NewContextWithEmail(request.Context(), email)
+		handler.ServeHTTP(recorder, request.WithContext(ctx))
+	}
+
+	serve := func(query string) {
+		serveAs("admin@example.com", query)
+	}
+
+	BeforeEach(func() {
+		recorder = httptest.NewRecorder()
+		handler = &StackHandler{App: app}
+	})
+
+	It("should list stacks of every user", func() {
+		expectStack(yaml1)
+		serve("?cluster=myCustomApps")
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		bodyJSON := make(map[string][]*models.Stack)
+		json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+		Expect(bodyJSON["stacks"]).To(HaveLen(1))
+		Expect(bodyJSON["stacks"][0].OwnerEmail).To(Equal("user@example.com"))
+		Expect(bodyJSON["stacks"][0].Namespace).To(Equal("mystack-user-mycustomapps-a62c7ed6aa"))
+		Expect(bodyJSON["stacks"][0].Yaml).To(Equal(yaml1))
+	})
+
+	It("should return 403 if user is not an admin", func() {
+		serveAs("user@example.com", "?cluster=myCustomApps")
+
+		Expect(recorder.Code).To(Equal(http.StatusForbidden))
+		bodyJSON := make(map[string]string)
+		json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+		Expect(bodyJSON["description"]).To(Equal("user 'user@example.com' is not an admin"))
+	})
+
+	It("should return 422 if state is invalid", func() {
+		serve("?state=sleeping")
+
+		Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+		bodyJSON := make(map[string]string)
+		json.Unmarshal(recorder.Body.Bytes(), &bodyJSON)
+		Expect(bodyJSON["description"]).To(Equal("invalid state: sleeping"))
+	})
+})
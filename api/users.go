@@ -27,13 +27,13 @@ func (u *UserHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	u.App.Logger.Info("getting email from access token")
 	accessToken := r.FormValue("token")
 
-	token, err := extensions.Token(accessToken, u.App.DB)
+	token, err := extensions.Token(r.Context(), accessToken, u.App.DB)
 	if err != nil {
 		u.App.HandleError(w, Status(err), "user access error", err)
 		return
 	}
 
-	msg, status, err := extensions.Authenticate(token, &models.OSCredentials{}, u.App.DB)
+	msg, status, err := extensions.Authenticate(r.Context(), token, &models.OSCredentials{}, u.App.DB)
 	if err != nil {
 		u.App.HandleError(w, Status(err), "user access error", err)
 		return
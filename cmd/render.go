@@ -0,0 +1,98 @@
+// mystack
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/topfreegames/mystack-controller/models"
+)
+
+var renderFile, renderUser, renderClusterName, renderParameters string
+
+// renderCmd represents the render command
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "renders a cluster config to Kubernetes manifests",
+	Long: `Renders a local cluster config file to the Kubernetes manifests a cluster
+created from it would submit, without deploying anything. Configs that extend
+others must be rendered by the controller, which reads them from the database.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		InitConfig()
+
+		manifests, err := renderClusterConfigFile()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		os.Stdout.Write(manifests)
+	},
+}
+
+func renderClusterConfigFile() ([]byte, error) {
+	if len(renderFile) == 0 {
+		return nil, fmt.Errorf("cluster config file is required")
+	}
+
+	bts, err := ioutil.ReadFile(renderFile)
+	if err != nil {
+		return nil, err
+	}
+	yamlStr := string(bts)
+
+	clusterName := renderClusterName
+	if len(clusterName) == 0 {
+		clusterName = strings.TrimSuffix(filepath.Base(renderFile), filepath.Ext(renderFile))
+	}
+
+	parameters, err := models.ParseParameterValues(renderParameters)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered, err := models.RenderClusterConfig(yamlStr, parameters)
+	if err != nil {
+		return nil, err
+	}
+	clusterConfig, err := models.ParseYaml(rendered)
+	if err != nil {
+		return nil, err
+	}
+	if len(clusterConfig.Extends) > 0 {
+		return nil, fmt.Errorf("cluster config extends '%s', render it with the controller", clusterConfig.Extends)
+	}
+
+	cluster, err := models.NewClusterFromYamlWithOptions(
+		yamlStr,
+		&models.ClusterOptions{Parameters: parameters},
+		renderUser,
+		clusterName,
+		nil, nil,
+		config,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return cluster.Render()
+}
+
+func init() {
+	RootCmd.AddCommand(renderCmd)
+
+	renderCmd.Flags().StringVarP(&renderFile, "file", "f", "", "Cluster config file to render")
+	renderCmd.Flags().StringVarP(&renderUser, "user", "u", "render", "User the cluster is rendered for")
+	renderCmd.Flags().StringVarP(&renderClusterName, "name", "n", "", "Cluster name (default is the file name)")
+	renderCmd.Flags().StringVarP(&renderParameters, "parameters", "p", "", `Parameter values as JSON, like {"replicas": 2}`)
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/Sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/topfreegames/mystack-controller/api"
+	"github.com/topfreegames/mystack-controller/models"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -54,7 +55,12 @@ environment variables to override configuration keys.`,
 			"debug":     debug,
 		})
 
-		clientset, err := getClientset()
+		restConfig, err := getRestConfig()
+		if err != nil {
+			cmdL.WithError(err).Fatal("Failed to start kubernetes clientset.")
+		}
+
+		clientset, err := kubernetes.NewForConfig(restConfig)
 		if err != nil {
 			cmdL.WithError(err).Fatal("Failed to start kubernetes clientset.")
 		}
@@ -71,6 +77,7 @@ environment variables to override configuration keys.`,
 		if err != nil {
 			cmdL.WithError(err).Fatal("Failed to start application.")
 		}
+		app.Executor = models.NewKubernetesExecutor(restConfig)
 		cmdL.Info("Application created successfully.")
 
 		cmdL.Info("Starting application...")
@@ -84,7 +91,7 @@ environment variables to override configuration keys.`,
 	},
 }
 
-func getClientset() (kubernetes.Interface, error) {
+func getRestConfig() (*rest.Config, error) {
 	var config *rest.Config
 	var err error
 
@@ -105,9 +112,8 @@ func getClientset() (kubernetes.Interface, error) {
 	if err != nil {
 		return nil, err
 	}
-	clientset, err := kubernetes.NewForConfig(config)
 
-	return clientset, err
+	return config, nil
 }
 
 func init() {
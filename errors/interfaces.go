@@ -11,3 +11,8 @@ package errors
 type SerializableError interface {
 	Serialize() []byte
 }
+
+//DetailedError is an error that carries more information about the failure
+type DetailedError interface {
+	Details() interface{}
+}
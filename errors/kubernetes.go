@@ -13,6 +13,7 @@ import "encoding/json"
 type KubernetesError struct {
 	sourceError error
 	message     string
+	details     interface{}
 }
 
 //NewKubernetesError ctor
@@ -27,17 +28,36 @@ func NewKubernetesError(message string, err error) *KubernetesError {
 	}
 }
 
+//NewKubernetesErrorWithDetails ctor of an error that carries
+//what was found about the failure, like pod events and logs
+func NewKubernetesErrorWithDetails(message string, err error, details interface{}) *KubernetesError {
+	return &KubernetesError{
+		sourceError: err,
+		message:     message,
+		details:     details,
+	}
+}
+
+//Details returns what was found about the failure, nil if nothing
+func (e *KubernetesError) Details() interface{} {
+	return e.details
+}
+
 func (e *KubernetesError) Error() string {
 	return e.sourceError.Error()
 }
 
 //Serialize returns the error serialized
 func (e *KubernetesError) Serialize() []byte {
-	g, _ := json.Marshal(map[string]interface{}{
+	body := map[string]interface{}{
 		"code":        "MST-004",
 		"error":       e.message,
 		"description": e.sourceError.Error(),
-	})
+	}
+	if e.details != nil {
+		body["details"] = e.details
+	}
 
+	g, _ := json.Marshal(body)
 	return g
 }
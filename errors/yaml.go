@@ -13,6 +13,7 @@ import "encoding/json"
 type YamlError struct {
 	sourceError error
 	message     string
+	details     interface{}
 }
 
 //NewYamlError ctor
@@ -27,17 +28,36 @@ func NewYamlError(message string, err error) *YamlError {
 	}
 }
 
+//NewYamlErrorWithDetails ctor of an error that carries
+//every problem found on the yaml
+func NewYamlErrorWithDetails(message string, err error, details interface{}) *YamlError {
+	return &YamlError{
+		sourceError: err,
+		message:     message,
+		details:     details,
+	}
+}
+
+//Details returns the problems found on the yaml, nil if not known
+func (e *YamlError) Details() interface{} {
+	return e.details
+}
+
 func (e *YamlError) Error() string {
 	return e.sourceError.Error()
 }
 
 //Serialize returns the error serialized
 func (e *YamlError) Serialize() []byte {
-	g, _ := json.Marshal(map[string]interface{}{
+	body := map[string]interface{}{
 		"code":        "MST-004",
 		"error":       e.message,
 		"description": e.sourceError.Error(),
-	})
+	}
+	if e.details != nil {
+		body["details"] = e.details
+	}
 
+	g, _ := json.Marshal(body)
 	return g
 }
@@ -8,19 +8,16 @@
 package extensions_test
 
 import (
-	"database/sql"
-
-	"github.com/jmoiron/sqlx"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	mTest "github.com/topfreegames/mystack-controller/testing"
 	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
 
 	"testing"
 )
 
 var (
-	db     *sql.DB
-	sqlxDB *sqlx.DB
+	sqlxDB *mTest.MockDB
 	mock   sqlmock.Sqlmock
 	err    error
 )
@@ -31,13 +28,12 @@ func TestExtensions(t *testing.T) {
 }
 
 var _ = BeforeEach(func() {
-	db, mock, err = sqlmock.New()
+	sqlxDB, mock, err = mTest.NewMockDB()
 	Expect(err).NotTo(HaveOccurred())
-	sqlxDB = sqlx.NewDb(db, "postgres")
 })
 
 var _ = AfterEach(func() {
-	defer db.Close()
+	defer sqlxDB.Close()
 	err = mock.ExpectationsWereMet()
 	Expect(err).NotTo(HaveOccurred())
 })
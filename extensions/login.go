@@ -8,6 +8,7 @@
 package extensions
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -80,6 +81,7 @@ func GetAccessToken(code string) (*oauth2.Token, error) {
 //Authenticate authenticates an access token or gets a new one with the refresh token
 //The returned string is either the error message or the user email
 func Authenticate(
+	ctx context.Context,
 	token *oauth2.Token,
 	credentials models.Credentials,
 	db models.DB,
@@ -130,7 +132,7 @@ func Authenticate(
 	json.Unmarshal(bts, &bodyObj)
 	email = bodyObj["email"].(string)
 	if expired {
-		err = SaveToken(newToken, email, token.AccessToken, db)
+		err = SaveToken(ctx, newToken, email, token.AccessToken, db)
 		if err != nil {
 			return email, http.StatusInternalServerError, errors.NewDatabaseError(err)
 		}
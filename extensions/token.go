@@ -7,6 +7,7 @@
 package extensions
 
 import (
+	"context"
 	"time"
 
 	"github.com/topfreegames/mystack-controller/errors"
@@ -15,7 +16,7 @@ import (
 )
 
 //SaveToken writes the token parameters on DB
-func SaveToken(token *oauth2.Token, email, keyAccessToken string, db models.DB) error {
+func SaveToken(ctx context.Context, token *oauth2.Token, email, keyAccessToken string, db models.DB) error {
 	query := `INSERT INTO users(access_token, refresh_token, expiry, token_type, email, key_access_token) 
 	VALUES(:access_token, :refresh_token, :expiry, :token_type, :email, :key_access_token)
 	ON CONFLICT(email) DO UPDATE
@@ -40,7 +41,7 @@ func SaveToken(token *oauth2.Token, email, keyAccessToken string, db models.DB)
 		"email":            email,
 		"key_access_token": keyAccessToken,
 	}
-	_, err := db.NamedExec(query, values)
+	_, err := db.NamedExecContext(ctx, query, values)
 
 	if err != nil {
 		return errors.NewDatabaseError(err)
@@ -50,7 +51,7 @@ func SaveToken(token *oauth2.Token, email, keyAccessToken string, db models.DB)
 }
 
 //Token reads token from DB
-func Token(accessToken string, db models.DB) (*oauth2.Token, error) {
+func Token(ctx context.Context, accessToken string, db models.DB) (*oauth2.Token, error) {
 	query := `SELECT access_token, refresh_token, expiry, token_type
 						FROM users
 						WHERE key_access_token = $1`
@@ -62,7 +63,7 @@ func Token(accessToken string, db models.DB) (*oauth2.Token, error) {
 		TokenType    string    `db:"token_type"`
 	}{}
 
-	err := db.Get(&destToken, query, accessToken)
+	err := db.GetContext(ctx, &destToken, query, accessToken)
 	if err != nil {
 		return nil, errors.NewAccessError("Access Token not found (have you logged in?)", err)
 	}
@@ -1,6 +1,7 @@
 package extensions_test
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -45,7 +46,7 @@ var _ = Describe("Token", func() {
 				TokenType:    tokenType,
 			}
 
-			err := SaveToken(token, email, accessToken, sqlxDB)
+			err := SaveToken(context.Background(), token, email, accessToken, sqlxDB)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
@@ -70,7 +71,7 @@ var _ = Describe("Token", func() {
 				TokenType:    tokenType,
 			}
 
-			err := SaveToken(token, email, accessToken, sqlxDB)
+			err := SaveToken(context.Background(), token, email, accessToken, sqlxDB)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
@@ -92,7 +93,7 @@ var _ = Describe("Token", func() {
 				TokenType:    tokenType,
 			}
 
-			err := SaveToken(token, email, accessToken, sqlxDB)
+			err := SaveToken(context.Background(), token, email, accessToken, sqlxDB)
 			Expect(err).To(HaveOccurred())
 			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.DatabaseError"))
 		})
@@ -111,7 +112,7 @@ var _ = Describe("Token", func() {
 				WithArgs(accessToken).
 				WillReturnRows(rows)
 
-			token, err := Token(accessToken, sqlxDB)
+			token, err := Token(context.Background(), accessToken, sqlxDB)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(token.AccessToken).To(Equal(accessToken))
 			Expect(token.RefreshToken).To(Equal(refreshToken))
@@ -128,7 +129,7 @@ var _ = Describe("Token", func() {
 				WithArgs(accessToken).
 				WillReturnError(fmt.Errorf("sql: no rows in result set"))
 
-			_, err := Token(accessToken, sqlxDB)
+			_, err := Token(context.Background(), accessToken, sqlxDB)
 			Expect(err).To(HaveOccurred())
 			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.AccessError"))
 		})
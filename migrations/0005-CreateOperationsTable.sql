@@ -0,0 +1,22 @@
+-- mystack-controller api
+-- https://github.com/topfreegames/mystack-controller
+--
+-- Licensed under the MIT license:
+-- http://www.opensource.org/licenses/mit-license
+-- Copyright © 2016 Top Free Games <backend@tfgco.com>
+
+CREATE TABLE operations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    kind varchar(255) NOT NULL,
+    cluster_name varchar(255) NOT NULL,
+    username varchar(255) NOT NULL,
+    status varchar(255) NOT NULL,
+    phase varchar(255) NOT NULL DEFAULT '',
+    steps TEXT NOT NULL DEFAULT '[]',
+    error TEXT NOT NULL DEFAULT '',
+    created_at timestamp WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at timestamp WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    finished_at timestamp WITH TIME ZONE
+);
+
+CREATE INDEX ON operations (username, cluster_name);
@@ -0,0 +1,21 @@
+-- mystack-controller api
+-- https://github.com/topfreegames/mystack-controller
+--
+-- Licensed under the MIT license:
+-- http://www.opensource.org/licenses/mit-license
+-- Copyright © 2016 Top Free Games <backend@tfgco.com>
+
+CREATE TABLE stacks (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    owner_email varchar(255) NOT NULL,
+    username varchar(255) NOT NULL,
+    namespace varchar(255) NOT NULL,
+    cluster_name varchar(255) NOT NULL,
+    yaml TEXT NOT NULL,
+    state varchar(255) NOT NULL,
+    created_at timestamp WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at timestamp WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ON stacks (namespace, state);
+CREATE INDEX ON stacks (username);
@@ -0,0 +1,8 @@
+-- mystack-controller api
+-- https://github.com/topfreegames/mystack-controller
+--
+-- Licensed under the MIT license:
+-- http://www.opensource.org/licenses/mit-license
+-- Copyright © 2016 Top Free Games <backend@tfgco.com>
+
+ALTER TABLE stacks ADD COLUMN overrides TEXT NOT NULL DEFAULT '';
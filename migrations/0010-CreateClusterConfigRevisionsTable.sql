@@ -0,0 +1,19 @@
+-- mystack-controller api
+-- https://github.com/topfreegames/mystack-controller
+--
+-- Licensed under the MIT license:
+-- http://www.opensource.org/licenses/mit-license
+-- Copyright © 2016 Top Free Games <backend@tfgco.com>
+
+CREATE TABLE cluster_config_revisions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    cluster_name varchar(255) NOT NULL,
+    revision integer NOT NULL,
+    yaml TEXT NOT NULL,
+    author_email varchar(255) NOT NULL DEFAULT '',
+    created_at timestamp WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (cluster_name, revision)
+);
+
+INSERT INTO cluster_config_revisions(cluster_name, revision, yaml)
+SELECT name, 1, yaml FROM clusters;
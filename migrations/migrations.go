@@ -4,6 +4,12 @@
 // migrations/0002-CreateClusterTable.sql
 // migrations/0003-AlterUserTableColumnKeyAccessToken.sql
 // migrations/0004-AlterTableUsersExpiryWithTimestamp.sql
+// migrations/0005-CreateOperationsTable.sql
+// migrations/0006-CreateStacksTable.sql
+// migrations/0007-AlterOperationsTableErrorDetails.sql
+// migrations/0008-AlterStacksTableOverrides.sql
+// migrations/0009-AlterStacksTableParameters.sql
+// migrations/0010-CreateClusterConfigRevisionsTable.sql
 // DO NOT EDIT!
 
 package migrations
@@ -151,6 +157,126 @@ func migrations0004AltertableusersexpirywithtimestampSql() (*asset, error) {
 	return a, nil
 }
 
+var _migrations0005CreateoperationstableSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa5\x92\xcd\x4e\x02\x31\x14\x85\xf7\xf3\x14\x77\x27\x24\x0c\xa3\x46\x5d\xa0\x31\xa2\x54\x9d\x38\x0c\x86\x74\x02\x6a\x0c\x29\x9d\x32\xd3\x00\x6d\xd3\x1f\x89\x8f\xe4\x6b\xf8\x64\x76\xe4\x27\x31\x08\x2e\xec\xae\xb7\xe7\x3b\x3d\xbd\xb7\x61\x08\xf3\x77\x63\x09\x9d\x86\x54\x0a\xab\xe5\x6c\xc6\x34\x10\xc5\x83\x30\x84\xd2\x5a\x65\x5a\x51\x54\x70\x5b\xba\x71\x93\xca\x79\x64\xa5\x9a\x68\xc6\x0a\x32\x67\x26\xda\x26\x3d\x55\x81\x09\xa7\x4c\x18\x96\x83\x13\xb9\xb7\xb3\x25\x83\x6e\x8c\x61\xb6\x2c\xb7\xd6\xde\xde\x7a\xb1\x58\x34\xa5\xf2\x55\xe9\x34\x65\x4d\xa9\x8b\x68\xa5\xf2\xf6\xdc\x86\xab\x4d\x45\xdc\x48\xf5\xae\x79\x51\x5a\xf8\xfc\x80\xe3\xc3\xa3\x33\xc0\x52\xc1\xad\x4f\x03\x77\x55\x1c\xb8\x18\xfb\x30\x4c\xe4\x57\x76\x52\x50\x59\xc5\xbd\x0c\x82\x9b\x3e\x6a\x63\x04\xb8\x7d\x9d\x20\xf0\x37\x69\x62\xb9\x14\x06\x6a\x01\xf8\xc5\x73\xc8\xb2\xb8\x03\x8f\xfd\xb8\xdb\xee\x3f\xc1\x03\x7a\x82\x0e\xba\x6d\x67\x09\x06\xe7\x78\x3e\x2a\x98\xa8\x10\x36\x7a\x3b\xa9\xd5\x1b\xdf\xcc\x94\x8b\x1c\xde\x88\xa6\x25\xd1\xb5\xe3\xd3\xd3\x3a\xa4\x3d\x0c\x69\x96\x24\xcb\x73\x3a\x73\xc6\x32\x3d\x12\x3e\xd4\x3e\x9d\x33\x4c\xff\xa5\xf1\xfd\xb5\xce\xec\x53\xa8\x92\x98\x1d\x16\x9b\x97\x1c\x1c\xac\xdd\x98\x32\x80\xd1\x10\xff\xa2\x79\x79\x5d\xa9\x98\xd6\x52\xef\x52\xad\x34\x54\x33\xdf\x94\x7c\x44\x2c\x58\xee\x7b\x6f\xc9\x5c\xc1\x20\xc6\xf7\x80\xe3\x2e\x82\xe7\x5e\x8a\xb6\xe1\xb4\x37\x58\xf7\xd0\xa9\xfc\x5f\xfc\x84\x0b\x6e\xca\xfd\x06\x41\xfd\x7c\x33\xfe\x38\xed\xa0\x21\xf4\xd2\x1f\x3f\x60\x3d\x80\xc6\x8f\x91\x79\xea\x0b\x76\x81\xf8\x87\x15\x03\x00\x00")
+
+func migrations0005CreateoperationstableSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations0005CreateoperationstableSql,
+		"migrations/0005-CreateOperationsTable.sql",
+	)
+}
+
+func migrations0005CreateoperationstableSql() (*asset, error) {
+	bytes, err := migrations0005CreateoperationstableSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/0005-CreateOperationsTable.sql", size: 789, mode: os.FileMode(420), modTime: time.Unix(1792189004, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations0006CreatestackstableSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x9d\x51\xdd\x4e\xc2\x30\x18\xbd\xdf\x53\x7c\x97\x90\x30\xa6\x44\xbc\x50\x63\x9c\x50\x74\x71\x6c\x86\x74\x01\xbc\x59\x4a\x57\xb6\xc6\x6d\x6d\xba\x0e\xc2\x23\xf9\x1a\x3e\x99\x1d\x3f\xbb\xd0\x88\x89\xbd\xeb\xe9\x39\xe7\x3b\xdf\xa9\x6d\x43\xb1\xab\x34\xa1\xef\x36\x15\xa5\x56\x22\xcf\x99\x02\x22\xb9\x65\xdb\x90\x69\x2d\xab\x1b\xc7\x49\xb9\xce\xea\x55\x9f\x8a\xc2\xd1\x42\xae\x15\x63\x29\x29\x58\xe5\xfc\x54\x1a\x55\x23\xf4\x39\x65\x65\xc5\x12\xa8\xcb\xc4\xd8\xe9\x8c\xc1\xd4\xc3\x90\x1f\xe0\x9b\x93\xb7\xb1\xde\x6e\xb7\x7d\x21\x0d\x2a\x6a\x45\x59\x5f\xa8\xd4\x39\xb2\x8c\x3d\xd7\xf6\xf1\xd2\x28\x46\x42\xee\x14\x4f\x33\x0d\x9f\x1f\x30\xb8\xb8\xbc\x06\x2c\x24\x4c\x4c\x1a\x78\x6a\xe2\xc0\xdd\xca\x84\x61\x65\xf2\xa0\xd7\x29\x15\x4d\xdc\x7b\xcb\x1a\xcd\x90\x8b\x11\x60\xf7\xd1\x47\xb0\x8f\x5b\x41\xc7\x02\x73\x78\x02\x51\xe4\x8d\xe1\x75\xe6\x4d\xdd\xd9\x12\x5e\xd0\x12\xc6\x68\xe2\x46\x3e\x86\xba\xe6\x49\x9c\xb2\x92\x29\xa2\x59\xbc\xb9\xea\x74\x7b\x7b\x8d\xd8\x1a\x28\x66\x05\xe1\x39\x6c\x88\xa2\x19\x51\x9d\xc1\x70\xd8\x85\x20\xc4\x10\x44\xbe\x7f\xa0\xd5\x15\x53\xa5\xc9\x74\x8e\xd3\xbc\x57\x92\xd0\xb3\x24\x9a\xd7\x95\x36\x13\xff\x32\xdb\x91\x22\x07\x8c\x16\xf8\x1b\x6e\x16\xd6\xe7\x07\x28\x66\x18\x49\x4c\x34\x68\x6e\x02\x69\x52\x48\x98\x7b\xf8\x19\xb0\x37\x45\xf0\x16\x06\xa8\x55\xb4\xed\x04\xe1\xfc\x54\x48\x2d\x93\x7f\xea\xad\xee\x6d\xfb\x3b\x5e\x30\x46\x0b\x08\x83\xf6\x83\xda\x72\x7a\x87\x15\x0c\xf7\x37\xea\xa9\x6b\x43\xf9\x02\x12\x9c\x4e\x07\xcd\x02\x00\x00")
+
+func migrations0006CreatestackstableSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations0006CreatestackstableSql,
+		"migrations/0006-CreateStacksTable.sql",
+	)
+}
+
+func migrations0006CreatestackstableSql() (*asset, error) {
+	bytes, err := migrations0006CreatestackstableSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/0006-CreateStacksTable.sql", size: 717, mode: os.FileMode(420), modTime: time.Unix(1792190148, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations0007AlteroperationstableerrordetailsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x65\x8e\xb1\x4e\xc3\x30\x10\x86\xf7\x3c\xc5\x6d\x9d\x9c\x00\x03\x43\x41\x88\xd0\xa4\x08\xc9\x6d\x25\xe4\x48\x6c\x55\xea\x5c\x1d\x8b\xc4\x67\xd9\x17\x45\x7d\x24\x5e\x83\x27\xc3\x11\x65\x62\xb8\xe1\x7e\xdd\xf7\xdf\x27\x04\x8c\x97\xc8\xad\xfe\x14\x9a\x1c\x07\x1a\x06\x0c\xd0\x7a\x9b\x09\x01\x3d\xb3\x8f\xeb\xa2\x30\x96\xfb\xe9\x94\x6b\x1a\x0b\x26\x7f\x0e\x88\xa6\x1d\x31\x16\xff\xc9\x44\x2d\xa0\xb4\x1a\x5d\xc4\x0e\x26\xd7\xa5\x3a\xee\x11\x76\x6f\x0a\x86\xdf\x78\xfd\xd7\x9d\xaa\xe7\x79\xce\xc9\xa7\x94\xa6\xa0\x31\xa7\x60\x8a\xeb\x55\xaa\xb7\x2c\xae\xcb\x42\x6c\xc8\x5f\x82\x35\x3d\xc3\xf7\x17\xdc\xdd\xdc\xde\x83\x22\x0f\xdb\x64\x03\xaf\x8b\x0e\x3c\x9e\x92\x0c\xba\xee\x99\xcf\x46\xd3\xa2\xfb\x94\x65\xa5\x54\xf5\x3b\xa8\xf2\x45\xd6\x90\x1e\x85\x96\x2d\xb9\x08\x65\x55\xc1\xe6\x20\x9b\xdd\x1e\x30\x04\x0a\xc7\x0e\xb9\xb5\x43\x04\x55\x7f\x28\xd8\x1f\xd2\x34\x52\x42\x55\x6f\xcb\x46\x2a\x58\xad\x1e\xb2\x1f\x7c\xc1\x97\x6a\x2b\x01\x00\x00")
+
+func migrations0007AlteroperationstableerrordetailsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations0007AlteroperationstableerrordetailsSql,
+		"migrations/0007-AlterOperationsTableErrorDetails.sql",
+	)
+}
+
+func migrations0007AlteroperationstableerrordetailsSql() (*asset, error) {
+	bytes, err := migrations0007AlteroperationstableerrordetailsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/0007-AlterOperationsTableErrorDetails.sql", size: 299, mode: os.FileMode(420), modTime: time.Unix(1792190785, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations0008AlterstackstableoverridesSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x65\x8e\xb1\x4e\xc3\x30\x10\x40\xf7\x7c\xc5\x6d\x9d\x1c\x03\x03\x43\x41\x88\xd0\xa4\x08\xc9\x6d\x25\xe4\x48\xac\xa9\x73\x75\x2c\x92\x9c\x65\x5f\x88\xfa\x49\xfc\x06\x5f\x86\x03\x65\x62\xb8\xe1\x4e\xf7\x9e\x9e\x10\x30\x9c\x23\x37\xe6\x5d\x18\x1a\x39\x50\xdf\x63\x80\xc6\xbb\x4c\x08\xe8\x98\x7d\x5c\x4b\x69\x1d\x77\xd3\x31\x37\x34\x48\x26\x7f\x0a\x88\xb6\x19\x30\xca\xff\x64\xa2\x16\x50\x39\x83\x63\xc4\x16\xa6\xb1\x4d\x3a\xee\x10\x76\x2f\x1a\xfa\xdf\xf3\xfa\xcf\x9d\xd4\xf3\x3c\xe7\xe4\xd3\x95\xa6\x60\x30\xa7\x60\xe5\xe5\x2b\xe9\x1d\x8b\xcb\xb2\x10\x1b\xf2\xe7\xe0\x6c\xc7\xf0\xf5\x09\x37\x57\xd7\xb7\xa0\xc9\xc3\x36\xd5\xc0\xf3\x92\x03\xf7\xc7\x14\x83\x63\xfb\xc8\x27\x6b\x68\xc9\x7d\xc8\xb2\x42\xe9\xea\x15\x74\xf1\xa4\x2a\xf8\xa9\x8d\x50\x94\x25\x6c\x0e\xaa\xde\xed\x81\x3e\x30\x04\xd7\x26\x58\x57\x6f\x1a\xf6\x87\x34\xb5\x52\x50\x56\xdb\xa2\x56\x1a\x56\xab\xbb\xec\x1b\xe9\x5f\x83\xf1\x23\x01\x00\x00")
+
+func migrations0008AlterstackstableoverridesSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations0008AlterstackstableoverridesSql,
+		"migrations/0008-AlterStacksTableOverrides.sql",
+	)
+}
+
+func migrations0008AlterstackstableoverridesSql() (*asset, error) {
+	bytes, err := migrations0008AlterstackstableoverridesSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/0008-AlterStacksTableOverrides.sql", size: 291, mode: os.FileMode(420), modTime: time.Unix(1792191816, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations0009AlterstackstableparametersSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x65\x8e\xb1\x4e\xc3\x30\x10\x86\xf7\x3c\xc5\xbf\x75\x72\x0c\x0c\x0c\x05\x21\xd2\x26\x45\x48\x6e\x2b\x21\x47\x62\x4d\x1d\xd7\xb1\x48\x62\xcb\xbe\x28\xea\x23\xf1\x1a\x3c\x19\x0e\x94\x89\xe1\x86\x3b\xdd\xf7\xe9\x63\x0c\xc3\x25\x52\xa3\x3e\x98\x72\x23\x05\xd7\xf7\x3a\xa0\xf1\x36\x63\x0c\x1d\x91\x8f\x6b\xce\x8d\xa5\x6e\x3a\xe5\xca\x0d\x9c\x9c\x3f\x07\xad\x4d\x33\xe8\xc8\xff\x93\x89\x5a\x40\x61\x95\x1e\xa3\x6e\x31\x8d\x6d\xd2\x51\xa7\xb1\x7f\x95\xe8\x7f\xcf\xeb\x3f\x77\x52\xcf\xf3\x9c\x3b\x9f\xae\x6e\x0a\x4a\xe7\x2e\x18\x7e\xfd\x4a\x7a\x4b\xec\xba\x2c\xc4\xd6\xf9\x4b\xb0\xa6\x23\x7c\x7d\xe2\xee\xe6\xf6\x1e\xd2\x79\xec\x52\x0d\x5e\x96\x1c\x3c\x9e\x52\x8c\x1e\xdb\x67\x3a\x1b\xe5\x96\xdc\xa7\x2c\x2b\x84\xac\xde\x20\x8b\x8d\xa8\xf0\x53\x1b\x51\x94\x25\xb6\x47\x51\xef\x0f\xf0\x4d\x48\x28\xe9\x10\x21\xab\x77\x89\xc3\x31\x4d\x2d\x04\xca\x6a\x57\xd4\x42\x62\xb5\x7a\xc8\xbe\x01\x86\x6f\xf8\xf0\x24\x01\x00\x00")
+
+func migrations0009AlterstackstableparametersSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations0009AlterstackstableparametersSql,
+		"migrations/0009-AlterStacksTableParameters.sql",
+	)
+}
+
+func migrations0009AlterstackstableparametersSql() (*asset, error) {
+	bytes, err := migrations0009AlterstackstableparametersSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/0009-AlterStacksTableParameters.sql", size: 292, mode: os.FileMode(420), modTime: time.Unix(1792192112, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _migrations0010CreateclusterconfigrevisionstableSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x75\x90\xcf\x4e\xe3\x30\x10\x87\xef\x7e\x8a\xb9\xd1\x48\x4d\x03\x68\xd9\x03\xa0\x15\xa5\xb8\x6c\xb4\x69\xb2\x04\x47\xfc\xb9\x44\x26\x99\x26\x16\x49\x1c\x39\x93\x56\x7d\xa4\x7d\x8d\x7d\xb2\x75\x69\x83\x60\x01\xdf\x3c\xe3\xdf\xe7\x6f\xc6\x75\xa1\xde\x74\x24\xb3\x67\x37\xd3\x0d\x19\x5d\x55\x68\x40\xb6\x8a\xb9\x2e\x94\x44\x6d\x77\xea\x79\x85\xa2\xb2\x7f\x9a\x64\xba\xf6\x48\xb7\x4b\x83\x58\xc8\x1a\x3b\xef\x63\xd2\xa6\xb6\xc1\x40\x65\xd8\x74\x98\x43\xdf\xe4\x16\x47\x25\xc2\xc2\x17\x50\xed\xca\xa7\x03\xdb\xa2\xd7\xeb\xf5\x44\xb7\xb6\xaa\x7b\x93\xe1\x44\x9b\xc2\xdb\xbf\xb2\x78\x45\xee\xfe\xb2\x4d\xcc\x74\xbb\x31\xaa\x28\x09\xfe\xfe\x81\xe3\xc3\xa3\xef\x20\x74\x0b\x73\x6b\x03\xd7\x5b\x1d\x38\x7f\xb2\x32\xd8\xe4\x17\xb4\x2c\x32\xbd\xd5\xfd\xc1\xd8\x2c\xe6\x53\xc1\x41\x4c\x2f\x03\x0e\x59\xd5\x77\x84\x26\xb5\xc2\x4b\x55\xa4\x06\x57\xaa\x53\xba\xe9\x60\xc4\xc0\x1e\x95\x43\x92\xf8\x57\xf0\x3b\xf6\x17\xd3\xf8\x01\x7e\xf1\x07\xb8\xe2\xf3\x69\x12\x08\xe8\x7b\x95\xa7\x05\x36\x68\x24\x61\xba\xfa\x36\x72\xc6\x2f\x99\x01\xd9\x58\x05\x58\x49\x93\x95\xd2\x8c\x8e\x4f\x4e\x1c\x08\x23\x01\x61\x12\x04\xbb\x77\xc3\x5f\xa0\x1a\xc2\xc2\x2e\xe5\x7d\x7b\x23\xeb\x0a\x04\xbf\x17\xff\xd5\x65\x4f\xa5\x36\x29\xd6\x52\x55\x9f\xe3\x5f\x0d\x0f\x0e\xf6\x46\x06\xad\x62\x9e\x4a\x02\x52\x76\x2f\x24\xeb\x16\xee\x7c\xf1\x13\x84\xbf\xe0\xf0\x18\x85\xfc\x63\x38\x8c\xee\x86\x89\x92\xd0\xbf\x49\x38\x8c\xde\x4e\x36\x7e\xf5\x77\x98\x73\xc6\x98\x1f\xde\xf2\x58\x80\x1f\x8a\xe8\xcb\xa5\x7e\x01\x18\xbf\xcc\xea\xb0\x5b\x1e\xf0\x99\x80\x5d\xf3\x68\x57\x85\x79\x1c\x2d\x06\x60\x77\xc6\xfe\x01\x18\xc2\x03\x89\x9e\x02\x00\x00")
+
+func migrations0010CreateclusterconfigrevisionstableSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations0010CreateclusterconfigrevisionstableSql,
+		"migrations/0010-CreateClusterConfigRevisionsTable.sql",
+	)
+}
+
+func migrations0010CreateclusterconfigrevisionstableSql() (*asset, error) {
+	bytes, err := migrations0010CreateclusterconfigrevisionstableSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/0010-CreateClusterConfigRevisionsTable.sql", size: 670, mode: os.FileMode(420), modTime: time.Unix(1792192409, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -207,6 +333,12 @@ var _bindata = map[string]func() (*asset, error){
 	"migrations/0002-CreateClusterTable.sql": migrations0002CreateclustertableSql,
 	"migrations/0003-AlterUserTableColumnKeyAccessToken.sql": migrations0003AlterusertablecolumnkeyaccesstokenSql,
 	"migrations/0004-AlterTableUsersExpiryWithTimestamp.sql": migrations0004AltertableusersexpirywithtimestampSql,
+	"migrations/0005-CreateOperationsTable.sql": migrations0005CreateoperationstableSql,
+	"migrations/0006-CreateStacksTable.sql": migrations0006CreatestackstableSql,
+	"migrations/0007-AlterOperationsTableErrorDetails.sql": migrations0007AlteroperationstableerrordetailsSql,
+	"migrations/0008-AlterStacksTableOverrides.sql": migrations0008AlterstackstableoverridesSql,
+	"migrations/0009-AlterStacksTableParameters.sql": migrations0009AlterstackstableparametersSql,
+	"migrations/0010-CreateClusterConfigRevisionsTable.sql": migrations0010CreateclusterconfigrevisionstableSql,
 }
 
 // AssetDir returns the file names below a certain
@@ -254,6 +386,12 @@ var _bintree = &bintree{nil, map[string]*bintree{
 		"0002-CreateClusterTable.sql": &bintree{migrations0002CreateclustertableSql, map[string]*bintree{}},
 		"0003-AlterUserTableColumnKeyAccessToken.sql": &bintree{migrations0003AlterusertablecolumnkeyaccesstokenSql, map[string]*bintree{}},
 		"0004-AlterTableUsersExpiryWithTimestamp.sql": &bintree{migrations0004AltertableusersexpirywithtimestampSql, map[string]*bintree{}},
+		"0005-CreateOperationsTable.sql": &bintree{migrations0005CreateoperationstableSql, map[string]*bintree{}},
+		"0006-CreateStacksTable.sql": &bintree{migrations0006CreatestackstableSql, map[string]*bintree{}},
+		"0007-AlterOperationsTableErrorDetails.sql": &bintree{migrations0007AlteroperationstableerrordetailsSql, map[string]*bintree{}},
+		"0008-AlterStacksTableOverrides.sql": &bintree{migrations0008AlterstackstableoverridesSql, map[string]*bintree{}},
+		"0009-AlterStacksTableParameters.sql": &bintree{migrations0009AlterstackstableparametersSql, map[string]*bintree{}},
+		"0010-CreateClusterConfigRevisionsTable.sql": &bintree{migrations0010CreateclusterconfigrevisionstableSql, map[string]*bintree{}},
 	}},
 }}
 
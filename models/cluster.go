@@ -8,7 +8,9 @@
 package models
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -26,6 +28,8 @@ import (
 type Cluster struct {
 	Namespace              string
 	Username               string
+	OwnerEmail             string
+	ClusterName            string
 	AppDeployments         []*Deployment
 	SvcDeployments         []*Deployment
 	K8sServices            map[*Deployment]*Service
@@ -33,6 +37,44 @@ type Cluster struct {
 	PersistentVolumeClaims []*PersistentVolumeClaim
 	DeploymentReadiness    Readiness
 	JobReadiness           Readiness
+	Tracker                Tracker
+	Progress               *Progress
+	TTL                    time.Duration
+	Schedule               *Schedule
+	ConfigYaml             string
+	RolloutWorkers         int
+	KeepOnFailure          bool
+	Overrides              Overrides
+	Parameters             ParameterValues
+
+	//createdNamespace is set when Claim created the namespace,
+	//only then a failed creation can delete it
+	createdNamespace bool
+	//leaseID is the creation lease taken by Claim
+	leaseID string
+}
+
+//Steps of the cluster creation pipeline
+const (
+	StepNamespace      = "namespace"
+	StepVolumes        = "volumes"
+	StepSvcDeployments = "svc-deployments"
+	StepSetup          = "setup"
+	StepAppDeployments = "app-deployments"
+	StepPostSetup      = "post-setup"
+)
+
+//Steps of the cluster deletion
+const (
+	StepDeleteApps      = "delete-apps"
+	StepDeleteNamespace = "delete-namespace"
+)
+
+//ClusterOptions are what a user chooses when creating a cluster
+//They are recorded with the stack, so the cluster is always built the same way
+type ClusterOptions struct {
+	Overrides  Overrides       `json:"apps"`
+	Parameters ParameterValues `json:"parameters"`
 }
 
 //NewCluster returns a new cluster ready to start
@@ -42,35 +84,132 @@ func NewCluster(
 	deploymentReadiness, jobReadiness Readiness,
 	config *viper.Viper,
 ) (*Cluster, error) {
-	namespace := usernameToNamespace(username)
+	return NewClusterWithOptions(db, username, clusterName, nil, deploymentReadiness, jobReadiness, config)
+}
+
+//NewClusterWithOptions returns a new cluster ready to start
+//with the parameters and overrides of options
+func NewClusterWithOptions(
+	db DB,
+	username, clusterName string,
+	options *ClusterOptions,
+	deploymentReadiness, jobReadiness Readiness,
+	config *viper.Viper,
+) (*Cluster, error) {
+	yamlStr, err := loadClusterConfigYaml(context.Background(), db, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewClusterFromYamlWithOptions(yamlStr, options, username, clusterName, deploymentReadiness, jobReadiness, config)
+}
+
+//NewClusterFromYaml returns a new cluster built from a cluster config yaml
+func NewClusterFromYaml(
+	yamlStr, username, clusterName string,
+	deploymentReadiness, jobReadiness Readiness,
+	config *viper.Viper,
+) (*Cluster, error) {
+	return NewClusterFromYamlWithOptions(yamlStr, nil, username, clusterName, deploymentReadiness, jobReadiness, config)
+}
+
+//NewClusterFromStack returns the cluster as it was created for the stack,
+//with the yaml and options recorded with it
+func NewClusterFromStack(
+	stack *Stack,
+	deploymentReadiness, jobReadiness Readiness,
+	config *viper.Viper,
+) (*Cluster, error) {
+	cluster, err := NewClusterFromYamlWithOptions(
+		stack.Yaml, stack.Options(),
+		stack.Username, stack.ClusterName,
+		deploymentReadiness, jobReadiness, config,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	cluster.OwnerEmail = stack.OwnerEmail
+	return cluster, nil
+}
+
+//NewClusterFromYamlWithOptions returns a new cluster built from a cluster config yaml
+//rendered with the parameters of options and changed by its overrides
+func NewClusterFromYamlWithOptions(
+	yamlStr string,
+	options *ClusterOptions,
+	username, clusterName string,
+	deploymentReadiness, jobReadiness Readiness,
+	config *viper.Viper,
+) (*Cluster, error) {
+	return newClusterFromYaml(yamlStr, options, true, username, clusterName, deploymentReadiness, jobReadiness, config)
+}
+
+//newClusterFromYaml builds the cluster of NewClusterFromYamlWithOptions
+//If not strict, parameters without values are rendered as their zero values
+func newClusterFromYaml(
+	yamlStr string,
+	options *ClusterOptions,
+	strict bool,
+	username, clusterName string,
+	deploymentReadiness, jobReadiness Readiness,
+	config *viper.Viper,
+) (*Cluster, error) {
+	if options == nil {
+		options = &ClusterOptions{}
+	}
 
-	clusterConfig, err := LoadClusterConfig(db, clusterName)
+	namespace := ClusterNamespace(username, clusterName)
+
+	rendered, err := render(yamlStr, options.Parameters, strict)
 	if err != nil {
 		return nil, err
 	}
 
+	clusterConfig, err := ParseYaml(rendered)
+	if err != nil {
+		return nil, errors.NewYamlError("load cluster config error", err)
+	}
+
+	err = clusterConfig.ValidateGraph()
+	if err != nil {
+		return nil, err
+	}
+
+	err = options.Overrides.Validate(clusterConfig)
+	if err != nil {
+		return nil, err
+	}
+	options.Overrides.apply(clusterConfig)
+
 	portMap := make(map[string][]*PortMap)
 	environment := []*EnvVar{}
-	k8sAppDeployments, environment, err := buildDeployments(clusterConfig.Apps, username, portMap, environment, config)
+	k8sAppDeployments, environment, err := buildDeployments(clusterConfig.Apps, username, namespace, portMap, environment, config)
 	if err != nil {
 		return nil, errors.NewYamlError("parse yaml error", err)
 	}
-	k8sSvcDeployments, environment, err := buildDeployments(clusterConfig.Services, username, portMap, environment, config)
+	k8sSvcDeployments, environment, err := buildDeployments(clusterConfig.Services, username, namespace, portMap, environment, config)
 	if err != nil {
 		return nil, errors.NewYamlError("parse yaml error", err)
 	}
 
 	clusterServices := make(map[*Deployment]*Service)
-	clusterServices = buildServices(k8sAppDeployments, clusterConfig.Apps, username, portMap, false, clusterServices)
-	clusterServices = buildServices(k8sSvcDeployments, clusterConfig.Services, username, portMap, true, clusterServices)
+	clusterServices = buildServices(k8sAppDeployments, clusterConfig.Apps, namespace, portMap, false, clusterServices)
+	clusterServices = buildServices(k8sSvcDeployments, clusterConfig.Services, namespace, portMap, true, clusterServices)
 
-	k8sJob := NewJob("setup", username, clusterConfig.Setup, environment)
-	k8sPostJob := NewJob("post-setup", username, clusterConfig.PostSetup, environment)
+	k8sJob := NewJob("setup", username, namespace, clusterConfig.Setup, environment)
+	k8sPostJob := NewJob("post-setup", username, namespace, clusterConfig.PostSetup, environment)
 
-	k8sPersistentVolumeClaims := buildPersistentVolumeClaims(clusterConfig.Volumes, username)
+	k8sPersistentVolumeClaims := buildPersistentVolumeClaims(clusterConfig.Volumes, namespace)
+
+	ttl, err := clusterConfig.GetTTL(config)
+	if err != nil {
+		return nil, err
+	}
 
 	cluster := &Cluster{
 		Username:               username,
+		ClusterName:            clusterName,
 		Namespace:              namespace,
 		AppDeployments:         k8sAppDeployments,
 		SvcDeployments:         k8sSvcDeployments,
@@ -80,6 +219,13 @@ func NewCluster(
 		DeploymentReadiness:    deploymentReadiness,
 		JobReadiness:           jobReadiness,
 		PersistentVolumeClaims: k8sPersistentVolumeClaims,
+		TTL:                    ttl,
+		Schedule:               clusterConfig.Schedule,
+		ConfigYaml:             yamlStr,
+		RolloutWorkers:         config.GetInt("kubernetes.stacks.rollout-workers"),
+		KeepOnFailure:          clusterConfig.KeepOnFailure,
+		Overrides:              options.Overrides,
+		Parameters:             options.Parameters,
 	}
 
 	return cluster, nil
@@ -111,10 +257,16 @@ func getPorts(name string, ports []string, portMap map[string][]*PortMap) ([]int
 	return containerPorts, nil
 }
 
-func hasSatisfiedDependencies(links []string, createdDeployments map[string]*Deployment) bool {
+//Links to deployments outside types, like apps linking to services,
+//are satisfied by the previous step of the cluster creation
+func hasSatisfiedDependencies(
+	links []string,
+	types map[string]*ClusterAppConfig,
+	createdDeployments map[string]*Deployment,
+) bool {
 	for _, link := range links {
 		_, ok := createdDeployments[link]
-		if !ok {
+		if _, isType := types[link]; isType && !ok {
 			return false
 		}
 	}
@@ -124,7 +276,7 @@ func hasSatisfiedDependencies(links []string, createdDeployments map[string]*Dep
 
 func buildDeployments(
 	types map[string]*ClusterAppConfig,
-	username string,
+	username, namespace string,
 	portMap map[string][]*PortMap,
 	environment []*EnvVar,
 	appConfig *viper.Viper,
@@ -139,16 +291,22 @@ func buildDeployments(
 
 	i := 0
 	for len(notCreatedDeployments) > 0 {
+		created := i
 		for name := range notCreatedDeployments {
 			config := types[name]
-			if hasSatisfiedDependencies(config.Links, createdDeployments) {
+			if hasSatisfiedDependencies(config.Links, types, createdDeployments) {
 				ports, err := getPorts(name, config.Ports, portMap)
 				if err != nil {
 					return nil, environment, err
 				}
-				deployment := NewDeployment(name, username, config.Image, ports, config.Environment, config.ReadinessProbe, config.VolumeMount, config.Command, config.Resources, appConfig)
+				deployment := NewDeployment(name, username, namespace, config.Image, ports, config.Environment, config.ReadinessProbe, config.VolumeMount, config.Command, config.Resources, appConfig)
+				if config.Replicas > 0 {
+					deployment.Replicas = config.Replicas
+				}
 				for _, link := range config.Links {
-					deployment.Links = append(deployment.Links, createdDeployments[link])
+					if linked, ok := createdDeployments[link]; ok {
+						deployment.Links = append(deployment.Links, linked)
+					}
 				}
 
 				createdDeployments[name] = deployment
@@ -159,6 +317,15 @@ func buildDeployments(
 				delete(notCreatedDeployments, name)
 			}
 		}
+
+		if created == i {
+			names := []string{}
+			for name := range notCreatedDeployments {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, environment, fmt.Errorf("links can't be resolved: %s", strings.Join(names, ", "))
+		}
 	}
 
 	return deployments, environment, nil
@@ -167,19 +334,19 @@ func buildDeployments(
 func buildServices(
 	deploys []*Deployment,
 	appConfigs map[string]*ClusterAppConfig,
-	username string,
+	namespace string,
 	portMap map[string][]*PortMap,
 	isMystackSvc bool,
 	services map[*Deployment]*Service,
 ) map[*Deployment]*Service {
 	for _, deploy := range deploys {
-		services[deploy] = NewService(deploy.Name, username, portMap[deploy.Name], isMystackSvc, appConfigs[deploy.Name].IsSocket)
+		services[deploy] = NewService(deploy.Name, namespace, portMap[deploy.Name], isMystackSvc, appConfigs[deploy.Name].IsSocket)
 	}
 	return services
 }
 
-func rollback(clientset kubernetes.Interface, username string, err error) error {
-	nsErr := DeleteNamespace(clientset, username)
+func rollback(clientset kubernetes.Interface, namespace string, err error) error {
+	nsErr := DeleteNamespace(clientset, namespace)
 	if nsErr != nil {
 		return errors.NewKubernetesError(
 			"create cluster error",
@@ -189,9 +356,9 @@ func rollback(clientset kubernetes.Interface, username string, err error) error
 	return err
 }
 
-func buildPersistentVolumeClaims(persistentVolumeClaims []*PersistentVolumeClaim, username string) []*PersistentVolumeClaim {
+func buildPersistentVolumeClaims(persistentVolumeClaims []*PersistentVolumeClaim, namespace string) []*PersistentVolumeClaim {
 	for _, pvc := range persistentVolumeClaims {
-		pvc.Namespace = usernameToNamespace(username)
+		pvc.Namespace = namespace
 	}
 
 	return persistentVolumeClaims
@@ -203,52 +370,252 @@ func log(logger logrus.FieldLogger, msg string) {
 	}
 }
 
+//CheckOwner returns an AccessError if the namespace of the cluster belongs to another
+//user or cluster, or if its stack was created by someone other than ownerEmail
+func (c *Cluster) CheckOwner(clientset kubernetes.Interface, ownerEmail string) error {
+	err := CheckNamespaceOwner(clientset, c.Namespace, c.Username, c.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	if len(c.OwnerEmail) > 0 && c.OwnerEmail != ownerEmail {
+		return errors.NewAccessError(
+			"cluster access error",
+			fmt.Errorf("stack on namespace '%s' doesn't belong to %s", c.Namespace, ownerEmail),
+		)
+	}
+
+	return nil
+}
+
 //Create creates namespace, deployments and services
+//Objects that already exist with the same config are kept, so a creation
+//interrupted midway can be completed by calling Create again
+//On failure the namespace is deleted if this call created it, unless KeepOnFailure is set
+//Namespaces that already existed are kept with the failed step saved
+//It fails if another creation holds the lease of the namespace, see Claim
 func (c *Cluster) Create(logger logrus.FieldLogger, clientset kubernetes.Interface) error {
-	if NamespaceExists(clientset, c.Namespace) {
-		return errors.NewKubernetesError(
-			"create cluster error",
-			fmt.Errorf("namespace for user '%s' already exists", c.Username),
+	return c.runSteps(logger, clientset, c.steps(), c.KeepOnFailure)
+}
+
+//Resume cleans what the failed step of a kept cluster created
+//and runs the pipeline again from that step
+func (c *Cluster) Resume(logger logrus.FieldLogger, clientset kubernetes.Interface) error {
+	failedStep, err := FailedStep(clientset, c.Namespace)
+	if err != nil {
+		return err
+	}
+	if len(failedStep) == 0 {
+		return errors.NewGenericError(
+			"resume cluster error",
+			fmt.Errorf("cluster '%s' has no failed step to resume", c.ClusterName),
 		)
 	}
 
-	log(logger, "creating namespace")
-	err := CreateNamespace(clientset, c.Username)
+	steps := c.steps()
+	for len(steps) > 0 && steps[0].name != failedStep {
+		steps = steps[1:]
+	}
+	if len(steps) == 0 {
+		return errors.NewGenericError(
+			"resume cluster error",
+			fmt.Errorf("unknown failed step '%s'", failedStep),
+		)
+	}
+
+	err = c.Claim(clientset)
 	if err != nil {
-		return rollback(clientset, c.Username, err)
+		return err
+	}
+
+	log(logger, fmt.Sprintf("cleaning failed step %s", failedStep))
+	c.cleanStep(clientset, failedStep)
+
+	return c.runSteps(logger, clientset, steps, true)
+}
+
+//runSteps runs the pipeline while holding the creation lease of the namespace
+func (c *Cluster) runSteps(
+	logger logrus.FieldLogger,
+	clientset kubernetes.Interface,
+	steps []*clusterStep,
+	keepOnFailure bool,
+) error {
+	return c.withLease(logger, clientset, func() error {
+		for _, step := range steps {
+			err := c.runStep(logger, clientset, step)
+			if err != nil && c.createdNamespace && (!keepOnFailure || step.name == StepNamespace) {
+				return rollback(clientset, c.Namespace, err)
+			}
+			if err != nil && step.name == StepNamespace {
+				return err
+			}
+			if err != nil {
+				return c.keepFailed(logger, clientset, step.name, err)
+			}
+		}
+
+		return annotateNamespace(clientset, c.Namespace, failedStepAnnotation, "")
+	})
+}
+
+//releaseLease removes the creation lease when the pipeline finishes
+//Namespaces deleted by a rollback have no lease to remove
+func (c *Cluster) releaseLease(logger logrus.FieldLogger, clientset kubernetes.Interface) {
+	if !NamespaceExists(clientset, c.Namespace) {
+		c.leaseID = ""
+		return
+	}
+
+	err := c.writeLease(clientset, nil)
+	if err != nil && logger != nil {
+		logger.WithError(err).Warn("failed to release creation lease")
+	}
+}
+
+//keepFailed saves on the namespace the step that failed, so it can be resumed later
+func (c *Cluster) keepFailed(
+	logger logrus.FieldLogger,
+	clientset kubernetes.Interface,
+	step string,
+	err error,
+) error {
+	annotateErr := annotateNamespace(clientset, c.Namespace, failedStepAnnotation, step)
+	if annotateErr != nil && logger != nil {
+		logger.WithError(annotateErr).Errorf("failed to save failed step %s", step)
+	}
+
+	return err
+}
+
+//cleanStep removes what a step may have created before failing
+//Resources that don't exist are ignored
+func (c *Cluster) cleanStep(clientset kubernetes.Interface, step string) {
+	switch step {
+	case StepVolumes:
+		for _, pvc := range c.PersistentVolumeClaims {
+			pvc.Delete(clientset)
+		}
+	case StepSvcDeployments:
+		c.deleteDeploymentsAndItsServices(clientset, c.SvcDeployments)
+	case StepSetup:
+		c.Job.Delete(clientset)
+	case StepAppDeployments:
+		c.deleteDeploymentsAndItsServices(clientset, c.AppDeployments)
+	case StepPostSetup:
+		c.PostJob.Delete(clientset)
+	}
+}
+
+func (c *Cluster) deleteDeploymentsAndItsServices(clientset kubernetes.Interface, deployments []*Deployment) {
+	for _, deployment := range deployments {
+		if service, ok := c.K8sServices[deployment]; ok {
+			service.Delete(clientset)
+		}
+		deployment.Delete(clientset)
+	}
+}
+
+type clusterStep struct {
+	name string
+	run  func(logrus.FieldLogger, kubernetes.Interface) error
+}
+
+//steps returns the pipeline run by Create, in order
+func (c *Cluster) steps() []*clusterStep {
+	return []*clusterStep{
+		{StepNamespace, c.createNamespace},
+		{StepVolumes, c.createVolumes},
+		{StepSvcDeployments, c.startSvcDeployments},
+		{StepSetup, c.runSetupJob},
+		{StepAppDeployments, c.startAppDeployments},
+		{StepPostSetup, c.runPostSetupJob},
+	}
+}
+
+func (c *Cluster) runStep(
+	logger logrus.FieldLogger,
+	clientset kubernetes.Interface,
+	step *clusterStep,
+) error {
+	if c.Tracker != nil {
+		if err := c.Tracker.StartStep(step.name); err != nil && logger != nil {
+			logger.WithError(err).Warnf("failed to track start of step %s", step.name)
+		}
+	}
+	c.Progress.StartStep(step.name)
+
+	err := step.run(logger, clientset)
+	c.Progress.FinishStep(err)
+
+	if c.Tracker != nil {
+		if trackErr := c.Tracker.FinishStep(step.name, err); trackErr != nil && logger != nil {
+			logger.WithError(trackErr).Warnf("failed to track end of step %s", step.name)
+		}
+	}
+
+	return err
+}
+
+//createNamespace sets up the namespace Claim created
+func (c *Cluster) createNamespace(logger logrus.FieldLogger, clientset kubernetes.Interface) error {
+	if !c.createdNamespace {
+		log(logger, "namespace already exists")
+		return nil
+	}
+
+	log(logger, "creating namespace")
+	if c.TTL > 0 {
+		err := SetClusterExpiration(clientset, c.Namespace, time.Now().Add(c.TTL))
+		if err != nil {
+			return err
+		}
 	}
 	log(logger, "done creating namespace")
 
+	return nil
+}
+
+func (c *Cluster) createVolumes(logger logrus.FieldLogger, clientset kubernetes.Interface) error {
 	log(logger, "creating svc volume")
 	for _, pvc := range c.PersistentVolumeClaims {
-		_, err = pvc.Start(clientset)
+		_, err := pvc.Ensure(clientset)
 		if err != nil {
-			logger.WithError(err).Error("failed to create PVC")
-			return rollback(clientset, c.Username, err)
+			if logger != nil {
+				logger.WithError(err).Error("failed to create PVC")
+			}
+			return err
 		}
 	}
 	log(logger, "done creating svc volume")
 
-	err = c.startDeploymentsAndItsServicesWithLinks(logger, clientset, c.SvcDeployments)
-	if err != nil {
-		return rollback(clientset, c.Username, err)
-	}
+	return nil
+}
 
-	err = c.runJob(logger, clientset, c.Job)
-	if err != nil {
-		return rollback(clientset, c.Username, err)
-	}
+func (c *Cluster) startSvcDeployments(logger logrus.FieldLogger, clientset kubernetes.Interface) error {
+	return c.startDeploymentsAndItsServicesWithLinks(logger, clientset, c.SvcDeployments)
+}
 
-	err = c.startDeploymentsAndItsServicesWithLinks(logger, clientset, c.AppDeployments)
-	if err != nil {
-		return rollback(clientset, c.Username, err)
+func (c *Cluster) runSetupJob(logger logrus.FieldLogger, clientset kubernetes.Interface) error {
+	return c.runJob(logger, clientset, c.Job)
+}
+
+func (c *Cluster) startAppDeployments(logger logrus.FieldLogger, clientset kubernetes.Interface) error {
+	return c.startDeploymentsAndItsServicesWithLinks(logger, clientset, c.AppDeployments)
+}
+
+func (c *Cluster) runPostSetupJob(logger logrus.FieldLogger, clientset kubernetes.Interface) error {
+	if c.PostJob != nil && c.PostJob.Setup.WaitForCompletion {
+		return c.runJob(logger, clientset, c.PostJob)
 	}
 
 	log(logger, "creating post-setup job")
-	_, err = c.PostJob.Run(clientset)
+	_, err := c.PostJob.Ensure(clientset)
 	if err != nil {
-		logger.WithError(err).Error("failed to run post job")
-		return rollback(clientset, c.Username, err)
+		if logger != nil {
+			logger.WithError(err).Error("failed to run post job")
+		}
+		return err
 	}
 
 	return nil
@@ -261,16 +628,20 @@ func (c *Cluster) runJob(
 ) error {
 	log(logger, "creating job")
 
-	_, err := job.Run(clientset)
+	_, err := job.Ensure(clientset)
 	if err != nil {
-		logger.WithError(err).Error("failed to run job")
-		return rollback(clientset, c.Username, err)
+		if logger != nil {
+			logger.WithError(err).Error("failed to run job")
+		}
+		return err
 	}
 
 	log(logger, "waiting for job completion")
 	err = c.JobReadiness.WaitForCompletion(clientset, job)
 	if err != nil {
-		logger.WithError(err).Error("failed to run job")
+		if logger != nil {
+			logger.WithError(err).Error("failed to run job")
+		}
 		return err
 	}
 
@@ -285,77 +656,46 @@ func (c *Cluster) startDeploymentsAndItsServicesWithLinks(
 	deployments []*Deployment,
 ) error {
 	log(logger, "Creating linked services")
-	deploymentsNotReady := make(map[*Deployment]bool)
-	for _, deployment := range deployments {
-		deploymentsNotReady[deployment] = true
-	}
-
-	for len(deploymentsNotReady) > 0 {
-		runnableDeployments := []*Deployment{}
-		for deployment := range deploymentsNotReady {
-			if canRun(deployment, deploymentsNotReady) {
-				runnableDeployments = append(runnableDeployments, deployment)
-			}
-		}
-
-		err := c.startDeploymentsAndItsServices(logger, clientset, runnableDeployments, c.K8sServices)
-		if err != nil {
+	err := c.rollout(logger, clientset, deployments)
+	if err != nil {
+		if logger != nil {
 			logger.WithError(err).Error("failed to create deployment and service")
-			return err
-		}
-
-		for _, deployment := range runnableDeployments {
-			delete(deploymentsNotReady, deployment)
 		}
+		return err
 	}
 
 	return nil
 }
 
-//A deployment can start if all its dependencies (links) are already running
-func canRun(deployment *Deployment, deploymentsNotReady map[*Deployment]bool) bool {
-	for _, dependencie := range deployment.Links {
-		if _, contains := deploymentsNotReady[dependencie]; contains {
-			return false
-		}
-	}
-	return true
-}
+//linkLayers groups deployments in the order they can start
+//Every deployment comes after the layers of all its links
+func linkLayers(deployments []*Deployment) [][]*Deployment {
+	pendingLinks, dependents := linkGraph(deployments)
 
-func (c *Cluster) startDeploymentsAndItsServices(
-	logger logrus.FieldLogger,
-	clientset kubernetes.Interface,
-	deployments []*Deployment,
-	services map[*Deployment]*Service,
-) error {
-	log(logger, "creating deployments")
+	layer := []*Deployment{}
 	for _, deployment := range deployments {
-		_, err := deployment.Deploy(clientset)
-		if err != nil {
-			logger.WithError(err).Errorf("failed to create deployment: %s", deployment)
-			return rollback(clientset, c.Username, err)
+		if pendingLinks[deployment] == 0 {
+			layer = append(layer, deployment)
 		}
 	}
 
-	log(logger, "waiting deployment completion")
-	err := c.DeploymentReadiness.WaitForCompletion(clientset, deployments)
-	if err != nil {
-		return rollback(clientset, c.Username, err)
-	}
-	log(logger, "done creating deployments")
+	layers := [][]*Deployment{}
+	for len(layer) > 0 {
+		layers = append(layers, layer)
 
-	log(logger, "creating services")
-	for _, deployment := range deployments {
-		service := services[deployment]
-		_, err = service.Expose(clientset)
-		if err != nil {
-			logger.WithError(err).Errorf("failed to create service: %s", deployment)
-			return rollback(clientset, c.Username, err)
+		next := []*Deployment{}
+		for _, deployment := range layer {
+			for _, dependent := range dependents[deployment] {
+				pendingLinks[dependent]--
+				if pendingLinks[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
 		}
+		layer = next
 	}
-	log(logger, "done creating services")
 
-	return nil
+	return layers
 }
 
 //Delete deletes namespace and all deployments and services
@@ -367,23 +707,37 @@ func (c *Cluster) Delete(clientset kubernetes.Interface) error {
 		)
 	}
 
+	c.Progress.StartStep(StepDeleteApps)
+	c.deleteApps(clientset)
+	c.Progress.FinishStep(nil)
+
+	c.Progress.StartStep(StepDeleteNamespace)
+	err := c.deleteNamespace(clientset)
+	c.Progress.FinishStep(err)
+
+	return err
+}
+
+func (c *Cluster) deleteApps(clientset kubernetes.Interface) {
 	for _, service := range c.K8sServices {
 		service.Delete(clientset)
 	}
 
-	for _, deployment := range c.AppDeployments {
-		deployment.Delete(clientset)
-	}
-
-	for _, deployment := range c.SvcDeployments {
-		deployment.Delete(clientset)
+	for _, deployments := range [][]*Deployment{c.AppDeployments, c.SvcDeployments} {
+		for _, deployment := range deployments {
+			c.Progress.StartApp(deployment.Name)
+			deployment.Delete(clientset)
+			c.Progress.FinishApp(deployment.Name, nil)
+		}
 	}
 
 	for _, pvc := range c.PersistentVolumeClaims {
 		pvc.Delete(clientset)
 	}
+}
 
-	err := DeleteNamespace(clientset, c.Username)
+func (c *Cluster) deleteNamespace(clientset kubernetes.Interface) error {
+	err := DeleteNamespace(clientset, c.Namespace)
 	if err != nil {
 		return err
 	}
@@ -403,12 +757,79 @@ func (c *Cluster) Delete(clientset kubernetes.Interface) error {
 	return nil
 }
 
+//Extend postpones the deletion of the cluster to ttl from now
+//If ttl is zero the cluster config ttl is used
+func (c *Cluster) Extend(clientset kubernetes.Interface, ttl time.Duration) (time.Time, error) {
+	if !NamespaceExists(clientset, c.Namespace) {
+		return time.Time{}, errors.NewKubernetesError(
+			"extend cluster error",
+			fmt.Errorf("namespace for user '%s' not found", c.Username),
+		)
+	}
+
+	if ttl == 0 {
+		ttl = c.TTL
+	}
+
+	if ttl <= 0 {
+		return time.Time{}, errors.NewGenericError(
+			"extend cluster error",
+			fmt.Errorf("cluster has no ttl, inform one to extend it"),
+		)
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	err := SetClusterExpiration(clientset, c.Namespace, expiresAt)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return expiresAt, nil
+}
+
+//Deployment returns the app or service deployment with this name
+func (c *Cluster) Deployment(name string) (*Deployment, error) {
+	for _, deployments := range [][]*Deployment{c.AppDeployments, c.SvcDeployments} {
+		for _, deployment := range deployments {
+			if deployment.Name == name {
+				return deployment, nil
+			}
+		}
+	}
+
+	return nil, errors.NewKubernetesError(
+		"get app error",
+		fmt.Errorf("app '%s' not found", name),
+	)
+}
+
+//GetJob returns the setup or post-setup job
+func (c *Cluster) GetJob(name string) (*Job, error) {
+	for _, job := range []*Job{c.Job, c.PostJob} {
+		if job != nil && job.Name == name {
+			return job, nil
+		}
+	}
+
+	return nil, errors.NewKubernetesError(
+		"get job error",
+		fmt.Errorf("job '%s' not found", name),
+	)
+}
+
+//ClusterApps are the domains of the cluster apps
+//Domains don't respond while the cluster is sleeping
+type ClusterApps struct {
+	Domains  map[string][]string `json:"domains"`
+	Sleeping bool                `json:"sleeping"`
+}
+
 //Apps returns a list of cluster apps
 func (c *Cluster) Apps(
 	config *viper.Viper,
 	clientset kubernetes.Interface,
 	k8sDomain string,
-) (map[string][]string, error) {
+) (*ClusterApps, error) {
 	if !NamespaceExists(clientset, c.Namespace) {
 		return nil, errors.NewKubernetesError(
 			"get apps error",
@@ -446,7 +867,15 @@ func (c *Cluster) Apps(
 		domains[service.Name] = []string{fmt.Sprintf("%s.%s.%s", service.Name, service.Namespace, k8sDomain)}
 	}
 
-	return domains, nil
+	sleeping, err := c.IsSleeping(clientset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClusterApps{
+		Domains:  domains,
+		Sleeping: sleeping,
+	}, nil
 }
 
 //Services returns a list of cluster services
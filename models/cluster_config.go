@@ -8,7 +8,12 @@
 package models
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
 	"github.com/topfreegames/mystack-controller/errors"
 	yaml "gopkg.in/yaml.v2"
 )
@@ -20,73 +25,195 @@ type ClusterConfig struct {
 	Volumes   []*PersistentVolumeClaim     `yaml:"volumes"`
 	Services  map[string]*ClusterAppConfig `yaml:"services"`
 	Apps      map[string]*ClusterAppConfig `yaml:"apps"`
+	TTL       string                       `yaml:"ttl"`
+	Schedule  *Schedule                    `yaml:"schedule"`
+
+	KeepOnFailure bool                  `yaml:"keepOnFailure"`
+	Parameters    map[string]*Parameter `yaml:"parameters"`
+	Extends       string                `yaml:"extends"`
+}
+
+//GetTTL returns how long a cluster created from this config lives
+//Configs without ttl use kubernetes.stacks.default-ttl and zero means forever
+func (c *ClusterConfig) GetTTL(config *viper.Viper) (time.Duration, error) {
+	ttl := c.TTL
+	if len(ttl) == 0 && config != nil {
+		ttl = config.GetString("kubernetes.stacks.default-ttl")
+	}
+
+	if len(ttl) == 0 {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(ttl)
+	if err != nil {
+		return 0, errors.NewYamlError("invalid ttl", err)
+	}
+
+	if duration < 0 {
+		return 0, errors.NewYamlError("invalid ttl", fmt.Errorf("ttl must be positive: %s", ttl))
+	}
+
+	return duration, nil
 }
 
 //LoadClusterConfig reads DB and create map with cluster configuration
+//merged with the configs it extends
 func LoadClusterConfig(
-	db DB,
+	ctx context.Context,
+	db Queryer,
 	clusterName string,
 ) (
 	*ClusterConfig,
 	error,
 ) {
-	if len(clusterName) == 0 {
-		return nil, errors.NewGenericError("load cluster config error", fmt.Errorf("invalid empty cluster name"))
+	yamlStr, err := loadClusterConfigYaml(ctx, db, clusterName)
+	if err != nil {
+		return nil, err
 	}
 
-	query := "SELECT yaml FROM clusters WHERE name = $1"
-	var yamlStr string
+	rendered, err := render(yamlStr, nil, false)
+	if err != nil {
+		return nil, err
+	}
 
-	err := db.Get(&yamlStr, query, clusterName)
+	clusterConfig, err := ParseYaml(rendered)
 	if err != nil {
-		return nil, errors.NewDatabaseError(err)
+		return nil, errors.NewYamlError("load cluster config error", err)
+	}
+
+	return clusterConfig, nil
+}
+
+func loadClusterConfigYaml(ctx context.Context, db Queryer, clusterName string) (string, error) {
+	if len(clusterName) == 0 {
+		return "", errors.NewGenericError("load cluster config error", fmt.Errorf("invalid empty cluster name"))
+	}
+
+	yamlStr, err := selectClusterConfigYaml(ctx, db, clusterName)
+	if err != nil {
+		return "", err
 	}
 
 	if len(yamlStr) == 0 {
-		return nil, errors.NewYamlError("load cluster config error", fmt.Errorf("invalid empty config"))
+		return "", errors.NewYamlError("load cluster config error", fmt.Errorf("invalid empty config"))
 	}
 
-	clusterConfig, err := ParseYaml(yamlStr)
+	return resolveExtends(ctx, db, yamlStr, []string{clusterName})
+}
+
+func selectClusterConfigYaml(ctx context.Context, db Queryer, clusterName string) (string, error) {
+	query := "SELECT yaml FROM clusters WHERE name = $1"
+	var yamlStr string
+
+	err := db.GetContext(ctx, &yamlStr, query, clusterName)
 	if err != nil {
-		return nil, errors.NewYamlError("load cluster config error", err)
+		return "", errors.NewDatabaseError(err)
 	}
 
-	return clusterConfig, nil
+	return yamlStr, nil
 }
 
-//WriteClusterConfig writes cluster config on DB
+//WriteClusterConfig writes cluster config on DB, if ValidateClusterConfig finds no problem,
+//and records it as the first revision, by authorEmail, on the same transaction
 func WriteClusterConfig(
+	ctx context.Context,
 	db DB,
 	clusterName string,
 	yamlStr string,
+	authorEmail string,
+	config *viper.Viper,
 ) error {
-	if len(clusterName) == 0 {
-		return errors.NewGenericError("write cluster config error", fmt.Errorf("invalid empty cluster name"))
-	}
-	if _, err := ParseYaml(yamlStr); err != nil {
-		return errors.NewYamlError("write cluster config error", err)
+	err := validateClusterConfig(ctx, db, clusterName, yamlStr, config)
+	if err != nil {
+		return err
 	}
-	if len(yamlStr) == 0 {
-		return errors.NewYamlError("write cluster config error", fmt.Errorf("invalid empty config"))
+
+	return WithTransaction(ctx, db, func(tx Queryer) error {
+		query := `INSERT INTO clusters(name, yaml) VALUES(:name, :yaml)`
+		values := map[string]interface{}{
+			"name": clusterName,
+			"yaml": yamlStr,
+		}
+		res, err := tx.NamedExecContext(ctx, query, values)
+		if err != nil {
+			return errors.NewDatabaseError(err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return errors.NewDatabaseError(fmt.Errorf("couldn't insert on database"))
+		}
+
+		return insertRevision(ctx, tx, clusterName, yamlStr, authorEmail)
+	})
+}
+
+//UpdateClusterConfig replaces the yaml of a cluster config, if ValidateClusterConfig finds no problem,
+//and records it as a new revision, by authorEmail, on the same transaction
+func UpdateClusterConfig(
+	ctx context.Context,
+	db DB,
+	clusterName string,
+	yamlStr string,
+	authorEmail string,
+	config *viper.Viper,
+) error {
+	err := validateClusterConfig(ctx, db, clusterName, yamlStr, config)
+	if err != nil {
+		return err
 	}
 
-	query := `INSERT INTO clusters(name, yaml) VALUES(:name, :yaml)`
+	return WithTransaction(ctx, db, func(tx Queryer) error {
+		return updateClusterConfig(ctx, tx, clusterName, yamlStr, authorEmail, config)
+	})
+}
+
+//updateClusterConfig replaces the yaml of a cluster config and records the revision
+//It fails if the configs extending it become invalid
+func updateClusterConfig(
+	ctx context.Context,
+	tx Queryer,
+	clusterName, yamlStr, authorEmail string,
+	config *viper.Viper,
+) error {
+	query := `UPDATE clusters SET yaml = :yaml WHERE name = :name`
 	values := map[string]interface{}{
 		"name": clusterName,
 		"yaml": yamlStr,
 	}
-	res, err := db.NamedExec(query, values)
+	res, err := tx.NamedExecContext(ctx, query, values)
 	if err != nil {
 		return errors.NewDatabaseError(err)
 	}
 	if n, _ := res.RowsAffected(); n == 0 {
-		return errors.NewDatabaseError(fmt.Errorf("couldn't insert on database"))
+		return errors.NewDatabaseError(fmt.Errorf("sql: no rows in result set"))
+	}
+
+	err = insertRevision(ctx, tx, clusterName, yamlStr, authorEmail)
+	if err != nil {
+		return err
 	}
-	return nil
+
+	return validateExtendingConfigs(ctx, tx, clusterName, config)
 }
 
-//RemoveClusterConfig writes cluster config on DB
+//validateClusterConfig returns the problems ValidateClusterConfig finds on yamlStr as an error
+func validateClusterConfig(ctx context.Context, db Queryer, clusterName, yamlStr string, config *viper.Viper) error {
+	if len(clusterName) == 0 {
+		return errors.NewGenericError("write cluster config error", fmt.Errorf("invalid empty cluster name"))
+	}
+
+	problems, err := ValidateClusterConfig(ctx, db, clusterName, yamlStr, config)
+	if err != nil {
+		return err
+	}
+
+	return configProblemsError(problems)
+}
+
+//RemoveClusterConfig removes cluster config from DB
+//Configs extended by others can't be removed
 func RemoveClusterConfig(
+	ctx context.Context,
 	db DB,
 	clusterName string,
 ) error {
@@ -94,19 +221,36 @@ func RemoveClusterConfig(
 		return errors.NewGenericError("remove cluster config error", fmt.Errorf("invalid empty cluster name"))
 	}
 
-	query := `DELETE FROM clusters WHERE name=:name`
-	values := map[string]interface{}{
-		"name": clusterName,
-	}
-	res, err := db.NamedExec(query, values)
-	if err != nil {
-		return errors.NewDatabaseError(err)
-	}
-	if n, _ := res.RowsAffected(); n == 0 {
-		err = fmt.Errorf("sql: no rows in result set")
-		return errors.NewDatabaseError(err)
-	}
-	return nil
+	return WithTransaction(ctx, db, func(tx Queryer) error {
+		extending, err := extendingConfigs(ctx, tx, clusterName)
+		if err != nil {
+			return err
+		}
+		if len(extending) > 0 {
+			names := make([]string, len(extending))
+			for i, config := range extending {
+				names[i] = fmt.Sprintf("'%s'", config.Name)
+			}
+			return errors.NewGenericError(
+				"remove cluster config error",
+				fmt.Errorf("cluster config '%s' is extended by %s", clusterName, strings.Join(names, ", ")),
+			)
+		}
+
+		query := `DELETE FROM clusters WHERE name=:name`
+		values := map[string]interface{}{
+			"name": clusterName,
+		}
+		res, err := tx.NamedExecContext(ctx, query, values)
+		if err != nil {
+			return errors.NewDatabaseError(err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			err = fmt.Errorf("sql: no rows in result set")
+			return errors.NewDatabaseError(err)
+		}
+		return nil
+	})
 }
 
 //ParseYaml convert string to maps
@@ -122,10 +266,10 @@ func ParseYaml(yamlStr string) (*ClusterConfig, error) {
 }
 
 //ListClusterConfig return the list of saved cluster configs
-func ListClusterConfig(db DB) ([]string, error) {
+func ListClusterConfig(ctx context.Context, db Queryer) ([]string, error) {
 	names := []string{}
 	query := "SELECT name FROM clusters"
-	err := db.Select(&names, query)
+	err := db.SelectContext(ctx, &names, query)
 
 	if err != nil {
 		return nil, errors.NewDatabaseError(err)
@@ -135,13 +279,11 @@ func ListClusterConfig(db DB) ([]string, error) {
 }
 
 //ClusterConfigDetails return the cluster config yaml
-func ClusterConfigDetails(db DB, clusterName string) (string, error) {
-	var yamlStr string
-	query := "SELECT yaml FROM clusters WHERE name = $1"
-	err := db.Get(&yamlStr, query, clusterName)
-	if err != nil {
-		return "", errors.NewDatabaseError(err)
-	}
+func ClusterConfigDetails(ctx context.Context, db Queryer, clusterName string) (string, error) {
+	return selectClusterConfigYaml(ctx, db, clusterName)
+}
 
-	return yamlStr, nil
+//ResolvedClusterConfigDetails return the cluster config yaml merged with the configs it extends
+func ResolvedClusterConfigDetails(ctx context.Context, db Queryer, clusterName string) (string, error) {
+	return loadClusterConfigYaml(ctx, db, clusterName)
 }
@@ -9,6 +9,8 @@
 package models_test
 
 import (
+	"context"
+
 	. "github.com/topfreegames/mystack-controller/models"
 
 	. "github.com/onsi/ginkgo"
@@ -75,32 +77,32 @@ apps:
 
 	Describe("WriteClusterConfig", func() {
 		It("should write cluster config", func() {
-			err = WriteClusterConfig(db, clusterName, yaml1)
+			err = WriteClusterConfig(context.Background(), db, clusterName, yaml1, "user@example.com", config)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
 		It("should return error when writing cluster config with same name", func() {
-			err = WriteClusterConfig(db, clusterName, yaml1)
+			err = WriteClusterConfig(context.Background(), db, clusterName, yaml1, "user@example.com", config)
 			Expect(err).NotTo(HaveOccurred())
 
-			err = WriteClusterConfig(db, clusterName, yaml1)
+			err = WriteClusterConfig(context.Background(), db, clusterName, yaml1, "user@example.com", config)
 			Expect(err).To(HaveOccurred())
 		})
 	})
 
 	Describe("LoadClusterConfig", func() {
 		It("should load cluster config", func() {
-			err = WriteClusterConfig(db, clusterName, yaml1)
+			err = WriteClusterConfig(context.Background(), db, clusterName, yaml1, "user@example.com", config)
 			Expect(err).NotTo(HaveOccurred())
 
-			clusterConfig, err := LoadClusterConfig(db, clusterName)
+			clusterConfig, err := LoadClusterConfig(context.Background(), db, clusterName)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(clusterConfig.Services).To(BeEquivalentTo(services))
 			Expect(clusterConfig.Apps).To(BeEquivalentTo(apps))
 		})
 
 		It("should return error if clusterName doesn't exist on DB", func() {
-			clusterConfig, err := LoadClusterConfig(db, clusterName)
+			clusterConfig, err := LoadClusterConfig(context.Background(), db, clusterName)
 			Expect(clusterConfig).To(BeNil())
 			Expect(err).To(HaveOccurred())
 		})
@@ -108,15 +110,15 @@ apps:
 
 	Describe("RemoveClusterConfig", func() {
 		It("should delete existing cluster config", func() {
-			err = WriteClusterConfig(db, clusterName, yaml1)
+			err = WriteClusterConfig(context.Background(), db, clusterName, yaml1, "user@example.com", config)
 			Expect(err).NotTo(HaveOccurred())
 
-			err = RemoveClusterConfig(db, clusterName)
+			err = RemoveClusterConfig(context.Background(), db, clusterName)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
 		It("should not return error when deleting non existing cluster config", func() {
-			err = RemoveClusterConfig(db, clusterName)
+			err = RemoveClusterConfig(context.Background(), db, clusterName)
 			Expect(err).To(HaveOccurred())
 		})
 	})
@@ -0,0 +1,120 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/topfreegames/mystack-controller/errors"
+)
+
+//ClusterConfigRevision is a version of a cluster config yaml, saved every time it is written
+type ClusterConfigRevision struct {
+	ClusterName string    `db:"cluster_name" json:"clusterName"`
+	Revision    int       `db:"revision" json:"revision"`
+	Yaml        string    `db:"yaml" json:"yaml,omitempty"`
+	AuthorEmail string    `db:"author_email" json:"authorEmail"`
+	CreatedAt   time.Time `db:"created_at" json:"createdAt"`
+}
+
+//ListClusterConfigRevisions returns the revisions of a cluster config, newest first, without their yaml
+func ListClusterConfigRevisions(ctx context.Context, db Queryer, clusterName string) ([]*ClusterConfigRevision, error) {
+	revisions := []*ClusterConfigRevision{}
+	query := `SELECT cluster_name, revision, author_email, created_at
+	FROM cluster_config_revisions
+	WHERE cluster_name = $1
+	ORDER BY revision DESC`
+	err := db.SelectContext(ctx, &revisions, query, clusterName)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return revisions, nil
+}
+
+//LoadClusterConfigRevision returns a revision of a cluster config with its yaml
+func LoadClusterConfigRevision(ctx context.Context, db Queryer, clusterName string, revision int) (*ClusterConfigRevision, error) {
+	configRevision := &ClusterConfigRevision{}
+	query := `SELECT cluster_name, revision, yaml, author_email, created_at
+	FROM cluster_config_revisions
+	WHERE cluster_name = $1 AND revision = $2`
+	err := db.GetContext(ctx, configRevision, query, clusterName, revision)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return configRevision, nil
+}
+
+//DiffClusterConfigRevisions returns the unified diff between two revisions of a cluster config
+//Revisions with too many changed lines to compare return a GenericError
+func DiffClusterConfigRevisions(ctx context.Context, db Queryer, clusterName string, from, to int) (string, error) {
+	fromRevision, err := LoadClusterConfigRevision(ctx, db, clusterName, from)
+	if err != nil {
+		return "", err
+	}
+
+	toRevision, err := LoadClusterConfigRevision(ctx, db, clusterName, to)
+	if err != nil {
+		return "", err
+	}
+
+	return unifiedDiff(
+		fmt.Sprintf("%s revision %d", clusterName, from),
+		fmt.Sprintf("%s revision %d", clusterName, to),
+		fromRevision.Yaml,
+		toRevision.Yaml,
+	)
+}
+
+//RollbackClusterConfig writes the yaml of an earlier revision as a new revision of the cluster config
+func RollbackClusterConfig(
+	ctx context.Context,
+	db DB,
+	clusterName string,
+	revision int,
+	authorEmail string,
+	config *viper.Viper,
+) error {
+	return WithTransaction(ctx, db, func(tx Queryer) error {
+		configRevision, err := LoadClusterConfigRevision(ctx, tx, clusterName, revision)
+		if err != nil {
+			return err
+		}
+
+		err = validateClusterConfig(ctx, tx, clusterName, configRevision.Yaml, config)
+		if err != nil {
+			return err
+		}
+
+		return updateClusterConfig(ctx, tx, clusterName, configRevision.Yaml, authorEmail, config)
+	})
+}
+
+//insertRevision records yamlStr as the next revision of a cluster config
+//Revisions are numbered from 1 for each cluster config name
+func insertRevision(ctx context.Context, tx Queryer, clusterName, yamlStr, authorEmail string) error {
+	query := `INSERT INTO cluster_config_revisions(cluster_name, revision, yaml, author_email)
+	SELECT :name, COALESCE(MAX(revision), 0) + 1, :yaml, :author_email
+	FROM cluster_config_revisions
+	WHERE cluster_name = :name`
+	values := map[string]interface{}{
+		"name":         clusterName,
+		"yaml":         yamlStr,
+		"author_email": authorEmail,
+	}
+	_, err := tx.NamedExecContext(ctx, query, values)
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,250 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/topfreegames/mystack-controller/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var _ = Describe("ClusterConfigRevision", func() {
+	const (
+		clusterName = "myCustomApps"
+		yamlV1      = `services:
+  postgres:
+    image: postgres:1.0
+apps:
+  app1:
+    image: app1:v1
+`
+		yamlV2 = `services:
+  postgres:
+    image: postgres:1.0
+apps:
+  app1:
+    image: app1:v2
+`
+	)
+
+	expectRevision := func(revision int, yamlStr string) {
+		mock.
+			ExpectQuery("^SELECT (.+) FROM cluster_config_revisions WHERE (.+)$").
+			WithArgs(clusterName, revision).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"cluster_name", "revision", "yaml", "author_email", "created_at",
+			}).AddRow(clusterName, revision, yamlStr, "user@example.com", time.Now()))
+	}
+
+	expectUpdate := func(yamlStr, email string) {
+		mock.
+			ExpectExec("^UPDATE clusters SET yaml = (.+) WHERE name = (.+)$").
+			WithArgs(yamlStr, clusterName).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.
+			ExpectExec("^INSERT INTO cluster_config_revisions(.+)$").
+			WithArgs(clusterName, yamlStr, email, clusterName).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.
+			ExpectQuery("^SELECT name, yaml FROM clusters WHERE (.+)$").
+			WillReturnRows(sqlmock.NewRows([]string{"name", "yaml"}))
+	}
+
+	Describe("UpdateClusterConfig", func() {
+		It("should update config and record the revision", func() {
+			mock.ExpectBegin()
+			expectUpdate(yamlV2, "user@example.com")
+			mock.ExpectCommit()
+
+			err := UpdateClusterConfig(context.Background(), sqlxDB, clusterName, yamlV2, "user@example.com", config)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should roll back update if revision can't be recorded", func() {
+			mock.ExpectBegin()
+			mock.
+				ExpectExec("^UPDATE clusters SET yaml = (.+) WHERE name = (.+)$").
+				WithArgs(yamlV2, clusterName).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.
+				ExpectExec("^INSERT INTO cluster_config_revisions(.+)$").
+				WithArgs(clusterName, yamlV2, "user@example.com", clusterName).
+				WillReturnError(fmt.Errorf("pq: connection reset"))
+			mock.ExpectRollback()
+
+			err := UpdateClusterConfig(context.Background(), sqlxDB, clusterName, yamlV2, "user@example.com", config)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("pq: connection reset"))
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.DatabaseError"))
+		})
+
+		It("should return error if config doesn't exist", func() {
+			mock.ExpectBegin()
+			mock.
+				ExpectExec("^UPDATE clusters SET yaml = (.+) WHERE name = (.+)$").
+				WithArgs(yamlV2, clusterName).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectRollback()
+
+			err := UpdateClusterConfig(context.Background(), sqlxDB, clusterName, yamlV2, "user@example.com", config)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("sql: no rows in result set"))
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.DatabaseError"))
+		})
+
+		It("should not update config if configs extending it become invalid", func() {
+			const yamlWithoutPostgres = `apps:
+  app1:
+    image: app1:v3
+`
+			mock.ExpectBegin()
+			mock.
+				ExpectExec("^UPDATE clusters SET yaml = (.+) WHERE name = (.+)$").
+				WithArgs(yamlWithoutPostgres, clusterName).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.
+				ExpectExec("^INSERT INTO cluster_config_revisions(.+)$").
+				WithArgs(clusterName, yamlWithoutPostgres, "user@example.com", clusterName).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.
+				ExpectQuery("^SELECT name, yaml FROM clusters WHERE (.+)$").
+				WillReturnRows(sqlmock.NewRows([]string{"name", "yaml"}).AddRow("variant", `extends: myCustomApps
+apps:
+  app2:
+    image: app2
+    links:
+      - postgres
+`))
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yamlWithoutPostgres))
+			mock.ExpectRollback()
+
+			err := UpdateClusterConfig(context.Background(), sqlxDB, clusterName, yamlWithoutPostgres, "user@example.com", config)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal(
+				"cluster config 'variant' that extends 'myCustomApps' would be invalid: app 'app2' links to unknown 'postgres'",
+			))
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.YamlError"))
+		})
+
+		It("should not update config with invalid yaml", func() {
+			err := UpdateClusterConfig(context.Background(), sqlxDB, clusterName, "apps: [", "user@example.com", config)
+			Expect(err).To(HaveOccurred())
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.YamlError"))
+		})
+	})
+
+	Describe("ListClusterConfigRevisions", func() {
+		It("should list revisions newest first", func() {
+			mock.
+				ExpectQuery("^SELECT (.+) FROM cluster_config_revisions WHERE cluster_name = (.+) ORDER BY revision DESC$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{
+					"cluster_name", "revision", "author_email", "created_at",
+				}).
+					AddRow(clusterName, 2, "user@example.com", time.Now()).
+					AddRow(clusterName, 1, "user@example.com", time.Now()))
+
+			revisions, err := ListClusterConfigRevisions(context.Background(), sqlxDB, clusterName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(revisions).To(HaveLen(2))
+			Expect(revisions[0].Revision).To(Equal(2))
+			Expect(revisions[0].Yaml).To(BeEmpty())
+		})
+	})
+
+	Describe("DiffClusterConfigRevisions", func() {
+		It("should return unified diff of two revisions", func() {
+			expectRevision(1, yamlV1)
+			expectRevision(2, yamlV2)
+
+			diff, err := DiffClusterConfigRevisions(context.Background(), sqlxDB, clusterName, 1, 2)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diff).To(Equal(`--- myCustomApps revision 1
++++ myCustomApps revision 2
+@@ -3,4 +3,4 @@
+     image: postgres:1.0
+ apps:
+   app1:
+-    image: app1:v1
++    image: app1:v2
+`))
+		})
+
+		It("should return empty diff of equal revisions", func() {
+			expectRevision(1, yamlV1)
+			expectRevision(1, yamlV1)
+
+			diff, err := DiffClusterConfigRevisions(context.Background(), sqlxDB, clusterName, 1, 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diff).To(BeEmpty())
+		})
+
+		lines := func(prefix string, count int) string {
+			var buf bytes.Buffer
+			for i := 0; i < count; i++ {
+				fmt.Fprintf(&buf, "%s%d\n", prefix, i)
+			}
+			return buf.String()
+		}
+
+		It("should diff large revisions that change few lines", func() {
+			common := lines("line", 5000)
+			expectRevision(1, common+"image: app1:v1\n"+common)
+			expectRevision(2, common+"image: app1:v2\n"+common)
+
+			diff, err := DiffClusterConfigRevisions(context.Background(), sqlxDB, clusterName, 1, 2)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diff).To(ContainSubstring("@@ -4998,7 +4998,7 @@\n"))
+			Expect(diff).To(ContainSubstring("-image: app1:v1\n+image: app1:v2\n"))
+		})
+
+		It("should return error if revisions have too many changed lines", func() {
+			expectRevision(1, lines("old", 1001))
+			expectRevision(2, lines("new", 1000))
+
+			_, err := DiffClusterConfigRevisions(context.Background(), sqlxDB, clusterName, 1, 2)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("too many changed lines to diff: 1001 lines against 1000"))
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.GenericError"))
+		})
+
+		It("should return error if revision doesn't exist", func() {
+			mock.
+				ExpectQuery("^SELECT (.+) FROM cluster_config_revisions WHERE (.+)$").
+				WithArgs(clusterName, 3).
+				WillReturnError(fmt.Errorf("sql: no rows in result set"))
+
+			_, err := DiffClusterConfigRevisions(context.Background(), sqlxDB, clusterName, 3, 1)
+			Expect(err).To(HaveOccurred())
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.DatabaseError"))
+		})
+	})
+
+	Describe("RollbackClusterConfig", func() {
+		It("should write yaml of revision as a new revision", func() {
+			mock.ExpectBegin()
+			expectRevision(1, yamlV1)
+			expectUpdate(yamlV1, "other@example.com")
+			mock.ExpectCommit()
+
+			err := RollbackClusterConfig(context.Background(), sqlxDB, clusterName, 1, "other@example.com", config)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
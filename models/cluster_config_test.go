@@ -9,7 +9,9 @@
 package models_test
 
 import (
+	"context"
 	"fmt"
+	"time"
 	. "github.com/topfreegames/mystack-controller/models"
 
 	. "github.com/onsi/ginkgo"
@@ -147,6 +149,19 @@ var _ = Describe("ClusterConfig", func() {
 		clusterName = "MyCustomApps"
 	)
 
+	expectWrite := func(yamlStr string) {
+		mock.ExpectBegin()
+		mock.
+			ExpectExec("^INSERT INTO clusters\\(name, yaml\\) VALUES\\((.+)\\)$").
+			WithArgs(clusterName, yamlStr).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.
+			ExpectExec("^INSERT INTO cluster_config_revisions(.+)$").
+			WithArgs(clusterName, yamlStr, "user@example.com", clusterName).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+	}
+
 	Describe("ParseYaml", func() {
 		It("should build correct struct from yaml", func() {
 			clusterConfig, err := ParseYaml(yaml1)
@@ -249,35 +264,64 @@ services {
 
 	Describe("WriteClusterConfig", func() {
 		It("should write cluster config", func() {
-			mock.
-				ExpectExec("^INSERT INTO clusters\\(name, yaml\\) VALUES\\((.+)\\)$").
-				WithArgs(clusterName, yaml1).
-				WillReturnResult(sqlmock.NewResult(1, 1))
+			expectWrite(yaml1)
 
-			err = WriteClusterConfig(sqlxDB, clusterName, yaml1)
+			err = WriteClusterConfig(context.Background(), sqlxDB, clusterName, yaml1, "user@example.com", config)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
 		It("should write cluster config without setup", func() {
-			mock.
-				ExpectExec("^INSERT INTO clusters\\(name, yaml\\) VALUES\\((.+)\\)$").
-				WithArgs(clusterName, yamlWithoutSetup).
-				WillReturnResult(sqlmock.NewResult(1, 1))
+			expectWrite(yamlWithoutSetup)
 
-			err = WriteClusterConfig(sqlxDB, clusterName, yamlWithoutSetup)
+			err = WriteClusterConfig(context.Background(), sqlxDB, clusterName, yamlWithoutSetup, "user@example.com", config)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
 		It("should write cluster config with volumes", func() {
-			mock.
-				ExpectExec("^INSERT INTO clusters\\(name, yaml\\) VALUES\\((.+)\\)$").
-				WithArgs(clusterName, yamlWithVolume).
-				WillReturnResult(sqlmock.NewResult(1, 1))
+			expectWrite(yamlWithVolume)
+
+			err = WriteClusterConfig(context.Background(), sqlxDB, clusterName, yamlWithVolume, "user@example.com", config)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should write cluster config with parameters", func() {
+			yamlStr := `
+parameters:
+  tag:
+    type: string
+    default: v1
+  debug:
+    type: bool
+apps:
+  app1:
+    image: app1:{{ .tag }}
+    port: 5000
+    env:
+      - name: DEBUG
+        value: "{{ .debug }}"
+`
+			expectWrite(yamlStr)
 
-			err = WriteClusterConfig(sqlxDB, clusterName, yamlWithVolume)
+			err = WriteClusterConfig(context.Background(), sqlxDB, clusterName, yamlStr, "user@example.com", config)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
+		It("should return error when writing config with undeclared parameter", func() {
+			yamlStr := `
+parameters:
+  tag:
+    default: v1
+apps:
+  app1:
+    image: app1:{{ .version }}
+    port: 5000
+`
+			err := WriteClusterConfig(context.Background(), sqlxDB, clusterName, yamlStr, "user@example.com", config)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`map has no entry for key "version"`))
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.YamlError"))
+		})
+
 		It("should return error when writing invalid yaml", func() {
 			invalidYaml := `
 services {
@@ -285,33 +329,32 @@ services {
     image: app
 }
       `
-			err := WriteClusterConfig(sqlxDB, clusterName, invalidYaml)
+			err := WriteClusterConfig(context.Background(), sqlxDB, clusterName, invalidYaml, "user@example.com", config)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(Equal("yaml: line 3: mapping values are not allowed in this context"))
 			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.YamlError"))
 		})
 
 		It("should return error when writing cluster with same name", func() {
-			mock.
-				ExpectExec("^INSERT INTO clusters\\(name, yaml\\) VALUES\\((.+)\\)$").
-				WithArgs(clusterName, yaml1).
-				WillReturnResult(sqlmock.NewResult(1, 1))
+			expectWrite(yaml1)
+			mock.ExpectBegin()
 			mock.
 				ExpectExec("^INSERT INTO clusters\\(name, yaml\\) VALUES\\((.+)\\)$").
 				WithArgs(clusterName, yaml1).
 				WillReturnError(fmt.Errorf(`pq: duplicate key value violates unique constraint "clusters_name_key"`))
+			mock.ExpectRollback()
 
-			err = WriteClusterConfig(sqlxDB, clusterName, yaml1)
+			err = WriteClusterConfig(context.Background(), sqlxDB, clusterName, yaml1, "user@example.com", config)
 			Expect(err).NotTo(HaveOccurred())
 
-			err = WriteClusterConfig(sqlxDB, clusterName, yaml1)
+			err = WriteClusterConfig(context.Background(), sqlxDB, clusterName, yaml1, "user@example.com", config)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(Equal(`pq: duplicate key value violates unique constraint "clusters_name_key"`))
 			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.DatabaseError"))
 		})
 
 		It("should return error when clusterName is empty", func() {
-			err := WriteClusterConfig(sqlxDB, "", yaml1)
+			err := WriteClusterConfig(context.Background(), sqlxDB, "", yaml1, "user@example.com", config)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(Equal("invalid empty cluster name"))
 			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.GenericError"))
@@ -324,21 +367,42 @@ services {
     image: app
 }
       `
-			err := WriteClusterConfig(sqlxDB, clusterName, invalidYaml)
+			err := WriteClusterConfig(context.Background(), sqlxDB, clusterName, invalidYaml, "user@example.com", config)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(Equal("yaml: line 3: mapping values are not allowed in this context"))
 			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.YamlError"))
 		})
 
 		It("should return error with empty yaml", func() {
-			err := WriteClusterConfig(sqlxDB, clusterName, "")
+			err := WriteClusterConfig(context.Background(), sqlxDB, clusterName, "", "user@example.com", config)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(Equal("invalid empty config"))
 			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.YamlError"))
 		})
 
+		It("should return error with invalid ttl", func() {
+			err := WriteClusterConfig(context.Background(), sqlxDB, clusterName, "ttl: -1h\n"+yaml1, "user@example.com", config)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("ttl must be positive: -1h"))
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.YamlError"))
+		})
+
 		It("should write object as string on env var", func() {
 			validYaml := `
+apps:
+  app1:
+    image: img
+    env:
+      - name: OBJ
+        value: "{\\\"key\\\": \\\"value\\\"}"
+      `
+			expectWrite(validYaml)
+			err := WriteClusterConfig(context.Background(), sqlxDB, clusterName, validYaml, "user@example.com", config)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should not write env var that breaks the deployment", func() {
+			invalidYaml := `
 apps:
   app1:
     image: img
@@ -346,12 +410,56 @@ apps:
       - name: OBJ
         value: "{\"key\": \"value\"}"
       `
-			mock.
-				ExpectExec("^INSERT INTO clusters\\(name, yaml\\) VALUES\\((.+)\\)$").
-				WithArgs(clusterName, validYaml).
-				WillReturnResult(sqlmock.NewResult(1, 1))
-			err := WriteClusterConfig(sqlxDB, clusterName, validYaml)
+			err := WriteClusterConfig(context.Background(), sqlxDB, clusterName, invalidYaml, "user@example.com", config)
+			Expect(err).To(HaveOccurred())
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.YamlError"))
+		})
+
+		It("should return every problem found on config", func() {
+			invalidYaml := `
+apps:
+  app1:
+    ports:
+      - http
+    links:
+      - db
+`
+			err := WriteClusterConfig(context.Background(), sqlxDB, clusterName, invalidYaml, "user@example.com", config)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal(
+				"apps.app1.image: image is required; apps.app1.ports[0]: invalid port 'http'; apps.app1.links[0]: unknown link 'db'",
+			))
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.YamlError"))
+		})
+	})
+
+	Describe("GetTTL", func() {
+		It("should use ttl from config", func() {
+			clusterConfig := &ClusterConfig{TTL: "2h"}
+			ttl, err := clusterConfig.GetTTL(config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ttl).To(Equal(2 * time.Hour))
+		})
+
+		It("should use default ttl if config has none", func() {
+			clusterConfig := &ClusterConfig{}
+			ttl, err := clusterConfig.GetTTL(config)
 			Expect(err).NotTo(HaveOccurred())
+			Expect(ttl).To(Equal(config.GetDuration("kubernetes.stacks.default-ttl")))
+		})
+
+		It("should return zero if there is no ttl", func() {
+			clusterConfig := &ClusterConfig{}
+			ttl, err := clusterConfig.GetTTL(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ttl).To(BeZero())
+		})
+
+		It("should return error if ttl is invalid", func() {
+			clusterConfig := &ClusterConfig{TTL: "forever"}
+			_, err := clusterConfig.GetTTL(nil)
+			Expect(err).To(HaveOccurred())
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.YamlError"))
 		})
 	})
 
@@ -362,7 +470,7 @@ apps:
 				WithArgs(clusterName).
 				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
 
-			clusterConfig, err := LoadClusterConfig(sqlxDB, clusterName)
+			clusterConfig, err := LoadClusterConfig(context.Background(), sqlxDB, clusterName)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(clusterConfig.Services["postgres"].Image).To(Equal("postgres:1.0"))
 			Expect(clusterConfig.Services["postgres"].Ports).To(BeEquivalentTo([]string{"8585:5432"}))
@@ -403,7 +511,7 @@ apps:
 				WithArgs(clusterName).
 				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yamlWithVolume))
 
-			clusterConfig, err := LoadClusterConfig(sqlxDB, clusterName)
+			clusterConfig, err := LoadClusterConfig(context.Background(), sqlxDB, clusterName)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(clusterConfig.Services["postgres"].Image).To(Equal("postgres:1.0"))
 			Expect(clusterConfig.Services["postgres"].Ports).To(BeEquivalentTo([]string{"8585:5432"}))
@@ -432,7 +540,7 @@ apps:
 				WithArgs(clusterName).
 				WillReturnRows(sqlmock.NewRows([]string{"yaml"}))
 
-			clusterConfig, err := LoadClusterConfig(sqlxDB, clusterName)
+			clusterConfig, err := LoadClusterConfig(context.Background(), sqlxDB, clusterName)
 			Expect(clusterConfig).To(BeNil())
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(Equal("sql: no rows in result set"))
@@ -440,7 +548,7 @@ apps:
 		})
 
 		It("should return error when loading empty clusterName", func() {
-			clusterConfig, err := LoadClusterConfig(sqlxDB, "")
+			clusterConfig, err := LoadClusterConfig(context.Background(), sqlxDB, "")
 			Expect(clusterConfig).To(BeNil())
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(Equal("invalid empty cluster name"))
@@ -459,7 +567,7 @@ services {
 				WithArgs(clusterName).
 				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(invalidYaml))
 
-			clusterConfig, err := LoadClusterConfig(sqlxDB, clusterName)
+			clusterConfig, err := LoadClusterConfig(context.Background(), sqlxDB, clusterName)
 			Expect(clusterConfig).To(BeNil())
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(Equal("yaml: line 3: mapping values are not allowed in this context"))
@@ -472,7 +580,7 @@ services {
 				WithArgs(clusterName).
 				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(""))
 
-			clusterConfig, err := LoadClusterConfig(sqlxDB, clusterName)
+			clusterConfig, err := LoadClusterConfig(context.Background(), sqlxDB, clusterName)
 			Expect(clusterConfig).To(BeNil())
 			Expect(err.Error()).To(Equal("invalid empty config"))
 			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.YamlError"))
@@ -480,30 +588,56 @@ services {
 	})
 
 	Describe("RemoveClusterConfig", func() {
+		expectExtending := func(rows *sqlmock.Rows) {
+			mock.
+				ExpectQuery("^SELECT name, yaml FROM clusters WHERE (.+)$").
+				WillReturnRows(rows)
+		}
+
 		It("should delete existing cluster config", func() {
+			mock.ExpectBegin()
+			expectExtending(sqlmock.NewRows([]string{"name", "yaml"}))
 			mock.
 				ExpectExec("^DELETE FROM clusters WHERE name=(.+)$").
 				WithArgs(clusterName).
 				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
 
-			err = RemoveClusterConfig(sqlxDB, clusterName)
+			err = RemoveClusterConfig(context.Background(), sqlxDB, clusterName)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
+		It("should not delete cluster config extended by others", func() {
+			mock.ExpectBegin()
+			expectExtending(sqlmock.NewRows([]string{"name", "yaml"}).
+				AddRow("variant", fmt.Sprintf("extends: %s\n", clusterName)).
+				AddRow("other", "extends: base\n").
+				AddRow("variant2", "extends: variant\n"))
+			mock.ExpectRollback()
+
+			err = RemoveClusterConfig(context.Background(), sqlxDB, clusterName)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal(fmt.Sprintf("cluster config '%s' is extended by 'variant', 'variant2'", clusterName)))
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.GenericError"))
+		})
+
 		It("should return error when deleting non existing cluster config", func() {
+			mock.ExpectBegin()
+			expectExtending(sqlmock.NewRows([]string{"name", "yaml"}))
 			mock.
 				ExpectExec("^DELETE FROM clusters WHERE name=(.+)$").
 				WithArgs(clusterName).
 				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectRollback()
 
-			err = RemoveClusterConfig(sqlxDB, clusterName)
+			err = RemoveClusterConfig(context.Background(), sqlxDB, clusterName)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(Equal("sql: no rows in result set"))
 			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.DatabaseError"))
 		})
 
 		It("should return error when cluster name is empty", func() {
-			err = RemoveClusterConfig(sqlxDB, "")
+			err = RemoveClusterConfig(context.Background(), sqlxDB, "")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(Equal("invalid empty cluster name"))
 			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.GenericError"))
@@ -516,7 +650,7 @@ services {
 				ExpectQuery("^SELECT name FROM clusters$").
 				WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("cluster1").AddRow("cluster2"))
 
-			names, err := ListClusterConfig(sqlxDB)
+			names, err := ListClusterConfig(context.Background(), sqlxDB)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(names).To(ConsistOf("cluster1", "cluster2"))
 		})
@@ -526,7 +660,7 @@ services {
 				ExpectQuery("^SELECT name FROM clusters$").
 				WillReturnRows(sqlmock.NewRows([]string{"name"}))
 
-			names, err := ListClusterConfig(sqlxDB)
+			names, err := ListClusterConfig(context.Background(), sqlxDB)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(names).To(BeEmpty())
 		})
@@ -539,7 +673,7 @@ services {
 				WithArgs(clusterName).
 				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yaml1))
 
-			config, err := ClusterConfigDetails(sqlxDB, clusterName)
+			config, err := ClusterConfigDetails(context.Background(), sqlxDB, clusterName)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(config).To(Equal(yaml1))
 		})
@@ -550,7 +684,7 @@ services {
 				WithArgs(clusterName).
 				WillReturnError(fmt.Errorf(`pq: no rows in result set`))
 
-			config, err := ClusterConfigDetails(sqlxDB, clusterName)
+			config, err := ClusterConfigDetails(context.Background(), sqlxDB, clusterName)
 			Expect(err).To(HaveOccurred())
 			Expect(config).To(BeEmpty())
 		})
@@ -9,6 +9,8 @@
 package models_test
 
 import (
+	"context"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	. "github.com/topfreegames/mystack-controller/models"
@@ -40,7 +42,7 @@ apps:
 `
 		clusterName = "myCustomApps"
 		username    = "user"
-		namespace   = "mystack-user"
+		namespace   = "mystack-user-mycustomapps-a62c7ed6aa"
 	)
 	var (
 		err     error
@@ -51,28 +53,29 @@ apps:
 	)
 
 	mockCluster := func(username string) *Cluster {
-		svcDeployment1 := NewDeployment("test0", username, "svc1", ports, nil, nil, nil, nil, nil, config)
-		appDeployment1 := NewDeployment("test1", username, "app1", ports, nil, nil, nil, nil, nil, config)
-		appDeployment2 := NewDeployment("test2", username, "app2", ports, nil, nil, nil, nil, nil, config)
-		appDeployment3 := NewDeployment("test3", username, "app3", ports, nil, nil, nil, nil, nil, config)
+		svcDeployment1 := NewDeployment("test0", username, namespace, "svc1", ports, nil, nil, nil, nil, nil, config)
+		appDeployment1 := NewDeployment("test1", username, namespace, "app1", ports, nil, nil, nil, nil, nil, config)
+		appDeployment2 := NewDeployment("test2", username, namespace, "app2", ports, nil, nil, nil, nil, nil, config)
+		appDeployment3 := NewDeployment("test3", username, namespace, "app3", ports, nil, nil, nil, nil, nil, config)
 
 		return &Cluster{
 			Username:       username,
+			ClusterName:    clusterName,
 			Namespace:      namespace,
 			AppDeployments: []*Deployment{appDeployment1, appDeployment2, appDeployment3},
 			SvcDeployments: []*Deployment{svcDeployment1},
 			K8sServices: map[*Deployment]*Service{
-				appDeployment1: NewService("test1", username, portMap, false),
-				appDeployment2: NewService("test2", username, portMap, false),
-				appDeployment3: NewService("test3", username, portMap, false),
-				svcDeployment1: NewService("test0", username, portMap, true),
+				appDeployment1: NewService("test1", namespace, portMap, false, false),
+				appDeployment2: NewService("test2", namespace, portMap, false, false),
+				appDeployment3: NewService("test3", namespace, portMap, false, false),
+				svcDeployment1: NewService("test0", namespace, portMap, true, false),
 			},
 		}
 	}
 
 	Describe("NewCluster", func() {
 		It("should construct a new cluster", func() {
-			err = WriteClusterConfig(db, clusterName, yaml1)
+			err = WriteClusterConfig(context.Background(), db, clusterName, yaml1, "user@example.com", config)
 			Expect(err).NotTo(HaveOccurred())
 
 			cluster, err := NewCluster(db, username, clusterName, &mTest.MockReadiness{}, &mTest.MockReadiness{}, config)
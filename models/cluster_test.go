@@ -9,23 +9,37 @@
 package models_test
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	. "github.com/topfreegames/mystack-controller/models"
 
-	"database/sql"
-
-	"github.com/jmoiron/sqlx"
 	mTest "github.com/topfreegames/mystack-controller/testing"
 	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/pkg/api/v1"
 	"k8s.io/client-go/pkg/fields"
 	"k8s.io/client-go/pkg/labels"
 )
 
+//failOnceReadiness fails the first wait and succeeds on the next ones
+type failOnceReadiness struct {
+	failed bool
+}
+
+func (r *failOnceReadiness) WaitForCompletion(kubernetes.Interface, interface{}) error {
+	if r.failed {
+		return nil
+	}
+	r.failed = true
+	return fmt.Errorf("failed to run stup job")
+}
+
 var _ = Describe("Cluster", func() {
 	const (
 		yaml1 = `
@@ -190,15 +204,14 @@ apps:
 `
 	)
 	var (
-		db          *sql.DB
-		sqlxDB      *sqlx.DB
+		sqlxDB      *mTest.MockDB
 		mock        sqlmock.Sqlmock
 		err         error
 		clusterName = "MyCustomApps"
 		domain      = "mystack.com"
 		clientset   *fake.Clientset
 		username    = "user"
-		namespace   = "mystack-user"
+		namespace   = "mystack-user-mycustomapps-a62c7ed6aa"
 		ports       = []int{5000, 5002}
 		portMaps    = []*PortMap{
 			&PortMap{Port: 5000, TargetPort: 5000},
@@ -215,15 +228,15 @@ apps:
 	)
 
 	mockCluster := func(period, timeout int, username string) *Cluster {
-		namespace := fmt.Sprintf("mystack-%s", username)
+		namespace := ClusterNamespace(username, clusterName)
 
-		appDeployment1 := NewDeployment("test1", username, "app1", ports, nil, nil, nil, nil, nil, config)
-		appDeployment2 := NewDeployment("test2", username, "app2", ports, nil, nil, nil, nil, nil, config)
-		appDeployment3 := NewDeployment("test3", username, "app3", ports, []*EnvVar{
+		appDeployment1 := NewDeployment("test1", username, namespace, "app1", ports, nil, nil, nil, nil, nil, config)
+		appDeployment2 := NewDeployment("test2", username, namespace, "app2", ports, nil, nil, nil, nil, nil, config)
+		appDeployment3 := NewDeployment("test3", username, namespace, "app3", ports, []*EnvVar{
 			&EnvVar{Name: "VARIABLE_1", Value: "100"},
 		}, nil, nil, nil, nil, config)
 
-		svcDeployment1 := NewDeployment("test0", username, "svc1", ports, nil, &Probe{
+		svcDeployment1 := NewDeployment("test0", username, namespace, "svc1", ports, nil, &Probe{
 			Command:        []string{"echo", "ready"},
 			TimeoutSeconds: timeout,
 			PeriodSeconds:  period,
@@ -231,17 +244,20 @@ apps:
 
 		return &Cluster{
 			Username:       username,
+			ClusterName:    clusterName,
 			Namespace:      namespace,
 			AppDeployments: []*Deployment{appDeployment1, appDeployment2, appDeployment3},
 			SvcDeployments: []*Deployment{svcDeployment1},
 			K8sServices: map[*Deployment]*Service{
-				appDeployment1: NewService("test1", username, portMaps, false),
-				appDeployment2: NewService("test2", username, portMaps, false),
-				appDeployment3: NewService("test3", username, portMaps, false),
-				svcDeployment1: NewService("test0", username, portMaps, true),
+				appDeployment1: NewService("test1", namespace, portMaps, false, false),
+				appDeployment2: NewService("test2", namespace, portMaps, false, false),
+				appDeployment3: NewService("test3", namespace, portMaps, false, false),
+				svcDeployment1: NewService("test0", namespace, portMaps, true, false),
 			},
 			Job: NewJob(
+				"setup",
 				username,
+				namespace,
 				&Setup{
 					Image:          "setup-img",
 					PeriodSeconds:  period,
@@ -259,26 +275,27 @@ apps:
 	BeforeEach(func() {
 		clientset = fake.NewSimpleClientset()
 
-		appDeploymentClusterWithVolume = NewDeployment("app1", username, "app1", []int{5000}, nil, nil, nil, nil, nil, config)
-		svcDeploymentClusterWithVolume = NewDeployment("svc1", username, "svc1", []int{5000}, nil, nil, &VolumeMount{Name: "svc-volume", MountPath: "/data"}, nil, nil, config)
+		appDeploymentClusterWithVolume = NewDeployment("app1", username, namespace, "app1", []int{5000}, nil, nil, nil, nil, nil, config)
+		svcDeploymentClusterWithVolume = NewDeployment("svc1", username, namespace, "svc1", []int{5000}, nil, nil, &VolumeMount{Name: "svc-volume", MountPath: "/data"}, nil, nil, config)
 
 		mockedClusterWithVolume = &Cluster{
-			Username:  username,
-			Namespace: namespace,
+			Username:    username,
+			ClusterName: clusterName,
+			Namespace:   namespace,
 			PersistentVolumeClaims: []*PersistentVolumeClaim{
 				&PersistentVolumeClaim{Name: "svc-volume", Storage: "1Gi", Namespace: namespace},
 			},
 			AppDeployments: []*Deployment{appDeploymentClusterWithVolume},
 			SvcDeployments: []*Deployment{svcDeploymentClusterWithVolume},
 			K8sServices: map[*Deployment]*Service{
-				appDeploymentClusterWithVolume: NewService("app1", username, []*PortMap{
+				appDeploymentClusterWithVolume: NewService("app1", namespace, []*PortMap{
 					&PortMap{Port: 5000, TargetPort: 5000},
-				}, false),
-				svcDeploymentClusterWithVolume: NewService("svc1", username, []*PortMap{
+				}, false, false),
+				svcDeploymentClusterWithVolume: NewService("svc1", namespace, []*PortMap{
 					&PortMap{Port: 5000, TargetPort: 5000},
-				}, true),
+				}, true, false),
 			},
-			Job: NewJob(username, &Setup{
+			Job: NewJob("setup", username, namespace, &Setup{
 				Image:          "setup-img",
 				PeriodSeconds:  0,
 				TimeoutSeconds: 0,
@@ -290,9 +307,8 @@ apps:
 
 	Describe("NewCluster", func() {
 		BeforeEach(func() {
-			db, mock, err = sqlmock.New()
+			sqlxDB, mock, err = mTest.NewMockDB()
 			Expect(err).NotTo(HaveOccurred())
-			sqlxDB = sqlx.NewDb(db, "postgres")
 
 			config, err = mTest.GetDefaultConfig()
 			Expect(err).NotTo(HaveOccurred())
@@ -301,7 +317,7 @@ apps:
 		AfterEach(func() {
 			err = mock.ExpectationsWereMet()
 			Expect(err).NotTo(HaveOccurred())
-			db.Close()
+			sqlxDB.Close()
 		})
 
 		It("should return cluster from config on DB", func() {
@@ -404,29 +420,30 @@ apps:
     ports: 
       - "5000"
 `
-			appDeploymentCluster := NewDeployment("app1", username, "app1", []int{5000}, nil, nil, nil, nil, nil, config)
-			svcDeploymentCluster1 := NewDeployment("svc1", username, "svc1", []int{5000}, nil, nil, nil, nil, nil, config)
-			svcDeploymentCluster2 := NewDeployment("svc2", username, "svc2", []int{5000}, nil, nil, nil, nil, nil, config)
+			appDeploymentCluster := NewDeployment("app1", username, namespace, "app1", []int{5000}, nil, nil, nil, nil, nil, config)
+			svcDeploymentCluster1 := NewDeployment("svc1", username, namespace, "svc1", []int{5000}, nil, nil, nil, nil, nil, config)
+			svcDeploymentCluster2 := NewDeployment("svc2", username, namespace, "svc2", []int{5000}, nil, nil, nil, nil, nil, config)
 
 			svcDeploymentCluster1.Links = []*Deployment{svcDeploymentCluster2}
 
 			mockedClusterWithLinks := &Cluster{
 				Username:       username,
+				ClusterName:    clusterName,
 				Namespace:      namespace,
 				AppDeployments: []*Deployment{appDeploymentCluster},
 				SvcDeployments: []*Deployment{svcDeploymentCluster1, svcDeploymentCluster2},
 				K8sServices: map[*Deployment]*Service{
-					appDeploymentCluster: NewService("app1", username, []*PortMap{
+					appDeploymentCluster: NewService("app1", namespace, []*PortMap{
 						&PortMap{Port: 5000, TargetPort: 5000},
-					}, false),
-					svcDeploymentCluster1: NewService("svc1", username, []*PortMap{
+					}, false, false),
+					svcDeploymentCluster1: NewService("svc1", namespace, []*PortMap{
 						&PortMap{Port: 5000, TargetPort: 5000},
-					}, true),
-					svcDeploymentCluster2: NewService("svc2", username, []*PortMap{
+					}, true, false),
+					svcDeploymentCluster2: NewService("svc2", namespace, []*PortMap{
 						&PortMap{Port: 5000, TargetPort: 5000},
-					}, true),
+					}, true, false),
 				},
-				Job: NewJob(username, &Setup{
+				Job: NewJob("setup", username, namespace, &Setup{
 					Image:          "setup-img",
 					PeriodSeconds:  0,
 					TimeoutSeconds: 0,
@@ -473,29 +490,30 @@ apps:
     ports: 
       - "5000"
 `
-			appDeploymentCluster1 := NewDeployment("app1", username, "app1", []int{5000}, nil, nil, nil, nil, nil, config)
-			appDeploymentCluster2 := NewDeployment("app2", username, "app2", []int{5000}, nil, nil, nil, nil, nil, config)
-			svcDeploymentCluster := NewDeployment("svc1", username, "svc1", []int{5000}, nil, nil, nil, nil, nil, config)
+			appDeploymentCluster1 := NewDeployment("app1", username, namespace, "app1", []int{5000}, nil, nil, nil, nil, nil, config)
+			appDeploymentCluster2 := NewDeployment("app2", username, namespace, "app2", []int{5000}, nil, nil, nil, nil, nil, config)
+			svcDeploymentCluster := NewDeployment("svc1", username, namespace, "svc1", []int{5000}, nil, nil, nil, nil, nil, config)
 
 			appDeploymentCluster1.Links = []*Deployment{appDeploymentCluster2}
 
 			mockedClusterWithLinks := &Cluster{
 				Username:       username,
+				ClusterName:    clusterName,
 				Namespace:      namespace,
 				AppDeployments: []*Deployment{appDeploymentCluster1, appDeploymentCluster2},
 				SvcDeployments: []*Deployment{svcDeploymentCluster},
 				K8sServices: map[*Deployment]*Service{
-					appDeploymentCluster1: NewService("app1", username, []*PortMap{
+					appDeploymentCluster1: NewService("app1", namespace, []*PortMap{
 						&PortMap{Port: 5000, TargetPort: 5000},
-					}, false),
-					appDeploymentCluster2: NewService("app2", username, []*PortMap{
+					}, false, false),
+					appDeploymentCluster2: NewService("app2", namespace, []*PortMap{
 						&PortMap{Port: 5000, TargetPort: 5000},
-					}, false),
-					svcDeploymentCluster: NewService("svc1", username, []*PortMap{
+					}, false, false),
+					svcDeploymentCluster: NewService("svc1", namespace, []*PortMap{
 						&PortMap{Port: 5000, TargetPort: 5000},
-					}, true),
+					}, true, false),
 				},
-				Job: NewJob(username, &Setup{
+				Job: NewJob("setup", username, namespace, &Setup{
 					Image:          "setup-img",
 					PeriodSeconds:  0,
 					TimeoutSeconds: 0,
@@ -677,14 +695,60 @@ apps:
 			Expect(volume.Spec.AccessModes).To(Equal([]v1.PersistentVolumeAccessMode{"ReadWriteOnce"}))
 		})
 
-		It("should return error if creating same cluster twice", func() {
+		It("should keep existing objects when creating same cluster twice", func() {
 			cluster := mockCluster(0, 0, username)
 			err := cluster.Create(nil, clientset)
 			Expect(err).NotTo(HaveOccurred())
 
+			err = cluster.Create(nil, clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			deploys, err := clientset.ExtensionsV1beta1().Deployments(namespace).List(listOptions)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deploys.Items).To(HaveLen(4))
+		})
+
+		It("should complete a partially created cluster", func() {
+			cluster := mockCluster(0, 0, username)
+			err := CreateNamespace(clientset, username, clusterName)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = cluster.SvcDeployments[0].Deploy(clientset)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = cluster.Job.Run(clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = cluster.Create(nil, clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			deploys, err := clientset.ExtensionsV1beta1().Deployments(namespace).List(listOptions)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deploys.Items).To(HaveLen(4))
+
+			services, err := clientset.CoreV1().Services(namespace).List(listOptions)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(services.Items).To(HaveLen(4))
+
+			running, err := CreationRunning(clientset, namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(running).To(BeFalse())
+		})
+
+		It("should return error if an object exists with a different config", func() {
+			cluster := mockCluster(0, 0, username)
+			err := CreateNamespace(clientset, username, clusterName)
+			Expect(err).NotTo(HaveOccurred())
+			other := NewDeployment("test0", username, namespace, "other", ports, nil, nil, nil, nil, nil, config)
+			_, err = other.Deploy(clientset)
+			Expect(err).NotTo(HaveOccurred())
+
 			err = cluster.Create(nil, clientset)
 			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(Equal("namespace for user 'user' already exists"))
+			Expect(err.Error()).To(Equal("deployment 'test0' already exists with a different config"))
+			Expect(NamespaceExists(clientset, namespace)).To(BeTrue())
+
+			failedStep, err := FailedStep(clientset, namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(failedStep).To(Equal(StepSvcDeployments))
 		})
 
 		It("should run without setup image", func() {
@@ -708,16 +772,17 @@ apps:
 
 		It("should run with env var as object", func() {
 			obj := "{\\\"key\\\": \\\"value\\\"}"
-			appDeployment := NewDeployment("test1", username, "app1", ports, []*EnvVar{
+			appDeployment := NewDeployment("test1", username, namespace, "app1", ports, []*EnvVar{
 				&EnvVar{Name: "VARIABLE_1", Value: obj},
 			}, nil, nil, nil, nil, config)
 
 			cluster := &Cluster{
 				Username:       username,
+				ClusterName:    clusterName,
 				Namespace:      namespace,
 				AppDeployments: []*Deployment{appDeployment},
 				K8sServices: map[*Deployment]*Service{
-					appDeployment: NewService("test1", username, portMaps, false),
+					appDeployment: NewService("test1", namespace, portMaps, false, false),
 				},
 				DeploymentReadiness: &mTest.MockReadiness{},
 				JobReadiness:        &mTest.MockReadiness{},
@@ -732,29 +797,30 @@ apps:
 		})
 
 		It("should run cluster with links", func() {
-			appDeploymentCluster1 := NewDeployment("app1", username, "app1", []int{5000}, nil, nil, nil, nil, nil, config)
-			appDeploymentCluster2 := NewDeployment("app2", username, "app2", []int{5000}, nil, nil, nil, nil, nil, config)
-			svcDeploymentCluster := NewDeployment("svc1", username, "svc1", []int{5000}, nil, nil, nil, nil, nil, config)
+			appDeploymentCluster1 := NewDeployment("app1", username, namespace, "app1", []int{5000}, nil, nil, nil, nil, nil, config)
+			appDeploymentCluster2 := NewDeployment("app2", username, namespace, "app2", []int{5000}, nil, nil, nil, nil, nil, config)
+			svcDeploymentCluster := NewDeployment("svc1", username, namespace, "svc1", []int{5000}, nil, nil, nil, nil, nil, config)
 
 			appDeploymentCluster1.Links = []*Deployment{appDeploymentCluster2}
 
 			mockedClusterWithLinks := &Cluster{
 				Username:       username,
+				ClusterName:    clusterName,
 				Namespace:      namespace,
 				AppDeployments: []*Deployment{appDeploymentCluster1, appDeploymentCluster2},
 				SvcDeployments: []*Deployment{svcDeploymentCluster},
 				K8sServices: map[*Deployment]*Service{
-					appDeploymentCluster1: NewService("app1", username, []*PortMap{
+					appDeploymentCluster1: NewService("app1", namespace, []*PortMap{
 						&PortMap{Port: 5000, TargetPort: 5000},
-					}, false),
-					appDeploymentCluster2: NewService("app2", username, []*PortMap{
+					}, false, false),
+					appDeploymentCluster2: NewService("app2", namespace, []*PortMap{
 						&PortMap{Port: 5000, TargetPort: 5000},
-					}, false),
-					svcDeploymentCluster: NewService("svc1", username, []*PortMap{
+					}, false, false),
+					svcDeploymentCluster: NewService("svc1", namespace, []*PortMap{
 						&PortMap{Port: 5000, TargetPort: 5000},
-					}, true),
+					}, true, false),
 				},
-				Job: NewJob(username, &Setup{
+				Job: NewJob("setup", username, namespace, &Setup{
 					Image:          "setup-img",
 					PeriodSeconds:  0,
 					TimeoutSeconds: 0,
@@ -768,6 +834,241 @@ apps:
 		})
 	})
 
+	Describe("KeepOnFailure", func() {
+		It("should delete namespace on failure by default", func() {
+			cluster := mockCluster(0, 0, username)
+			cluster.JobReadiness = &failOnceReadiness{}
+
+			err := cluster.Create(nil, clientset)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("failed to run stup job"))
+			Expect(NamespaceExists(clientset, namespace)).To(BeFalse())
+		})
+
+		It("should keep namespace it didn't create on failure", func() {
+			cluster := mockCluster(0, 0, username)
+			err := cluster.Create(nil, clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			cluster = mockCluster(0, 0, username)
+			cluster.JobReadiness = &failOnceReadiness{}
+			err = cluster.Create(nil, clientset)
+			Expect(err).To(HaveOccurred())
+			Expect(NamespaceExists(clientset, namespace)).To(BeTrue())
+
+			failedStep, err := FailedStep(clientset, namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(failedStep).To(Equal(StepSetup))
+		})
+
+		It("should keep namespace and save failed step", func() {
+			cluster := mockCluster(0, 0, username)
+			cluster.KeepOnFailure = true
+			cluster.JobReadiness = &failOnceReadiness{}
+
+			err := cluster.Create(nil, clientset)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("failed to run stup job"))
+			Expect(NamespaceExists(clientset, namespace)).To(BeTrue())
+
+			failedStep, err := FailedStep(clientset, namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(failedStep).To(Equal(StepSetup))
+
+			deploys, err := clientset.ExtensionsV1beta1().Deployments(namespace).List(listOptions)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deploys.Items).To(HaveLen(1))
+		})
+
+		It("should report failed step on status", func() {
+			cluster := mockCluster(0, 0, username)
+			cluster.KeepOnFailure = true
+			cluster.JobReadiness = &failOnceReadiness{}
+
+			err := cluster.Create(nil, clientset)
+			Expect(err).To(HaveOccurred())
+
+			status, err := cluster.Status(clientset)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status.FailedStep).To(Equal(StepSetup))
+		})
+	})
+
+	Describe("Claim", func() {
+		It("should not claim a namespace being created", func() {
+			cluster := mockCluster(0, 0, username)
+			err := cluster.Claim(clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			running, err := CreationRunning(clientset, namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(running).To(BeTrue())
+
+			other := mockCluster(0, 0, username)
+			err = other.Claim(clientset)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("namespace for user 'user' already exists and is being created"))
+
+			err = other.Create(nil, clientset)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("namespace for user 'user' already exists and is being created"))
+		})
+
+		It("should claim a namespace whose lease wasn't renewed", func() {
+			err := CreateNamespace(clientset, username, clusterName)
+			Expect(err).NotTo(HaveOccurred())
+			ns, err := clientset.CoreV1().Namespaces().Get(namespace)
+			Expect(err).NotTo(HaveOccurred())
+			ns.Annotations["mystack/creatingBy"] = "stopped-controller"
+			ns.Annotations["mystack/creatingRenewedAt"] = time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339)
+			_, err = clientset.CoreV1().Namespaces().Update(ns)
+			Expect(err).NotTo(HaveOccurred())
+
+			running, err := CreationRunning(clientset, namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(running).To(BeFalse())
+
+			cluster := mockCluster(0, 0, username)
+			err = cluster.Create(nil, clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			ns, err = clientset.CoreV1().Namespaces().Get(namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ns.Annotations).NotTo(HaveKey("mystack/creatingBy"))
+		})
+
+		It("should delete the namespace it created when the claim is released", func() {
+			cluster := mockCluster(0, 0, username)
+			err := cluster.Claim(clientset)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(NamespaceExists(clientset, namespace)).To(BeTrue())
+
+			err = cluster.ReleaseClaim(clientset)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(NamespaceExists(clientset, namespace)).To(BeFalse())
+		})
+
+		It("should keep the namespace it didn't create when the claim is released", func() {
+			err := CreateNamespace(clientset, username, clusterName)
+			Expect(err).NotTo(HaveOccurred())
+
+			cluster := mockCluster(0, 0, username)
+			err = cluster.Claim(clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = cluster.ReleaseClaim(clientset)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(NamespaceExists(clientset, namespace)).To(BeTrue())
+
+			running, err := CreationRunning(clientset, namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(running).To(BeFalse())
+		})
+	})
+
+	Describe("Resume", func() {
+		It("should run again from failed step", func() {
+			cluster := mockCluster(0, 0, username)
+			cluster.KeepOnFailure = true
+			cluster.JobReadiness = &failOnceReadiness{}
+
+			err := cluster.Create(nil, clientset)
+			Expect(err).To(HaveOccurred())
+
+			err = cluster.Resume(nil, clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			failedStep, err := FailedStep(clientset, namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(failedStep).To(BeEmpty())
+
+			deploys, err := clientset.ExtensionsV1beta1().Deployments(namespace).List(listOptions)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deploys.Items).To(HaveLen(4))
+
+			_, err = clientset.BatchV1().Jobs(namespace).Get("setup")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should return error if cluster has no failed step", func() {
+			cluster := mockCluster(0, 0, username)
+			err := cluster.Create(nil, clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = cluster.Resume(nil, clientset)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("cluster 'MyCustomApps' has no failed step to resume"))
+		})
+
+		It("should return error if cluster is not running", func() {
+			cluster := mockCluster(0, 0, username)
+			err := cluster.Resume(nil, clientset)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Namespace \"mystack-user-mycustomapps-a62c7ed6aa\" not found"))
+		})
+	})
+
+	Describe("PostSetup", func() {
+		postSetupCluster := func(wait bool) *Cluster {
+			cluster := mockCluster(0, 0, username)
+			cluster.Job = nil
+			cluster.PostJob = NewJob("post-setup", username, namespace, &Setup{
+				Image:             "post-setup-img",
+				WaitForCompletion: wait,
+			}, nil)
+			cluster.JobReadiness = &failOnceReadiness{}
+			return cluster
+		}
+
+		It("should not wait for post-setup job by default", func() {
+			cluster := postSetupCluster(false)
+
+			err := cluster.Create(nil, clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = clientset.BatchV1().Jobs(namespace).Get("post-setup")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should fail creation if post-setup job fails and waitForCompletion is set", func() {
+			cluster := postSetupCluster(true)
+
+			err := cluster.Create(nil, clientset)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("failed to run stup job"))
+			Expect(NamespaceExists(clientset, namespace)).To(BeFalse())
+		})
+
+		It("should get setup and post-setup jobs", func() {
+			cluster := postSetupCluster(true)
+			cluster.Job = NewJob("setup", username, namespace, &Setup{Image: "setup-img"}, nil)
+
+			job, err := cluster.GetJob("post-setup")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job).To(Equal(cluster.PostJob))
+
+			job, err = cluster.GetJob("setup")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job).To(Equal(cluster.Job))
+		})
+
+		It("should return error if job isn't on cluster", func() {
+			cluster := postSetupCluster(true)
+
+			_, err := cluster.GetJob("setup")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("job 'setup' not found"))
+		})
+
+		It("should return error when job has no pods to log", func() {
+			cluster := postSetupCluster(false)
+
+			err := cluster.PostJob.WriteJobLogs(context.Background(), clientset, nil, ioutil.Discard)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("pods of job 'post-setup' not found"))
+		})
+	})
+
 	Describe("Delete", func() {
 		It("should delete cluster", func() {
 			cluster := mockCluster(0, 0, username)
@@ -801,22 +1102,22 @@ apps:
 			err = cluster1.Delete(clientset)
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(NamespaceExists(clientset, "mystack-user1")).To(BeFalse())
-			Expect(NamespaceExists(clientset, "mystack-user2")).To(BeTrue())
+			Expect(NamespaceExists(clientset, "mystack-user1-mycustomapps-d65a7655b6")).To(BeFalse())
+			Expect(NamespaceExists(clientset, "mystack-user2-mycustomapps-6f2356e459")).To(BeTrue())
 
-			deploys, err := clientset.ExtensionsV1beta1().Deployments("mystack-user1").List(listOptions)
+			deploys, err := clientset.ExtensionsV1beta1().Deployments("mystack-user1-mycustomapps-d65a7655b6").List(listOptions)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(deploys.Items).To(BeEmpty())
 
-			services, err := clientset.CoreV1().Services("mystack-user1").List(listOptions)
+			services, err := clientset.CoreV1().Services("mystack-user1-mycustomapps-d65a7655b6").List(listOptions)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(services.Items).To(BeEmpty())
 
-			deploys, err = clientset.ExtensionsV1beta1().Deployments("mystack-user2").List(listOptions)
+			deploys, err = clientset.ExtensionsV1beta1().Deployments("mystack-user2-mycustomapps-6f2356e459").List(listOptions)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(deploys.Items).To(HaveLen(4))
 
-			services, err = clientset.CoreV1().Services("mystack-user2").List(listOptions)
+			services, err = clientset.CoreV1().Services("mystack-user2-mycustomapps-6f2356e459").List(listOptions)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(services.Items).To(HaveLen(4))
 		})
@@ -853,22 +1154,193 @@ apps:
 		})
 	})
 
+	Describe("Extend", func() {
+		It("should postpone cluster expiration", func() {
+			cluster := mockCluster(0, 0, username)
+			cluster.TTL = time.Hour
+			err := cluster.Create(nil, clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			expiresAt, err := cluster.Extend(clientset, 24*time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(expiresAt).To(BeTemporally("~", time.Now().Add(24*time.Hour), time.Minute))
+
+			saved, err := ClusterExpiration(clientset, cluster.Namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*saved).To(BeTemporally("~", expiresAt, time.Second))
+		})
+
+		It("should use cluster ttl if none is informed", func() {
+			cluster := mockCluster(0, 0, username)
+			cluster.TTL = time.Hour
+			err := cluster.Create(nil, clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			expiresAt, err := cluster.Extend(clientset, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(expiresAt).To(BeTemporally("~", time.Now().Add(time.Hour), time.Minute))
+		})
+
+		It("should return error if cluster has no ttl", func() {
+			cluster := mockCluster(0, 0, username)
+			err := cluster.Create(nil, clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = cluster.Extend(clientset, 0)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("cluster has no ttl, inform one to extend it"))
+		})
+
+		It("should return error if cluster is not running", func() {
+			cluster := mockCluster(0, 0, username)
+			_, err := cluster.Extend(clientset, time.Hour)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("namespace for user 'user' not found"))
+		})
+	})
+
+	Describe("Sleep", func() {
+		It("should scale every deployment to zero and wake them back", func() {
+			cluster := mockCluster(0, 0, username)
+			err := cluster.Create(nil, clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = cluster.Sleep(nil, clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			sleeping, err := cluster.IsSleeping(clientset)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sleeping).To(BeTrue())
+
+			deploys, err := clientset.ExtensionsV1beta1().Deployments(namespace).List(listOptions)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deploys.Items).To(HaveLen(4))
+			for _, deploy := range deploys.Items {
+				Expect(*deploy.Spec.Replicas).To(BeEquivalentTo(0))
+			}
+
+			err = cluster.Wake(nil, clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			sleeping, err = cluster.IsSleeping(clientset)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sleeping).To(BeFalse())
+
+			deploys, err = clientset.ExtensionsV1beta1().Deployments(namespace).List(listOptions)
+			Expect(err).NotTo(HaveOccurred())
+			for _, deploy := range deploys.Items {
+				Expect(*deploy.Spec.Replicas).To(BeEquivalentTo(1))
+			}
+		})
+
+		It("should report cluster as sleeping on apps", func() {
+			cluster := mockCluster(0, 0, username)
+			err := cluster.Create(nil, clientset)
+			Expect(err).NotTo(HaveOccurred())
+			err = cluster.Sleep(nil, clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			apps, err := cluster.Apps(config, clientset, domain)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(apps.Sleeping).To(BeTrue())
+		})
+
+		It("should return error if cluster is being created", func() {
+			cluster := mockCluster(0, 0, username)
+			err := cluster.Create(nil, clientset)
+			Expect(err).NotTo(HaveOccurred())
+			err = mockCluster(0, 0, username).Claim(clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = cluster.Sleep(nil, clientset)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("namespace for user 'user' already exists and is being created"))
+
+			err = cluster.Wake(nil, clientset)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("namespace for user 'user' already exists and is being created"))
+		})
+
+		It("should return error if cluster is not running", func() {
+			cluster := mockCluster(0, 0, username)
+			err := cluster.Sleep(nil, clientset)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("namespace for user 'user' not found"))
+		})
+	})
+
+	Describe("ApplySchedule", func() {
+		var (
+			monday9am  = time.Date(2017, 5, 8, 9, 0, 0, 0, time.UTC)
+			monday10pm = time.Date(2017, 5, 8, 22, 0, 0, 0, time.UTC)
+		)
+
+		It("should sleep and wake cluster on schedule boundaries", func() {
+			cluster := mockCluster(0, 0, username)
+			cluster.Schedule = &Schedule{Days: []string{"mon", "fri"}, From: "08:00", To: "20:00"}
+			err := cluster.Create(nil, clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			state, err := cluster.ApplySchedule(nil, clientset, monday10pm)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state).To(Equal(ScheduleAsleep))
+			Expect(cluster.IsSleeping(clientset)).To(BeTrue())
+
+			state, err = cluster.ApplySchedule(nil, clientset, monday10pm.Add(time.Minute))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state).To(BeEmpty())
+
+			state, err = cluster.ApplySchedule(nil, clientset, monday9am.Add(7*24*time.Hour))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state).To(Equal(ScheduleAwake))
+			Expect(cluster.IsSleeping(clientset)).To(BeFalse())
+		})
+
+		It("should keep a cluster woken by hand until next boundary", func() {
+			cluster := mockCluster(0, 0, username)
+			cluster.Schedule = &Schedule{From: "08:00", To: "20:00"}
+			err := cluster.Create(nil, clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = cluster.ApplySchedule(nil, clientset, monday10pm)
+			Expect(err).NotTo(HaveOccurred())
+			err = cluster.Wake(nil, clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			state, err := cluster.ApplySchedule(nil, clientset, monday10pm.Add(time.Hour))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state).To(BeEmpty())
+			Expect(cluster.IsSleeping(clientset)).To(BeFalse())
+		})
+
+		It("should do nothing without schedule", func() {
+			cluster := mockCluster(0, 0, username)
+			err := cluster.Create(nil, clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			state, err := cluster.ApplySchedule(nil, clientset, monday10pm)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state).To(BeEmpty())
+		})
+	})
+
 	Describe("Apps", func() {
 		It("should return correct apps if cluster is running", func() {
 			cluster := mockCluster(0, 0, "user")
 			err := cluster.Create(nil, clientset)
 
-			domains, err := cluster.Apps(clientset, domain)
+			apps, err := cluster.Apps(config, clientset, domain)
 
 			Expect(err).NotTo(HaveOccurred())
-			Expect(domains["test1"]).To(Equal([]string{"test1.mystack-user.mystack.com"}))
-			Expect(domains["test2"]).To(Equal([]string{"test2.mystack-user.mystack.com"}))
-			Expect(domains["test3"]).To(Equal([]string{"test3.mystack-user.mystack.com"}))
+			Expect(apps.Sleeping).To(BeFalse())
+			Expect(apps.Domains["test1"]).To(Equal([]string{"test1.mystack-user-mycustomapps-a62c7ed6aa.mystack.com"}))
+			Expect(apps.Domains["test2"]).To(Equal([]string{"test2.mystack-user-mycustomapps-a62c7ed6aa.mystack.com"}))
+			Expect(apps.Domains["test3"]).To(Equal([]string{"test3.mystack-user-mycustomapps-a62c7ed6aa.mystack.com"}))
 		})
 
 		It("should return error if cluster is not runnig", func() {
 			cluster := mockCluster(0, 0, "user")
-			_, err := cluster.Apps(clientset, domain)
+			_, err := cluster.Apps(config, clientset, domain)
 			Expect(err).To(HaveOccurred())
 		})
 	})
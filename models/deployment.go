@@ -10,12 +10,16 @@ package models
 import (
 	"bytes"
 	"fmt"
+	"math"
+	"strconv"
 	"text/template"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/topfreegames/mystack-controller/errors"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/pkg/api"
+	k8serrors "k8s.io/client-go/pkg/api/errors"
 	"k8s.io/client-go/pkg/api/v1"
 	"k8s.io/client-go/pkg/apis/extensions"
 	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
@@ -31,7 +35,7 @@ metadata:
     mystack/routable: "true"
     app: {{.Name}}
 spec:
-  replicas: 1
+  replicas: {{.Replicas}}
   template:
     metadata:
       labels:
@@ -88,6 +92,12 @@ spec:
       {{end}}
 `
 
+//Annotations saved on the deployment and its pod template
+const (
+	restartedAtAnnotation = "mystack/restartedAt"
+	replicasAnnotation    = "mystack/replicas"
+)
+
 //Deployment represents a deployment
 type Deployment struct {
 	Name            string
@@ -102,11 +112,30 @@ type Deployment struct {
 	Volume          *VolumeMount
 	Links           []*Deployment
 	Resources       *Resources
+	Replicas        int
+	MaxReplicas     int
+}
+
+//defaultMaxReplicas is used when kubernetes.stacks.max-replicas is not set
+const defaultMaxReplicas = 10
+
+//maxReplicas returns the number of replicas a deployment can be scaled to,
+//never more than fits on the deployment spec
+func maxReplicas(config *viper.Viper) int {
+	max := defaultMaxReplicas
+	if config != nil && config.IsSet("kubernetes.stacks.max-replicas") {
+		max = config.GetInt("kubernetes.stacks.max-replicas")
+	}
+	if max > math.MaxInt32 {
+		max = math.MaxInt32
+	}
+
+	return max
 }
 
 //NewDeployment is the deployment ctor
 func NewDeployment(
-	name, username, image string,
+	name, username, namespace, image string,
 	ports []int,
 	environment []*EnvVar,
 	readinessProbe *Probe,
@@ -115,8 +144,6 @@ func NewDeployment(
 	resources *Resources,
 	config *viper.Viper,
 ) *Deployment {
-	namespace := usernameToNamespace(username)
-
 	resources = addDefaultValuesIfNecessary(resources, config)
 
 	return &Deployment{
@@ -131,6 +158,8 @@ func NewDeployment(
 		Links:          []*Deployment{},
 		Command:        command,
 		Resources:      resources,
+		Replicas:       1,
+		MaxReplicas:    maxReplicas(config),
 	}
 }
 
@@ -181,6 +210,89 @@ func (d *Deployment) Deploy(clientset kubernetes.Interface) (*v1beta1.Deployment
 		return nil, errors.NewKubernetesError("create namespace error", err)
 	}
 
+	dst, err := d.build()
+	if err != nil {
+		return nil, err
+	}
+
+	deployment, err := clientset.ExtensionsV1beta1().Deployments(d.Namespace).Create(dst)
+	if err != nil {
+		return nil, errors.NewKubernetesError("create deployment error", err)
+	}
+
+	return deployment, nil
+}
+
+//Apply creates the deployment if it doesn't exist or updates it
+//if the running one was built from a different config
+//The replicas of the running one are kept, so scaled and sleeping deployments stay as they are
+func (d *Deployment) Apply(clientset kubernetes.Interface) (string, error) {
+	existing, err := clientset.ExtensionsV1beta1().Deployments(d.Namespace).Get(d.Name)
+	if k8serrors.IsNotFound(err) {
+		_, err = d.Deploy(clientset)
+		if err != nil {
+			return "", err
+		}
+		return ChangeCreated, nil
+	}
+	if err != nil {
+		return "", errors.NewKubernetesError("update deployment error", err)
+	}
+
+	dst, err := d.build()
+	if err != nil {
+		return "", err
+	}
+
+	if configHash(existing.ObjectMeta) == configHash(dst.ObjectMeta) {
+		return ChangeUnchanged, nil
+	}
+
+	dst.ResourceVersion = existing.ResourceVersion
+	dst.Spec.Replicas = existing.Spec.Replicas
+	if replicas, ok := existing.GetAnnotations()[replicasAnnotation]; ok {
+		if dst.Annotations == nil {
+			dst.Annotations = map[string]string{}
+		}
+		dst.Annotations[replicasAnnotation] = replicas
+	}
+
+	_, err = clientset.ExtensionsV1beta1().Deployments(d.Namespace).Update(dst)
+	if err != nil {
+		return "", errors.NewKubernetesError("update deployment error", err)
+	}
+
+	return ChangeUpdated, nil
+}
+
+//Ensure creates the deployment if it doesn't exist
+//An existing one is kept if it was built from the same config
+func (d *Deployment) Ensure(clientset kubernetes.Interface) (string, error) {
+	existing, err := clientset.ExtensionsV1beta1().Deployments(d.Namespace).Get(d.Name)
+	if k8serrors.IsNotFound(err) {
+		_, err = d.Deploy(clientset)
+		if err != nil {
+			return "", err
+		}
+		return ChangeCreated, nil
+	}
+	if err != nil {
+		return "", errors.NewKubernetesError("create deployment error", err)
+	}
+
+	dst, err := d.build()
+	if err != nil {
+		return "", err
+	}
+
+	if configHash(existing.ObjectMeta) != configHash(dst.ObjectMeta) {
+		return "", differentConfigError("deployment", d.Name)
+	}
+
+	return ChangeUnchanged, nil
+}
+
+func (d *Deployment) build() (*v1beta1.Deployment, error) {
 	tmpl, err := template.New("deploy").Parse(deployYaml)
 	if err != nil {
 		return nil, errors.NewYamlError("parse yaml error", err)
@@ -206,12 +318,9 @@ func (d *Deployment) Deploy(clientset kubernetes.Interface) (*v1beta1.Deployment
 		return nil, errors.NewYamlError("parse yaml error", err)
 	}
 
-	deployment, err := clientset.ExtensionsV1beta1().Deployments(d.Namespace).Create(dst)
-	if err != nil {
-		return nil, errors.NewKubernetesError("create deployment error", err)
-	}
+	setConfigHash(&dst.ObjectMeta, buf.Bytes())
 
-	return deployment, nil
+	return dst, nil
 }
 
 //Delete deletes deployment from cluster
@@ -225,3 +334,117 @@ func (d *Deployment) Delete(clientset kubernetes.Interface) error {
 
 	return nil
 }
+
+//Restart rolls all the pods of the running deployment
+func (d *Deployment) Restart(clientset kubernetes.Interface) (*v1beta1.Deployment, error) {
+	return d.change(clientset, func(deployment *v1beta1.Deployment) {
+		podTemplate := &deployment.Spec.Template
+		if podTemplate.Annotations == nil {
+			podTemplate.Annotations = map[string]string{}
+		}
+		podTemplate.Annotations[restartedAtAnnotation] = time.Now().UTC().Format(time.RFC3339Nano)
+	})
+}
+
+//SetImage changes the image of the running deployment
+func (d *Deployment) SetImage(clientset kubernetes.Interface, image string) (*v1beta1.Deployment, error) {
+	deployment, err := d.change(clientset, func(deployment *v1beta1.Deployment) {
+		containers := deployment.Spec.Template.Spec.Containers
+		for i := range containers {
+			if containers[i].Name == d.Name {
+				containers[i].Image = image
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d.Image = image
+	return deployment, nil
+}
+
+//Scale changes the number of replicas of the running deployment
+//It can't be scaled above MaxReplicas
+func (d *Deployment) Scale(clientset kubernetes.Interface, replicas int) (*v1beta1.Deployment, error) {
+	if replicas < 0 {
+		return nil, errors.NewGenericError(
+			"scale deployment error",
+			fmt.Errorf("invalid number of replicas: %d", replicas),
+		)
+	}
+	if replicas > d.MaxReplicas {
+		return nil, errors.NewGenericError(
+			"scale deployment error",
+			fmt.Errorf("invalid number of replicas: %d, the maximum is %d", replicas, d.MaxReplicas),
+		)
+	}
+
+	deployment, err := d.change(clientset, func(deployment *v1beta1.Deployment) {
+		desired := int32(replicas)
+		deployment.Spec.Replicas = &desired
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d.Replicas = replicas
+	return deployment, nil
+}
+
+//Sleep scales the running deployment to zero
+//The replicas it had are saved so Wake can restore them
+func (d *Deployment) Sleep(clientset kubernetes.Interface) (*v1beta1.Deployment, error) {
+	return d.change(clientset, func(deployment *v1beta1.Deployment) {
+		if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas == 0 {
+			return
+		}
+
+		if deployment.Annotations == nil {
+			deployment.Annotations = map[string]string{}
+		}
+		deployment.Annotations[replicasAnnotation] = strconv.Itoa(int(*deployment.Spec.Replicas))
+
+		zero := int32(0)
+		deployment.Spec.Replicas = &zero
+	})
+}
+
+//Wake scales the deployment back to the replicas it had before Sleep
+//Deployments that are not sleeping are kept as they are
+func (d *Deployment) Wake(clientset kubernetes.Interface) (*v1beta1.Deployment, error) {
+	return d.change(clientset, func(deployment *v1beta1.Deployment) {
+		value, ok := deployment.GetAnnotations()[replicasAnnotation]
+		if !ok {
+			return
+		}
+		delete(deployment.Annotations, replicasAnnotation)
+
+		replicas, err := strconv.Atoi(value)
+		if err != nil || replicas <= 0 {
+			replicas = d.Replicas
+		}
+
+		desired := int32(replicas)
+		deployment.Spec.Replicas = &desired
+	})
+}
+
+func (d *Deployment) change(
+	clientset kubernetes.Interface,
+	mutate func(*v1beta1.Deployment),
+) (*v1beta1.Deployment, error) {
+	deployment, err := clientset.ExtensionsV1beta1().Deployments(d.Namespace).Get(d.Name)
+	if err != nil {
+		return nil, errors.NewKubernetesError("update deployment error", err)
+	}
+
+	mutate(deployment)
+
+	deployment, err = clientset.ExtensionsV1beta1().Deployments(d.Namespace).Update(deployment)
+	if err != nil {
+		return nil, errors.NewKubernetesError("update deployment error", err)
+	}
+
+	return deployment, nil
+}
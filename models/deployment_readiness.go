@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"github.com/topfreegames/mystack-controller/errors"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
 	"time"
 )
 
@@ -49,28 +50,53 @@ func (dr *DeploymentReadiness) WaitForCompletion(clientset kubernetes.Interface,
 
 	for _, deploy := range deployments {
 		period, timeout := getDeployTimes(deploy.ReadinessProbe)
-		k8sDeploy, err := clientset.ExtensionsV1beta1().Deployments(deploy.Namespace).Get(deploy.Name)
+		err := pollDeployment(clientset, deploy.Namespace, deploy.Name, period, timeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//pollDeployment gets the deployment every period until it is rolled out or timeout is reached
+//It fails before the timeout if a pod of the deployment won't become ready by itself
+func pollDeployment(
+	clientset kubernetes.Interface,
+	namespace, name string,
+	period, timeout time.Duration,
+) error {
+	k8sDeploy, err := clientset.ExtensionsV1beta1().Deployments(namespace).Get(name)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	desiredNumberReplicas := *k8sDeploy.Spec.Replicas
+
+	for !rolledOut(k8sDeploy, desiredNumberReplicas) {
+		err = checkDeploymentPods(clientset, namespace, name)
 		if err != nil {
 			return err
 		}
 
-		start := time.Now()
-		desiredNumberReplicas := *k8sDeploy.Spec.Replicas
-
-		for desiredNumberReplicas > k8sDeploy.Status.AvailableReplicas {
-			time.Sleep(period)
-			k8sDeploy, err = clientset.ExtensionsV1beta1().Deployments(deploy.Namespace).Get(deploy.Name)
-			if err != nil {
-				return err
-			}
-			if time.Now().Sub(start) > timeout {
-				return errors.NewKubernetesError(
-					"wait for deployment completion error",
-					fmt.Errorf("wait for deployment completion error due to timeout"),
-				)
-			}
+		time.Sleep(period)
+		k8sDeploy, err = clientset.ExtensionsV1beta1().Deployments(namespace).Get(name)
+		if err != nil {
+			return err
+		}
+		if time.Now().Sub(start) > timeout {
+			return deploymentTimeoutError(clientset, namespace, name)
 		}
 	}
 
 	return nil
 }
+
+//rolledOut is true when the last change of the deployment is running on all its replicas
+func rolledOut(deploy *v1beta1.Deployment, desiredNumberReplicas int32) bool {
+	status := deploy.Status
+	return status.ObservedGeneration >= deploy.Generation &&
+		status.UpdatedReplicas >= desiredNumberReplicas &&
+		status.AvailableReplicas >= desiredNumberReplicas
+}
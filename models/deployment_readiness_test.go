@@ -30,12 +30,12 @@ var _ = Describe("DeploymentReadiness", func() {
 	})
 
 	It("should reach non default timeout", func() {
-		CreateNamespace(clientset, "user")
+		CreateNamespace(clientset, "user", "stack")
 		probe := &Probe{
 			PeriodSeconds:  1,
 			TimeoutSeconds: 1,
 		}
-		deploy := NewDeployment("app", "user", "image", nil, nil, probe, nil, nil, nil, config)
+		deploy := NewDeployment("app", "user", "mystack-user-stack-7f46a74a4d", "image", nil, nil, probe, nil, nil, nil, config)
 		_, err := deploy.Deploy(clientset)
 		Expect(err).NotTo(HaveOccurred())
 
@@ -53,7 +53,7 @@ var _ = Describe("DeploymentReadiness", func() {
 			TimeoutSeconds: 1,
 		}
 		deployments := []*Deployment{
-			NewDeployment("app", "user", "image", nil, nil, probe, nil, nil, nil, config),
+			NewDeployment("app", "user", "mystack-user-stack-7f46a74a4d", "image", nil, nil, probe, nil, nil, nil, config),
 		}
 
 		readiness := &DeploymentReadiness{}
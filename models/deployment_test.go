@@ -9,7 +9,10 @@
 package models_test
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"math"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	. "github.com/topfreegames/mystack-controller/models"
@@ -26,7 +29,8 @@ var _ = Describe("Deployment", func() {
 	var (
 		clientset   *fake.Clientset
 		name        = "test"
-		namespace   = "mystack-user"
+		namespace   = "mystack-user-stack-7f46a74a4d"
+		stack       = "stack"
 		username    = "user"
 		image       = "hello-world"
 		ports       = []int{5000, 5001, 5002}
@@ -43,17 +47,17 @@ var _ = Describe("Deployment", func() {
 
 	Describe("Deploy", func() {
 		It("should return error since namespace was not created", func() {
-			deployment := NewDeployment(name, username, image, ports, nil, nil, nil, nil, nil, config)
+			deployment := NewDeployment(name, username, namespace, image, ports, nil, nil, nil, nil, nil, config)
 			_, err := deployment.Deploy(clientset)
 			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(Equal("namespace mystack-user not found"))
+			Expect(err.Error()).To(Equal("namespace mystack-user-stack-7f46a74a4d not found"))
 		})
 
 		It("should create a deployment", func() {
-			err := CreateNamespace(clientset, username)
+			err := CreateNamespace(clientset, username, stack)
 			Expect(err).NotTo(HaveOccurred())
 
-			deployment := NewDeployment(name, username, image, ports, nil, nil, nil, nil, nil, config)
+			deployment := NewDeployment(name, username, namespace, image, ports, nil, nil, nil, nil, nil, config)
 			deploy, err := deployment.Deploy(clientset)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(deploy).NotTo(BeNil())
@@ -76,7 +80,7 @@ var _ = Describe("Deployment", func() {
 		})
 
 		It("should create deployment with environment variables", func() {
-			err := CreateNamespace(clientset, username)
+			err := CreateNamespace(clientset, username, stack)
 			Expect(err).NotTo(HaveOccurred())
 
 			environment := []*EnvVar{
@@ -86,7 +90,7 @@ var _ = Describe("Deployment", func() {
 				},
 			}
 
-			deployment := NewDeployment(name, username, image, ports, environment, nil, nil, nil, nil, config)
+			deployment := NewDeployment(name, username, namespace, image, ports, environment, nil, nil, nil, nil, config)
 			deploy, err := deployment.Deploy(clientset)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(deploy).NotTo(BeNil())
@@ -95,14 +99,14 @@ var _ = Describe("Deployment", func() {
 		})
 
 		It("should create deployment with readiness probe and default times", func() {
-			err := CreateNamespace(clientset, username)
+			err := CreateNamespace(clientset, username, stack)
 			Expect(err).NotTo(HaveOccurred())
 
 			probe := &Probe{
 				Command: []string{"echo", "ready"},
 			}
 
-			deployment := NewDeployment(name, username, image, ports, nil, probe, nil, nil, nil, config)
+			deployment := NewDeployment(name, username, namespace, image, ports, nil, probe, nil, nil, nil, config)
 			deploy, err := deployment.Deploy(clientset)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(deploy).NotTo(BeNil())
@@ -115,7 +119,7 @@ var _ = Describe("Deployment", func() {
 		})
 
 		It("should create deployment with readiness probe and times", func() {
-			err := CreateNamespace(clientset, username)
+			err := CreateNamespace(clientset, username, stack)
 			Expect(err).NotTo(HaveOccurred())
 
 			probe := &Probe{
@@ -124,7 +128,7 @@ var _ = Describe("Deployment", func() {
 				TimeoutSeconds: 180,
 			}
 
-			deployment := NewDeployment(name, username, image, ports, nil, probe, nil, nil, nil, config)
+			deployment := NewDeployment(name, username, namespace, image, ports, nil, probe, nil, nil, nil, config)
 			deploy, err := deployment.Deploy(clientset)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(deploy.Spec.Template.Spec.Containers[0].ReadinessProbe.Handler.Exec.Command).To(Equal(probe.Command))
@@ -136,10 +140,10 @@ var _ = Describe("Deployment", func() {
 		})
 
 		It("should create deployment with commands", func() {
-			err := CreateNamespace(clientset, username)
+			err := CreateNamespace(clientset, username, stack)
 			Expect(err).NotTo(HaveOccurred())
 
-			deployment := NewDeployment(name, username, image, ports, nil, nil, nil, []string{"cma1", "cmd2"}, nil, config)
+			deployment := NewDeployment(name, username, namespace, image, ports, nil, nil, nil, []string{"cma1", "cmd2"}, nil, config)
 			deploy, err := deployment.Deploy(clientset)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(deploy.Spec.Template.Spec.Containers[0].Command).To(BeEquivalentTo([]string{"cma1", "cmd2"}))
@@ -150,7 +154,7 @@ var _ = Describe("Deployment", func() {
 		})
 
 		It("should create deployment with volumes", func() {
-			err := CreateNamespace(clientset, username)
+			err := CreateNamespace(clientset, username, stack)
 			Expect(err).NotTo(HaveOccurred())
 
 			volume := &VolumeMount{
@@ -158,7 +162,7 @@ var _ = Describe("Deployment", func() {
 				MountPath: "/data",
 			}
 
-			deployment := NewDeployment(name, username, image, ports, nil, nil, volume, nil, nil, config)
+			deployment := NewDeployment(name, username, namespace, image, ports, nil, nil, volume, nil, nil, config)
 			deploy, err := deployment.Deploy(clientset)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(deploy).NotTo(BeNil())
@@ -171,10 +175,10 @@ var _ = Describe("Deployment", func() {
 		})
 
 		It("should return error if duplicate deployment", func() {
-			err := CreateNamespace(clientset, username)
+			err := CreateNamespace(clientset, username, stack)
 			Expect(err).NotTo(HaveOccurred())
 
-			deployment := NewDeployment(name, username, image, ports, nil, nil, nil, nil, nil, config)
+			deployment := NewDeployment(name, username, namespace, image, ports, nil, nil, nil, nil, nil, config)
 			_, err = deployment.Deploy(clientset)
 			Expect(err).NotTo(HaveOccurred())
 
@@ -185,14 +189,14 @@ var _ = Describe("Deployment", func() {
 		})
 
 		It("should not return error if create second deployment on same namespace", func() {
-			err := CreateNamespace(clientset, username)
+			err := CreateNamespace(clientset, username, stack)
 			Expect(err).NotTo(HaveOccurred())
 
-			deployment := NewDeployment(name, username, image, ports, nil, nil, nil, nil, nil, config)
+			deployment := NewDeployment(name, username, namespace, image, ports, nil, nil, nil, nil, nil, config)
 			_, err = deployment.Deploy(clientset)
 			Expect(err).NotTo(HaveOccurred())
 
-			deployment2 := NewDeployment("test2", username, "new-image", []int{5000}, nil, nil, nil, nil, nil, config)
+			deployment2 := NewDeployment("test2", username, namespace, "new-image", []int{5000}, nil, nil, nil, nil, nil, config)
 			_, err = deployment2.Deploy(clientset)
 			Expect(err).NotTo(HaveOccurred())
 
@@ -204,16 +208,16 @@ var _ = Describe("Deployment", func() {
 
 	Describe("Delete", func() {
 		It("should return error if deployment wasn't deployed", func() {
-			deploy := NewDeployment(name, username, image, ports, nil, nil, nil, nil, nil, config)
+			deploy := NewDeployment(name, username, namespace, image, ports, nil, nil, nil, nil, nil, config)
 			err := deploy.Delete(clientset)
 			Expect(err).To(HaveOccurred())
 		})
 
 		It("should delete deployment after deploy", func() {
-			err := CreateNamespace(clientset, username)
+			err := CreateNamespace(clientset, username, stack)
 			Expect(err).NotTo(HaveOccurred())
 
-			deploy := NewDeployment(name, username, image, ports, nil, nil, nil, nil, nil, config)
+			deploy := NewDeployment(name, username, namespace, image, ports, nil, nil, nil, nil, nil, config)
 			_, err = deploy.Deploy(clientset)
 			Expect(err).NotTo(HaveOccurred())
 
@@ -226,14 +230,14 @@ var _ = Describe("Deployment", func() {
 		})
 
 		It("should not delete all deployments", func() {
-			err := CreateNamespace(clientset, username)
+			err := CreateNamespace(clientset, username, stack)
 			Expect(err).NotTo(HaveOccurred())
 
-			deploy := NewDeployment(name, username, image, ports, nil, nil, nil, nil, nil, config)
+			deploy := NewDeployment(name, username, namespace, image, ports, nil, nil, nil, nil, nil, config)
 			_, err = deploy.Deploy(clientset)
 			Expect(err).NotTo(HaveOccurred())
 
-			deploy2 := NewDeployment("test2", username, image, ports, nil, nil, nil, nil, nil, config)
+			deploy2 := NewDeployment("test2", username, namespace, image, ports, nil, nil, nil, nil, nil, config)
 			_, err = deploy2.Deploy(clientset)
 			Expect(err).NotTo(HaveOccurred())
 
@@ -246,10 +250,10 @@ var _ = Describe("Deployment", func() {
 		})
 
 		It("should create deployments with default requests and limtis", func() {
-			err := CreateNamespace(clientset, username)
+			err := CreateNamespace(clientset, username, stack)
 			Expect(err).NotTo(HaveOccurred())
 
-			deploy := NewDeployment(name, username, image, ports, nil, nil, nil, nil, nil, config)
+			deploy := NewDeployment(name, username, namespace, image, ports, nil, nil, nil, nil, nil, config)
 			_, err = deploy.Deploy(clientset)
 			Expect(err).NotTo(HaveOccurred())
 
@@ -269,10 +273,10 @@ var _ = Describe("Deployment", func() {
 		})
 
 		It("should create deployments with informed requests and limtis", func() {
-			err := CreateNamespace(clientset, username)
+			err := CreateNamespace(clientset, username, stack)
 			Expect(err).NotTo(HaveOccurred())
 
-			deploy := NewDeployment(name, username, image, ports, nil, nil, nil, nil, &Resources{
+			deploy := NewDeployment(name, username, namespace, image, ports, nil, nil, nil, nil, &Resources{
 				Limits:   &MemoryAndCPUResource{CPU: "20m", Memory: "100Mi"},
 				Requests: &MemoryAndCPUResource{CPU: "10m", Memory: "10Mi"},
 			}, config)
@@ -295,10 +299,10 @@ var _ = Describe("Deployment", func() {
 		})
 
 		It("should create deployments with partial informed requests and limtis", func() {
-			err := CreateNamespace(clientset, username)
+			err := CreateNamespace(clientset, username, stack)
 			Expect(err).NotTo(HaveOccurred())
 
-			deploy := NewDeployment(name, username, image, ports, nil, nil, nil, nil, &Resources{
+			deploy := NewDeployment(name, username, namespace, image, ports, nil, nil, nil, nil, &Resources{
 				Limits:   &MemoryAndCPUResource{CPU: "20m"},
 				Requests: &MemoryAndCPUResource{Memory: "10Mi"},
 			}, config)
@@ -320,4 +324,164 @@ var _ = Describe("Deployment", func() {
 			Expect(k8sDeploy.Spec.Template.Spec.Containers[0].Resources.Requests["memory"]).To(Equal(requestMemory))
 		})
 	})
+
+	Describe("Restart", func() {
+		It("should change pod template to roll pods", func() {
+			err := CreateNamespace(clientset, username, stack)
+			Expect(err).NotTo(HaveOccurred())
+			deployment := NewDeployment(name, username, namespace, image, ports, nil, nil, nil, nil, nil, config)
+			_, err = deployment.Deploy(clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			deploy, err := deployment.Restart(clientset)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deploy.Spec.Template.Annotations).To(HaveKey("mystack/restartedAt"))
+		})
+
+		It("should return error if deployment wasn't deployed", func() {
+			deployment := NewDeployment(name, username, namespace, image, ports, nil, nil, nil, nil, nil, config)
+			_, err := deployment.Restart(clientset)
+			Expect(err).To(HaveOccurred())
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.KubernetesError"))
+		})
+	})
+
+	Describe("SetImage", func() {
+		It("should change container image", func() {
+			err := CreateNamespace(clientset, username, stack)
+			Expect(err).NotTo(HaveOccurred())
+			deployment := NewDeployment(name, username, namespace, image, ports, nil, nil, nil, nil, nil, config)
+			_, err = deployment.Deploy(clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = deployment.SetImage(clientset, "hello-world:branch")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deployment.Image).To(Equal("hello-world:branch"))
+
+			deploy, err := clientset.ExtensionsV1beta1().Deployments(namespace).Get(name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deploy.Spec.Template.Spec.Containers[0].Image).To(Equal("hello-world:branch"))
+		})
+	})
+
+	Describe("Scale", func() {
+		It("should change number of replicas", func() {
+			err := CreateNamespace(clientset, username, stack)
+			Expect(err).NotTo(HaveOccurred())
+			deployment := NewDeployment(name, username, namespace, image, ports, nil, nil, nil, nil, nil, config)
+			deploy, err := deployment.Deploy(clientset)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*deploy.Spec.Replicas).To(BeEquivalentTo(1))
+
+			deploy, err = deployment.Scale(clientset, 3)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*deploy.Spec.Replicas).To(BeEquivalentTo(3))
+			Expect(deployment.Replicas).To(Equal(3))
+		})
+
+		It("should return error with more than the max replicas", func() {
+			err := CreateNamespace(clientset, username, stack)
+			Expect(err).NotTo(HaveOccurred())
+			deployment := NewDeployment(name, username, namespace, image, ports, nil, nil, nil, nil, nil, config)
+			_, err = deployment.Deploy(clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = deployment.Scale(clientset, 11)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("invalid number of replicas: 11, the maximum is 10"))
+
+			deploy, err := clientset.ExtensionsV1beta1().Deployments(namespace).Get(name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*deploy.Spec.Replicas).To(BeEquivalentTo(1))
+		})
+
+		It("should return error with replicas that overflow the deployment spec", func() {
+			deployment := NewDeployment(name, username, namespace, image, ports, nil, nil, nil, nil, nil, config)
+			deployment.MaxReplicas = math.MaxInt32
+
+			_, err := deployment.Scale(clientset, math.MaxInt32+1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("invalid number of replicas: 2147483648, the maximum is 2147483647"))
+			Expect(deployment.Replicas).To(Equal(1))
+		})
+
+		It("should return error with negative replicas", func() {
+			deployment := NewDeployment(name, username, namespace, image, ports, nil, nil, nil, nil, nil, config)
+			_, err := deployment.Scale(clientset, -1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("invalid number of replicas: -1"))
+		})
+	})
+
+	Describe("Sleep", func() {
+		It("should scale to zero and wake with previous replicas", func() {
+			err := CreateNamespace(clientset, username, stack)
+			Expect(err).NotTo(HaveOccurred())
+			deployment := NewDeployment(name, username, namespace, image, ports, nil, nil, nil, nil, nil, config)
+			_, err = deployment.Deploy(clientset)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = deployment.Scale(clientset, 3)
+			Expect(err).NotTo(HaveOccurred())
+
+			deploy, err := deployment.Sleep(clientset)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*deploy.Spec.Replicas).To(BeEquivalentTo(0))
+
+			deploy, err = deployment.Wake(clientset)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*deploy.Spec.Replicas).To(BeEquivalentTo(3))
+			Expect(deploy.Annotations).NotTo(HaveKey("mystack/replicas"))
+		})
+
+		It("should keep replicas when waking a deployment that is not sleeping", func() {
+			err := CreateNamespace(clientset, username, stack)
+			Expect(err).NotTo(HaveOccurred())
+			deployment := NewDeployment(name, username, namespace, image, ports, nil, nil, nil, nil, nil, config)
+			_, err = deployment.Deploy(clientset)
+			Expect(err).NotTo(HaveOccurred())
+
+			deploy, err := deployment.Wake(clientset)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*deploy.Spec.Replicas).To(BeEquivalentTo(1))
+		})
+	})
+	Describe("WriteLogs", func() {
+		It("should not read logs of pending pods", func() {
+			deployment := NewDeployment(name, username, namespace, image, ports, nil, nil, nil, nil, nil, config)
+			_, err := clientset.CoreV1().Pods(namespace).Create(&v1.Pod{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      "test-1",
+					Namespace: namespace,
+					Labels:    map[string]string{"app": name},
+				},
+				Status: v1.PodStatus{Phase: v1.PodPending},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = deployment.WriteLogs(context.Background(), clientset, &LogOptions{Follow: true}, ioutil.Discard)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("pods of app 'test' not found"))
+		})
+
+		It("should stop following logs when the context is done", func() {
+			deployment := NewDeployment(name, username, namespace, image, ports, nil, nil, nil, nil, nil, config)
+			for _, podName := range []string{"test-1", "test-2"} {
+				_, err := clientset.CoreV1().Pods(namespace).Create(&v1.Pod{
+					ObjectMeta: v1.ObjectMeta{
+						Name:      podName,
+						Namespace: namespace,
+						Labels:    map[string]string{"app": name},
+					},
+					Status: v1.PodStatus{Phase: v1.PodRunning},
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := deployment.WriteLogs(ctx, clientset, &LogOptions{Follow: true}, ioutil.Discard)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
 })
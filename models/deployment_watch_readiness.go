@@ -0,0 +1,123 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"fmt"
+	"github.com/topfreegames/mystack-controller/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
+	"k8s.io/client-go/pkg/fields"
+	"k8s.io/client-go/pkg/watch"
+	"time"
+)
+
+//DeploymentWatchReadiness implements Readiness interface
+//watching the deployments instead of polling them.
+//If the watch breaks, it polls the deployment until the timeout.
+type DeploymentWatchReadiness struct{}
+
+//WaitForCompletion waits until deployments are rolled out
+func (dr *DeploymentWatchReadiness) WaitForCompletion(clientset kubernetes.Interface, d interface{}) error {
+	deployments, ok := d.([]*Deployment)
+	if !ok {
+		return errors.NewGenericError("wait for deployment completion error", fmt.Errorf("interface{} is not of type []*models.Deployment"))
+	}
+
+	for _, deploy := range deployments {
+		period, timeout := getDeployTimes(deploy.ReadinessProbe)
+		start := time.Now()
+
+		watched, err := watchDeployment(clientset, deploy.Namespace, deploy.Name, period, timeout)
+		if err != nil {
+			return err
+		}
+		if watched {
+			continue
+		}
+
+		remaining := timeout - time.Now().Sub(start)
+		if remaining <= 0 {
+			return deploymentTimeoutError(clientset, deploy.Namespace, deploy.Name)
+		}
+
+		err = pollDeployment(clientset, deploy.Namespace, deploy.Name, period, remaining)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//watchDeployment returns true when the deployment is rolled out
+//and false when the watch broke before that
+//Every period it checks if a pod of the deployment won't become ready by itself
+func watchDeployment(
+	clientset kubernetes.Interface,
+	namespace, name string,
+	period, timeout time.Duration,
+) (bool, error) {
+	k8sDeploy, err := clientset.ExtensionsV1beta1().Deployments(namespace).Get(name)
+	if err != nil {
+		return false, err
+	}
+
+	desiredNumberReplicas := *k8sDeploy.Spec.Replicas
+	if rolledOut(k8sDeploy, desiredNumberReplicas) {
+		return true, nil
+	}
+
+	watcher, err := clientset.ExtensionsV1beta1().Deployments(namespace).Watch(v1.ListOptions{
+		FieldSelector:   fields.OneTermEqualSelector("metadata.name", name).String(),
+		ResourceVersion: k8sDeploy.ResourceVersion,
+	})
+	if err != nil {
+		return false, nil
+	}
+	defer watcher.Stop()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-watcher.ResultChan():
+			if !ok || event.Type == watch.Error {
+				return false, nil
+			}
+
+			if event.Type == watch.Deleted {
+				return false, errors.NewKubernetesError(
+					"wait for deployment completion error",
+					fmt.Errorf("deployment %s was deleted", name),
+				)
+			}
+
+			k8sDeploy, ok := event.Object.(*v1beta1.Deployment)
+			if !ok {
+				continue
+			}
+
+			if rolledOut(k8sDeploy, desiredNumberReplicas) {
+				return true, nil
+			}
+		case <-ticker.C:
+			err := checkDeploymentPods(clientset, namespace, name)
+			if err != nil {
+				return false, err
+			}
+		case <-timer.C:
+			return false, deploymentTimeoutError(clientset, namespace, name)
+		}
+	}
+}
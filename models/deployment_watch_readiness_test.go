@@ -0,0 +1,114 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models_test
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/topfreegames/mystack-controller/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/pkg/apis/extensions/v1beta1"
+	"k8s.io/client-go/pkg/watch"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var _ = Describe("DeploymentWatchReadiness", func() {
+
+	var (
+		clientset *fake.Clientset
+		watcher   *watch.FakeWatcher
+		probe     *Probe
+		readiness *DeploymentWatchReadiness
+	)
+
+	rollOut := func(deploy *Deployment) *v1beta1.Deployment {
+		k8sDeploy, err := clientset.ExtensionsV1beta1().Deployments(deploy.Namespace).Get(deploy.Name)
+		Expect(err).NotTo(HaveOccurred())
+		k8sDeploy.Status.UpdatedReplicas = *k8sDeploy.Spec.Replicas
+		k8sDeploy.Status.AvailableReplicas = *k8sDeploy.Spec.Replicas
+		return k8sDeploy
+	}
+
+	BeforeEach(func() {
+		clientset = fake.NewSimpleClientset()
+		watcher = watch.NewFake()
+		clientset.PrependWatchReactor("deployments", k8stesting.DefaultWatchReactor(watcher, nil))
+		probe = &Probe{
+			PeriodSeconds:  1,
+			TimeoutSeconds: 2,
+		}
+		readiness = &DeploymentWatchReadiness{}
+		CreateNamespace(clientset, "user", "stack")
+	})
+
+	It("should return when watch reports deployment rolled out", func() {
+		deploy := NewDeployment("app", "user", "mystack-user-stack-7f46a74a4d", "image", nil, nil, probe, nil, nil, nil, config)
+		_, err := deploy.Deploy(clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		go watcher.Modify(rollOut(deploy))
+
+		err = readiness.WaitForCompletion(clientset, []*Deployment{deploy})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should reach non default timeout", func() {
+		probe.TimeoutSeconds = 1
+		deploy := NewDeployment("app", "user", "mystack-user-stack-7f46a74a4d", "image", nil, nil, probe, nil, nil, nil, config)
+		_, err := deploy.Deploy(clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = readiness.WaitForCompletion(clientset, []*Deployment{deploy})
+		Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.KubernetesError"))
+		Expect(err.Error()).To(Equal("wait for deployment completion error due to timeout"))
+	})
+
+	It("should fall back to polling if watch breaks", func() {
+		deploy := NewDeployment("app", "user", "mystack-user-stack-7f46a74a4d", "image", nil, nil, probe, nil, nil, nil, config)
+		_, err := deploy.Deploy(clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		watcher.Stop()
+		go func() {
+			defer GinkgoRecover()
+			time.Sleep(500 * time.Millisecond)
+			_, err := clientset.ExtensionsV1beta1().Deployments(deploy.Namespace).Update(rollOut(deploy))
+			Expect(err).NotTo(HaveOccurred())
+		}()
+
+		err = readiness.WaitForCompletion(clientset, []*Deployment{deploy})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should return error if deployment is deleted", func() {
+		deploy := NewDeployment("app", "user", "mystack-user-stack-7f46a74a4d", "image", nil, nil, probe, nil, nil, nil, config)
+		k8sDeploy, err := deploy.Deploy(clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		go watcher.Delete(k8sDeploy)
+
+		err = readiness.WaitForCompletion(clientset, []*Deployment{deploy})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("deployment app was deleted"))
+	})
+
+	It("should return error for non existing deployment", func() {
+		deployments := []*Deployment{
+			NewDeployment("app", "user", "mystack-user-stack-7f46a74a4d", "image", nil, nil, probe, nil, nil, nil, config),
+		}
+
+		err := readiness.WaitForCompletion(clientset, deployments)
+		Expect(err.Error()).To(Equal("Deployment.extensions \"app\" not found"))
+	})
+})
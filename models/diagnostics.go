@@ -0,0 +1,235 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"fmt"
+	"github.com/topfreegames/mystack-controller/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/pkg/fields"
+	"k8s.io/client-go/pkg/labels"
+	"strings"
+)
+
+const (
+	unschedulableReason = "Unschedulable"
+	diagnosticsLogLines = 20
+)
+
+//DeploymentDiagnostics reports why the pods of a deployment aren't ready
+type DeploymentDiagnostics struct {
+	Deployment string            `json:"deployment"`
+	Pods       []*PodDiagnostics `json:"pods"`
+}
+
+//JobDiagnostics reports why the pods of a job didn't succeed
+type JobDiagnostics struct {
+	Job  string            `json:"job"`
+	Pods []*PodDiagnostics `json:"pods"`
+}
+
+//PodDiagnostics has the events and the last log lines of a pod that isn't ready
+type PodDiagnostics struct {
+	Name    string              `json:"name"`
+	Reason  string              `json:"reason,omitempty"`
+	Message string              `json:"message,omitempty"`
+	Events  []string            `json:"events"`
+	Logs    map[string][]string `json:"logs"`
+}
+
+//podFailure returns why the pod won't become ready by itself
+//or an empty reason if it still may become ready
+func podFailure(pod *v1.Pod) (string, string) {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodScheduled &&
+			condition.Status == v1.ConditionFalse &&
+			condition.Reason == unschedulableReason {
+			return condition.Reason, condition.Message
+		}
+	}
+
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		waiting := containerStatus.State.Waiting
+		if waiting != nil && failingReasons[waiting.Reason] {
+			return waiting.Reason, waiting.Message
+		}
+	}
+
+	return "", ""
+}
+
+func podReady(pod *v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+//appPods returns the pods of the deployment or job called name
+func appPods(clientset kubernetes.Interface, namespace, name string) ([]v1.Pod, error) {
+	labelMap := labels.Set{"app": name}
+	listOptions := v1.ListOptions{
+		LabelSelector: labelMap.AsSelector().String(),
+		FieldSelector: fields.Everything().String(),
+	}
+
+	pods, err := clientset.CoreV1().Pods(namespace).List(listOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return pods.Items, nil
+}
+
+//checkDeploymentPods returns an error if a pod of the deployment
+//is in a state it won't recover from, like ImagePullBackOff
+func checkDeploymentPods(clientset kubernetes.Interface, namespace, name string) error {
+	pods, err := appPods(clientset, namespace, name)
+	if err != nil {
+		return nil
+	}
+
+	for _, pod := range pods {
+		reason, _ := podFailure(&pod)
+		if reason == "" {
+			continue
+		}
+
+		return errors.NewKubernetesErrorWithDetails(
+			"wait for deployment completion error",
+			fmt.Errorf("deployment %s failed: pod %s is %s", name, pod.Name, reason),
+			diagnoseDeployment(clientset, namespace, name, pods),
+		)
+	}
+
+	return nil
+}
+
+//deploymentTimeoutError is returned when the deployment isn't ready after its timeout
+func deploymentTimeoutError(clientset kubernetes.Interface, namespace, name string) error {
+	err := fmt.Errorf("wait for deployment completion error due to timeout")
+	pods, listErr := appPods(clientset, namespace, name)
+	if listErr != nil {
+		return errors.NewKubernetesError("wait for deployment completion error", err)
+	}
+
+	return errors.NewKubernetesErrorWithDetails(
+		"wait for deployment completion error",
+		err,
+		diagnoseDeployment(clientset, namespace, name, pods),
+	)
+}
+
+//diagnoseDeployment gets events and logs of the pods that aren't ready
+func diagnoseDeployment(
+	clientset kubernetes.Interface,
+	namespace, name string,
+	pods []v1.Pod,
+) *DeploymentDiagnostics {
+	diagnostics := &DeploymentDiagnostics{
+		Deployment: name,
+		Pods:       []*PodDiagnostics{},
+	}
+
+	for _, pod := range pods {
+		if podReady(&pod) {
+			continue
+		}
+
+		diagnostics.Pods = append(diagnostics.Pods, diagnosePod(clientset, &pod))
+	}
+
+	return diagnostics
+}
+
+//jobError returns err with the events and logs of the job pods that didn't succeed
+func jobError(clientset kubernetes.Interface, namespace, name string, err error) error {
+	pods, listErr := appPods(clientset, namespace, name)
+	if listErr != nil {
+		return errors.NewKubernetesError("setup error", err)
+	}
+
+	diagnostics := &JobDiagnostics{
+		Job:  name,
+		Pods: []*PodDiagnostics{},
+	}
+
+	for _, pod := range pods {
+		if pod.Status.Phase == v1.PodSucceeded {
+			continue
+		}
+
+		diagnostics.Pods = append(diagnostics.Pods, diagnosePod(clientset, &pod))
+	}
+
+	return errors.NewKubernetesErrorWithDetails("setup error", err, diagnostics)
+}
+
+func diagnosePod(clientset kubernetes.Interface, pod *v1.Pod) *PodDiagnostics {
+	reason, message := podFailure(pod)
+	return &PodDiagnostics{
+		Name:    pod.Name,
+		Reason:  reason,
+		Message: message,
+		Events:  podEvents(clientset, pod),
+		Logs:    podLogs(clientset, pod),
+	}
+}
+
+func podEvents(clientset kubernetes.Interface, pod *v1.Pod) []string {
+	fieldMap := fields.Set{
+		"involvedObject.kind": "Pod",
+		"involvedObject.name": pod.Name,
+	}
+	listOptions := v1.ListOptions{
+		FieldSelector: fieldMap.AsSelector().String(),
+	}
+
+	messages := []string{}
+	events, err := clientset.CoreV1().Events(pod.Namespace).List(listOptions)
+	if err != nil {
+		return messages
+	}
+
+	for _, event := range events.Items {
+		if event.InvolvedObject.Name != pod.Name {
+			continue
+		}
+
+		messages = append(messages, fmt.Sprintf("%s %s: %s", event.Type, event.Reason, event.Message))
+	}
+
+	return messages
+}
+
+//podLogs returns the last log lines of each container
+//Containers that restarted have the logs of the previous run, the one that crashed
+func podLogs(clientset kubernetes.Interface, pod *v1.Pod) map[string][]string {
+	logs := map[string][]string{}
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		tailLines := int64(diagnosticsLogLines)
+		options := &v1.PodLogOptions{
+			Container: containerStatus.Name,
+			TailLines: &tailLines,
+			Previous:  containerStatus.RestartCount > 0,
+		}
+
+		raw, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, options).Do().Raw()
+		if err != nil || len(raw) == 0 {
+			continue
+		}
+
+		logs[containerStatus.Name] = strings.Split(strings.TrimRight(string(raw), "\n"), "\n")
+	}
+
+	return logs
+}
@@ -0,0 +1,144 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models_test
+
+import (
+	"fmt"
+
+	"github.com/topfreegames/mystack-controller/errors"
+	. "github.com/topfreegames/mystack-controller/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+var _ = Describe("Diagnostics", func() {
+
+	var (
+		clientset *fake.Clientset
+		deploy    *Deployment
+		namespace = "mystack-user-stack-7f46a74a4d"
+	)
+
+	createPod := func(status v1.PodStatus) {
+		pod := &v1.Pod{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "app-1",
+				Namespace: namespace,
+				Labels:    map[string]string{"app": "app"},
+			},
+			Status: status,
+		}
+		_, err := clientset.CoreV1().Pods(namespace).Create(pod)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	createEvent := func(reason, message string) {
+		event := &v1.Event{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      fmt.Sprintf("app-1.%s", reason),
+				Namespace: namespace,
+			},
+			InvolvedObject: v1.ObjectReference{Kind: "Pod", Name: "app-1", Namespace: namespace},
+			Type:           "Warning",
+			Reason:         reason,
+			Message:        message,
+		}
+		_, err := clientset.CoreV1().Events(namespace).Create(event)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	waiting := func(reason string) v1.PodStatus {
+		return v1.PodStatus{
+			Phase: v1.PodPending,
+			ContainerStatuses: []v1.ContainerStatus{{
+				Name: "app",
+				State: v1.ContainerState{
+					Waiting: &v1.ContainerStateWaiting{Reason: reason, Message: "waiting " + reason},
+				},
+			}},
+		}
+	}
+
+	BeforeEach(func() {
+		clientset = fake.NewSimpleClientset()
+		CreateNamespace(clientset, "user", "stack")
+
+		probe := &Probe{PeriodSeconds: 1, TimeoutSeconds: 1}
+		deploy = NewDeployment("app", "user", namespace, "image", nil, nil, probe, nil, nil, nil, config)
+		_, err := deploy.Deploy(clientset)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	for _, reason := range []string{"ImagePullBackOff", "ErrImagePull", "CrashLoopBackOff"} {
+		reason := reason
+		It(fmt.Sprintf("should fail fast if pod is in %s", reason), func() {
+			createPod(waiting(reason))
+			createEvent("Failed", "Failed to pull image \"image\"")
+
+			err := (&DeploymentReadiness{}).WaitForCompletion(clientset, []*Deployment{deploy})
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.KubernetesError"))
+			Expect(err.Error()).To(Equal(fmt.Sprintf("deployment app failed: pod app-1 is %s", reason)))
+
+			diagnostics := err.(errors.DetailedError).Details().(*DeploymentDiagnostics)
+			Expect(diagnostics.Deployment).To(Equal("app"))
+			Expect(diagnostics.Pods).To(HaveLen(1))
+			Expect(diagnostics.Pods[0].Name).To(Equal("app-1"))
+			Expect(diagnostics.Pods[0].Reason).To(Equal(reason))
+			Expect(diagnostics.Pods[0].Message).To(Equal("waiting " + reason))
+			Expect(diagnostics.Pods[0].Events).To(ConsistOf("Warning Failed: Failed to pull image \"image\""))
+		})
+	}
+
+	It("should fail fast if pod is unschedulable", func() {
+		createPod(v1.PodStatus{
+			Phase: v1.PodPending,
+			Conditions: []v1.PodCondition{{
+				Type:    v1.PodScheduled,
+				Status:  v1.ConditionFalse,
+				Reason:  "Unschedulable",
+				Message: "0/1 nodes are available: 1 Insufficient cpu.",
+			}},
+		})
+
+		err := (&DeploymentReadiness{}).WaitForCompletion(clientset, []*Deployment{deploy})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("deployment app failed: pod app-1 is Unschedulable"))
+
+		diagnostics := err.(errors.DetailedError).Details().(*DeploymentDiagnostics)
+		Expect(diagnostics.Pods[0].Message).To(Equal("0/1 nodes are available: 1 Insufficient cpu."))
+	})
+
+	It("should report pods that aren't ready on timeout", func() {
+		createPod(waiting("ContainerCreating"))
+		createEvent("Pulling", "pulling image \"image\"")
+
+		err := (&DeploymentReadiness{}).WaitForCompletion(clientset, []*Deployment{deploy})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("wait for deployment completion error due to timeout"))
+
+		diagnostics := err.(errors.DetailedError).Details().(*DeploymentDiagnostics)
+		Expect(diagnostics.Pods).To(HaveLen(1))
+		Expect(diagnostics.Pods[0].Reason).To(BeEmpty())
+		Expect(diagnostics.Pods[0].Events).To(ConsistOf("Warning Pulling: pulling image \"image\""))
+	})
+
+	It("should serialize details on error body", func() {
+		createPod(waiting("ImagePullBackOff"))
+
+		err := (&DeploymentReadiness{}).WaitForCompletion(clientset, []*Deployment{deploy})
+		Expect(err).To(HaveOccurred())
+
+		body := string(err.(errors.SerializableError).Serialize())
+		Expect(body).To(ContainSubstring(`"details":{"deployment":"app","pods":[{"name":"app-1","reason":"ImagePullBackOff"`))
+	})
+})
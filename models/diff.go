@@ -0,0 +1,187 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/topfreegames/mystack-controller/errors"
+)
+
+const (
+	diffContextLines = 3
+	//maxDiffCells caps the table diffLines builds for the lines that differ,
+	//which grows with the product of their counts
+	maxDiffCells = 1000000
+)
+
+type diffOp struct {
+	kind byte
+	line string
+}
+
+//unifiedDiff returns the lines that changed from a to b in unified format
+//It is empty if a and b are equal
+func unifiedDiff(fromName, toName, a, b string) (string, error) {
+	ops, err := diffLines(splitLines(a), splitLines(b))
+	if err != nil {
+		return "", err
+	}
+
+	changes := []int{}
+	for i, op := range ops {
+		if op.kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return "", nil
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", fromName, toName)
+
+	for first := 0; first < len(changes); {
+		last := first
+		for last+1 < len(changes) && changes[last+1]-changes[last] <= 2*diffContextLines {
+			last++
+		}
+
+		start := changes[first] - diffContextLines
+		if start < 0 {
+			start = 0
+		}
+		end := changes[last] + diffContextLines + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		writeHunk(&buf, ops, start, end)
+		first = last + 1
+	}
+
+	return buf.String(), nil
+}
+
+func writeHunk(buf *bytes.Buffer, ops []diffOp, start, end int) {
+	aStart, bStart := 0, 0
+	for _, op := range ops[:start] {
+		if op.kind != '+' {
+			aStart++
+		}
+		if op.kind != '-' {
+			bStart++
+		}
+	}
+
+	aCount, bCount := 0, 0
+	for _, op := range ops[start:end] {
+		if op.kind != '+' {
+			aCount++
+		}
+		if op.kind != '-' {
+			bCount++
+		}
+	}
+
+	fmt.Fprintf(buf, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+	for _, op := range ops[start:end] {
+		fmt.Fprintf(buf, "%c%s\n", op.kind, op.line)
+	}
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+//diffLines returns the operations that turn a into b, keeping their longest common subsequence
+//The lines a and b start and end with are kept as they are, only the ones between
+//them are compared, as long as they fit in maxDiffCells
+func diffLines(a, b []string) ([]diffOp, error) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	changedA, changedB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(changedA)*len(changedB) > maxDiffCells {
+		return nil, errors.NewGenericError(
+			"diff error",
+			fmt.Errorf("too many changed lines to diff: %d lines against %d", len(changedA), len(changedB)),
+		)
+	}
+
+	ops := []diffOp{}
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, diffChangedLines(changedA, changedB)...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+
+	return ops, nil
+}
+
+func diffChangedLines(a, b []string) []diffOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ops := []diffOp{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		} else {
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+
+	return ops
+}
+
+func splitLines(str string) []string {
+	if len(str) == 0 {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(str, "\n"), "\n")
+}
@@ -0,0 +1,129 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/topfreegames/mystack-controller/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/rest"
+)
+
+//Channels of the exec streams
+//The first byte of every websocket message is the channel of its data
+const (
+	StdinChannel  byte = 0
+	StdoutChannel byte = 1
+	StderrChannel byte = 2
+	ErrorChannel  byte = 3
+	ResizeChannel byte = 4
+)
+
+//ExecProtocol is the Kubernetes websocket protocol that supports terminal resize
+const ExecProtocol = "v4.channel.k8s.io"
+
+//ExecOptions has the command to run on a container
+type ExecOptions struct {
+	Container string
+	Command   []string
+	TTY       bool
+}
+
+//Executor opens a websocket connected to the exec subresource of a pod
+type Executor interface {
+	Exec(namespace, pod string, options *ExecOptions) (*websocket.Conn, error)
+}
+
+//KubernetesExecutor runs commands on pods through the Kubernetes API server
+type KubernetesExecutor struct {
+	config *rest.Config
+}
+
+//NewKubernetesExecutor is the KubernetesExecutor ctor
+func NewKubernetesExecutor(config *rest.Config) *KubernetesExecutor {
+	return &KubernetesExecutor{config: config}
+}
+
+//Exec dials the API server exec endpoint of the pod
+func (k *KubernetesExecutor) Exec(namespace, pod string, options *ExecOptions) (*websocket.Conn, error) {
+	execURL, err := url.Parse(k.config.Host)
+	if err != nil {
+		return nil, errors.NewKubernetesError("exec error", err)
+	}
+
+	switch execURL.Scheme {
+	case "http":
+		execURL.Scheme = "ws"
+	default:
+		execURL.Scheme = "wss"
+	}
+	execURL.Path = fmt.Sprintf("%s/api/v1/namespaces/%s/pods/%s/exec", strings.TrimRight(execURL.Path, "/"), namespace, pod)
+
+	query := url.Values{}
+	query.Set("container", options.Container)
+	query.Set("stdin", "true")
+	query.Set("stdout", "true")
+	query.Set("stderr", strconv.FormatBool(!options.TTY))
+	query.Set("tty", strconv.FormatBool(options.TTY))
+	for _, arg := range options.Command {
+		query.Add("command", arg)
+	}
+	execURL.RawQuery = query.Encode()
+
+	tlsConfig, err := rest.TLSConfigFor(k.config)
+	if err != nil {
+		return nil, errors.NewKubernetesError("exec error", err)
+	}
+
+	header := http.Header{}
+	if len(k.config.BearerToken) > 0 {
+		header.Set("Authorization", fmt.Sprintf("Bearer %s", k.config.BearerToken))
+	} else if len(k.config.Username) > 0 {
+		credentials := fmt.Sprintf("%s:%s", k.config.Username, k.config.Password)
+		header.Set("Authorization", fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(credentials))))
+	}
+
+	dialer := &websocket.Dialer{
+		TLSClientConfig: tlsConfig,
+		Subprotocols:    []string{ExecProtocol},
+	}
+
+	conn, _, err := dialer.Dial(execURL.String(), header)
+	if err != nil {
+		return nil, errors.NewKubernetesError("exec error", err)
+	}
+
+	return conn, nil
+}
+
+//RunningPod returns the newest running pod of the app
+func (d *Deployment) RunningPod(clientset kubernetes.Interface) (*v1.Pod, error) {
+	pods, err := sortedPods(clientset, d.Namespace, d.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(pods) - 1; i >= 0; i-- {
+		if pods[i].Status.Phase == v1.PodRunning && pods[i].DeletionTimestamp == nil {
+			return &pods[i], nil
+		}
+	}
+
+	return nil, errors.NewKubernetesError(
+		"exec error",
+		fmt.Errorf("running pod of app '%s' not found", d.Name),
+	)
+}
@@ -0,0 +1,285 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/viper"
+	"github.com/topfreegames/mystack-controller/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+//extendedSections are the keys a cluster config inherits from the config it extends
+//Parameters are inherited too, so the parent can still reference its own
+var extendedSections = map[string]bool{
+	"services":   true,
+	"apps":       true,
+	"volumes":    true,
+	"setup":      true,
+	"postSetup":  true,
+	"parameters": true,
+}
+
+//configExtends returns the name of the config yamlStr extends, empty if none
+func configExtends(yamlStr string) string {
+	raw := yamlStr
+	tmpl, err := template.New("config").Parse(yamlStr)
+	if err == nil {
+		var buf bytes.Buffer
+		if tmpl.Execute(&buf, map[string]interface{}{}) == nil {
+			raw = buf.String()
+		}
+	}
+
+	section := struct {
+		Extends string `yaml:"extends"`
+	}{}
+	if yaml.Unmarshal([]byte(raw), &section) != nil {
+		return ""
+	}
+
+	return section.Extends
+}
+
+//namedConfig is a cluster config yaml saved on DB
+type namedConfig struct {
+	Name string `db:"name"`
+	Yaml string `db:"yaml"`
+}
+
+//extendingConfigs returns the configs that extend clusterName, directly or through
+//other configs, the ones closest to it first
+func extendingConfigs(ctx context.Context, db Queryer, clusterName string) ([]*namedConfig, error) {
+	configs := []*namedConfig{}
+	query := "SELECT name, yaml FROM clusters WHERE yaml LIKE '%extends%'"
+	err := db.SelectContext(ctx, &configs, query)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	extending := []*namedConfig{}
+	found := map[string]bool{clusterName: true}
+	parents := []string{clusterName}
+	for len(parents) > 0 {
+		children := []string{}
+		for _, config := range configs {
+			if !found[config.Name] && containsString(parents, configExtends(config.Yaml)) {
+				found[config.Name] = true
+				extending = append(extending, config)
+				children = append(children, config.Name)
+			}
+		}
+		parents = children
+	}
+
+	return extending, nil
+}
+
+//validateExtendingConfigs returns an error if any config extending clusterName has problems
+//It is called after clusterName changes, on the same transaction, so they are checked against the change
+func validateExtendingConfigs(ctx context.Context, tx Queryer, clusterName string, config *viper.Viper) error {
+	extending, err := extendingConfigs(ctx, tx, clusterName)
+	if err != nil {
+		return err
+	}
+
+	for _, child := range extending {
+		problems, err := ValidateClusterConfig(ctx, tx, child.Name, child.Yaml, config)
+		if err != nil {
+			return err
+		}
+		if len(problems) == 0 {
+			continue
+		}
+
+		messages := make([]string, len(problems))
+		for i, problem := range problems {
+			messages[i] = problem.String()
+		}
+		return errors.NewYamlErrorWithDetails(
+			"invalid cluster config",
+			fmt.Errorf(
+				"cluster config '%s' that extends '%s' would be invalid: %s",
+				child.Name, clusterName, strings.Join(messages, "; "),
+			),
+			problems,
+		)
+	}
+
+	return nil
+}
+
+func containsString(list []string, str string) bool {
+	for _, item := range list {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
+
+//resolveExtends returns yamlStr merged with the configs it extends, recursively
+//chain has the names of the configs already on the way, to detect cycles
+func resolveExtends(ctx context.Context, db Queryer, yamlStr string, chain []string) (string, error) {
+	parentName := configExtends(yamlStr)
+	if len(parentName) == 0 {
+		return yamlStr, nil
+	}
+
+	for _, name := range chain {
+		if name == parentName {
+			return "", errors.NewGenericError(
+				"extends error",
+				fmt.Errorf("cluster config inheritance cycle: %s -> %s", strings.Join(chain, " -> "), parentName),
+			)
+		}
+	}
+
+	parentYaml, err := selectClusterConfigYaml(ctx, db, parentName)
+	if err != nil && strings.Contains(err.Error(), "no rows in result set") {
+		return "", errors.NewGenericError(
+			"extends error",
+			fmt.Errorf("cluster config '%s' extended by '%s' not found", parentName, chain[len(chain)-1]),
+		)
+	} else if err != nil {
+		return "", err
+	}
+
+	parentYaml, err = resolveExtends(ctx, db, parentYaml, append(chain, parentName))
+	if err != nil {
+		return "", err
+	}
+
+	parent, err := yamlMapSlice(parentName, parentYaml)
+	if err != nil {
+		return "", err
+	}
+	child, err := yamlMapSlice(chain[len(chain)-1], yamlStr)
+	if err != nil {
+		return "", err
+	}
+
+	bts, err := yaml.Marshal(mergeConfigs(parent, child))
+	if err != nil {
+		return "", errors.NewYamlError("extends error", err)
+	}
+
+	return string(bts), nil
+}
+
+//yamlMapSlice parses a config keeping the order of its keys
+//Parameter references must be on yaml strings for the config to be merged
+func yamlMapSlice(name, yamlStr string) (yaml.MapSlice, error) {
+	config := yaml.MapSlice{}
+	err := yaml.Unmarshal([]byte(yamlStr), &config)
+	if err != nil {
+		return nil, errors.NewYamlError(
+			"extends error",
+			fmt.Errorf("cluster config '%s' can't be merged: %s", name, err.Error()),
+		)
+	}
+
+	return config, nil
+}
+
+//mergeConfigs returns the inherited sections of parent deep merged with child
+//Other keys come only from child
+func mergeConfigs(parent, child yaml.MapSlice) yaml.MapSlice {
+	merged := yaml.MapSlice{}
+	for _, item := range parent {
+		if extendedSections[fmt.Sprint(item.Key)] {
+			merged = append(merged, item)
+		}
+	}
+
+	for _, item := range child {
+		if fmt.Sprint(item.Key) == "extends" {
+			continue
+		}
+		merged = mergeItem(merged, item)
+	}
+
+	return merged
+}
+
+func mergeItem(items yaml.MapSlice, item yaml.MapItem) yaml.MapSlice {
+	for i := range items {
+		if items[i].Key == item.Key {
+			items[i].Value = mergeValues(items[i].Value, item.Value)
+			return items
+		}
+	}
+
+	return append(items, item)
+}
+
+//mergeValues merges maps key by key and lists of named items, like env and volumes, by name
+//Any other value of child replaces the one of parent
+func mergeValues(parent, child interface{}) interface{} {
+	if child == nil {
+		return parent
+	}
+
+	switch childValue := child.(type) {
+	case yaml.MapSlice:
+		parentValue, ok := parent.(yaml.MapSlice)
+		if !ok {
+			return child
+		}
+
+		merged := append(yaml.MapSlice{}, parentValue...)
+		for _, item := range childValue {
+			merged = mergeItem(merged, item)
+		}
+		return merged
+	case []interface{}:
+		parentValue, ok := parent.([]interface{})
+		if !ok || !namedItems(parentValue) || !namedItems(childValue) {
+			return child
+		}
+
+		merged := yaml.MapSlice{}
+		for _, item := range parentValue {
+			merged = append(merged, yaml.MapItem{Key: itemName(item), Value: item})
+		}
+		for _, item := range childValue {
+			merged = mergeItem(merged, yaml.MapItem{Key: itemName(item), Value: item})
+		}
+
+		list := make([]interface{}, len(merged))
+		for i, item := range merged {
+			list[i] = item.Value
+		}
+		return list
+	}
+
+	return child
+}
+
+func itemName(item interface{}) interface{} {
+	for _, field := range item.(yaml.MapSlice) {
+		if field.Key == "name" {
+			return field.Value
+		}
+	}
+	return nil
+}
+
+func namedItems(list []interface{}) bool {
+	for _, item := range list {
+		if _, ok := item.(yaml.MapSlice); !ok || itemName(item) == nil {
+			return false
+		}
+	}
+	return true
+}
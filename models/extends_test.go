@@ -0,0 +1,114 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models_test
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/topfreegames/mystack-controller/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var _ = Describe("Extends", func() {
+	const (
+		baseYaml = `
+ttl: 1h
+volumes:
+  - name: postgres-volume
+    storage: 1Gi
+setup:
+  image: setup-img
+services:
+  postgres:
+    image: postgres:1.0
+    env:
+      - name: POSTGRES_USER
+        value: user
+      - name: POSTGRES_DB
+        value: db
+apps:
+  app1:
+    image: app1
+`
+		variantYaml = `
+extends: base
+volumes:
+  - name: postgres-volume
+    storage: 2Gi
+services:
+  postgres:
+    env:
+      - name: POSTGRES_DB
+        value: variant
+apps:
+  app2:
+    image: app2
+`
+	)
+
+	expectConfig := func(name, yamlStr string) {
+		mock.
+			ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+			WithArgs(name).
+			WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(yamlStr))
+	}
+
+	It("should deep merge config with the config it extends", func() {
+		expectConfig("variant", variantYaml)
+		expectConfig("base", baseYaml)
+
+		clusterConfig, err := LoadClusterConfig(context.Background(), sqlxDB, "variant")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(clusterConfig.TTL).To(BeEmpty())
+		Expect(clusterConfig.Setup.Image).To(Equal("setup-img"))
+		Expect(clusterConfig.Volumes).To(HaveLen(1))
+		Expect(clusterConfig.Volumes[0].Storage).To(Equal("2Gi"))
+		Expect(clusterConfig.Apps).To(HaveKey("app1"))
+		Expect(clusterConfig.Apps).To(HaveKey("app2"))
+
+		postgres := clusterConfig.Services["postgres"]
+		Expect(postgres.Image).To(Equal("postgres:1.0"))
+		Expect(postgres.Environment).To(Equal([]*EnvVar{
+			{Name: "POSTGRES_USER", Value: "user"},
+			{Name: "POSTGRES_DB", Value: "variant"},
+		}))
+	})
+
+	It("should return error if configs extend each other", func() {
+		expectConfig("variant", variantYaml)
+		expectConfig("base", "extends: variant\n")
+
+		_, err := LoadClusterConfig(context.Background(), sqlxDB, "variant")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("cluster config inheritance cycle: variant -> base -> variant"))
+		Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.GenericError"))
+	})
+
+	It("should return error if extended config doesn't exist", func() {
+		expectConfig("variant", variantYaml)
+		mock.
+			ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+			WithArgs("base").
+			WillReturnError(fmt.Errorf("sql: no rows in result set"))
+
+		_, err := LoadClusterConfig(context.Background(), sqlxDB, "variant")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("cluster config 'base' extended by 'variant' not found"))
+	})
+
+	It("should not write config that extends itself", func() {
+		err := WriteClusterConfig(context.Background(), sqlxDB, "variant", "extends: variant\n", "user@example.com", config)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("cluster config inheritance cycle: variant -> variant"))
+	})
+})
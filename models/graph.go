@@ -0,0 +1,166 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/topfreegames/mystack-controller/errors"
+)
+
+//ValidateGraph checks the links between apps and services
+//Apps can link to apps and services, services only to services,
+//names must be unique and links can't form a cycle
+func (c *ClusterConfig) ValidateGraph() error {
+	problems := []string{}
+
+	for _, kind := range []struct {
+		name    string
+		configs map[string]*ClusterAppConfig
+	}{{"service", c.Services}, {"app", c.Apps}} {
+		for _, name := range sortedNames(kind.configs) {
+			if kind.configs[name] == nil {
+				problems = append(problems, fmt.Sprintf("%s '%s' has no config", kind.name, name))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return errors.NewYamlError("invalid links", fmt.Errorf("%s", strings.Join(problems, "; ")))
+	}
+
+	for _, name := range sortedNames(c.Apps) {
+		if _, ok := c.Services[name]; ok {
+			problems = append(problems, fmt.Sprintf("'%s' is defined both as app and service", name))
+		}
+	}
+
+	volumes := make(map[string]bool)
+	for _, volume := range c.Volumes {
+		if volumes[volume.Name] {
+			problems = append(problems, fmt.Sprintf("volume '%s' is defined more than once", volume.Name))
+		}
+		volumes[volume.Name] = true
+	}
+
+	for _, kind := range []struct {
+		name    string
+		configs map[string]*ClusterAppConfig
+	}{{"app", c.Apps}, {"service", c.Services}} {
+		for _, name := range sortedNames(kind.configs) {
+			for _, link := range kind.configs[name].Links {
+				switch c.checkLink(kind.name == "service", link) {
+				case linkToApp:
+					problems = append(problems, fmt.Sprintf("%s '%s' can't link to app '%s'", kind.name, name, link))
+				case linkUnknown:
+					problems = append(problems, fmt.Sprintf("%s '%s' links to unknown '%s'", kind.name, name, link))
+				}
+			}
+		}
+	}
+
+	for _, kind := range []struct {
+		name    string
+		configs map[string]*ClusterAppConfig
+	}{{"services", c.Services}, {"apps", c.Apps}} {
+		for _, cycle := range findCycles(kind.configs) {
+			problems = append(problems, fmt.Sprintf("%s links form a cycle: %s", kind.name, strings.Join(cycle, " -> ")))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.NewYamlError("invalid links", fmt.Errorf("%s", strings.Join(problems, "; ")))
+	}
+
+	return nil
+}
+
+//Problems of a single link, returned by checkLink
+const (
+	linkValid = iota
+	linkUnknown
+	linkToApp
+)
+
+//checkLink returns the problem of a link of an app, or of a service if fromService is true
+//Apps can link to apps and services, services only to services
+func (c *ClusterConfig) checkLink(fromService bool, link string) int {
+	_, isApp := c.Apps[link]
+	_, isService := c.Services[link]
+	if isService {
+		return linkValid
+	}
+	if isApp && fromService {
+		return linkToApp
+	}
+	if !isApp {
+		return linkUnknown
+	}
+
+	return linkValid
+}
+
+//findCycles returns the cycles formed by links between configs
+//Links to names outside configs are ignored
+func findCycles(configs map[string]*ClusterAppConfig) [][]string {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	cycles := [][]string{}
+	state := make(map[string]int)
+	path := []string{}
+
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = visiting
+		path = append(path, name)
+
+		for _, link := range configs[name].Links {
+			if _, ok := configs[link]; !ok {
+				continue
+			}
+
+			switch state[link] {
+			case visiting:
+				for i := range path {
+					if path[i] == link {
+						cycle := append([]string{}, path[i:]...)
+						cycles = append(cycles, append(cycle, link))
+						break
+					}
+				}
+			case 0:
+				visit(link)
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = visited
+	}
+
+	for _, name := range sortedNames(configs) {
+		if state[name] == 0 {
+			visit(name)
+		}
+	}
+
+	return cycles
+}
+
+func sortedNames(configs map[string]*ClusterAppConfig) []string {
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
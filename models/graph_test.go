@@ -0,0 +1,195 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models_test
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/topfreegames/mystack-controller/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var _ = Describe("Graph", func() {
+	validate := func(yamlStr string) error {
+		clusterConfig, err := ParseYaml(yamlStr)
+		Expect(err).NotTo(HaveOccurred())
+		return clusterConfig.ValidateGraph()
+	}
+
+	Describe("ValidateGraph", func() {
+		It("should accept apps linking to apps and services", func() {
+			err := validate(`
+services:
+  postgres:
+    image: postgres
+  redis:
+    image: redis
+    links:
+      - postgres
+apps:
+  api:
+    image: api
+    links:
+      - postgres
+      - worker
+  worker:
+    image: worker
+    links:
+      - redis
+`)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should reject cycles", func() {
+			err := validate(`
+apps:
+  app1:
+    image: app1
+    links:
+      - app2
+  app2:
+    image: app2
+    links:
+      - app3
+  app3:
+    image: app3
+    links:
+      - app1
+`)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("apps links form a cycle: app1 -> app2 -> app3 -> app1"))
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.YamlError"))
+		})
+
+		It("should reject app linking to itself", func() {
+			err := validate(`
+apps:
+  app1:
+    image: app1
+    links:
+      - app1
+`)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("apps links form a cycle: app1 -> app1"))
+		})
+
+		It("should reject unknown links", func() {
+			err := validate(`
+services:
+  postgres:
+    image: postgres
+    links:
+      - redis
+apps:
+  app1:
+    image: app1
+    links:
+      - postgre
+`)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("app 'app1' links to unknown 'postgre'; service 'postgres' links to unknown 'redis'"))
+		})
+
+		It("should reject services linking to apps", func() {
+			err := validate(`
+services:
+  postgres:
+    image: postgres
+    links:
+      - app1
+apps:
+  app1:
+    image: app1
+`)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("service 'postgres' can't link to app 'app1'"))
+		})
+
+		It("should reject duplicate names", func() {
+			err := validate(`
+volumes:
+  - name: data
+    storage: 1Gi
+  - name: data
+    storage: 2Gi
+services:
+  app1:
+    image: svc
+apps:
+  app1:
+    image: app1
+`)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("'app1' is defined both as app and service; volume 'data' is defined more than once"))
+		})
+	})
+
+	Describe("WriteClusterConfig", func() {
+		It("should not write config with cycles", func() {
+			err := WriteClusterConfig(context.Background(), sqlxDB, "cycle", `
+services:
+  svc1:
+    image: svc1
+    links:
+      - svc2
+  svc2:
+    image: svc2
+    links:
+      - svc1
+`, "user@example.com", config)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("services links form a cycle: svc1 -> svc2 -> svc1"))
+		})
+	})
+
+	Describe("NewCluster", func() {
+		It("should return error instead of hanging on unknown links", func() {
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs("broken").
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(`
+apps:
+  app1:
+    image: app1
+    links:
+      - app2
+`))
+
+			_, err := NewCluster(sqlxDB, "user", "broken", nil, nil, config)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("app 'app1' links to unknown 'app2'"))
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.YamlError"))
+		})
+
+		It("should build apps linking to services", func() {
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs("linked").
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(`
+services:
+  postgres:
+    image: postgres
+apps:
+  app1:
+    image: app1
+    links:
+      - postgres
+`))
+
+			cluster, err := NewCluster(sqlxDB, "user", "linked", nil, nil, config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cluster.AppDeployments).To(HaveLen(1))
+			Expect(cluster.AppDeployments[0].Links).To(BeEmpty())
+		})
+	})
+})
@@ -8,13 +8,25 @@
 package models
 
 import (
+	"context"
+	"crypto/sha1"
 	"database/sql"
 	"fmt"
 	"github.com/cenkalti/backoff"
 	"github.com/jmoiron/sqlx"
+	"github.com/topfreegames/mystack-controller/errors"
+	"regexp"
+	"strings"
 	"time"
 )
 
+const maxNamespaceLength = 63
+
+//namespaceHashLength is the length of the hash that makes cluster namespaces unique
+const namespaceHashLength = 10
+
+var invalidNamespaceChars = regexp.MustCompile("[^a-z0-9-]+")
+
 //GetDB Connection using the given properties
 func GetDB(
 	host string, user string, port int, sslmode string,
@@ -46,6 +58,43 @@ func GetDB(
 	return db, nil
 }
 
+//sqlxDB implements DB over a sqlx connection
+type sqlxDB struct {
+	*sqlx.DB
+}
+
+//NewDB returns a DB that runs queries and transactions on db
+func NewDB(db *sqlx.DB) DB {
+	return &sqlxDB{db}
+}
+
+//BeginTransaction begins a transaction on the connection
+func (d *sqlxDB) BeginTransaction(ctx context.Context) (Tx, error) {
+	return d.BeginTxx(ctx, nil)
+}
+
+//WithTransaction runs fn inside a transaction of db
+//The transaction is committed if fn succeeds and rolled back otherwise
+func WithTransaction(ctx context.Context, db DB, fn func(tx Queryer) error) error {
+	tx, err := db.BeginTransaction(ctx)
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+
+	err = fn(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
 //ShouldPing the database
 func shouldPing(db *sql.DB, timeout time.Duration) error {
 	var err error
@@ -67,6 +116,18 @@ func shouldPing(db *sql.DB, timeout time.Duration) error {
 	return fmt.Errorf("could not ping database")
 }
 
-func usernameToNamespace(username string) string {
-	return fmt.Sprintf("mystack-%s", username)
+//ClusterNamespace returns the namespace of the user stack created from clusterName
+//The readable prefix is suffixed with a hash of username and clusterName as they are,
+//so users and clusters whose names read the same never share a namespace
+//Prefixes that don't fit on a k8s namespace are truncated
+func ClusterNamespace(username, clusterName string) string {
+	hash := fmt.Sprintf("%x", sha1.Sum([]byte(username+"\x00"+clusterName)))[:namespaceHashLength]
+
+	prefix := fmt.Sprintf("mystack-%s-%s", username, clusterName)
+	prefix = invalidNamespaceChars.ReplaceAllString(strings.ToLower(prefix), "-")
+	if len(prefix) > maxNamespaceLength-len(hash)-1 {
+		prefix = prefix[:maxNamespaceLength-len(hash)-1]
+	}
+
+	return fmt.Sprintf("%s-%s", strings.Trim(prefix, "-"), hash)
 }
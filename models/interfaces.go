@@ -8,7 +8,9 @@
 package models
 
 import (
+	"context"
 	"database/sql"
+
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -25,11 +27,27 @@ type Credentials interface {
 	GetSecret() string
 }
 
-//DB is the mystack-controller db interface
-type DB interface {
+//Queryer runs queries on the database or inside a transaction
+type Queryer interface {
 	NamedExec(query string, arg interface{}) (sql.Result, error)
 	Get(dest interface{}, query string, args ...interface{}) error
 	Select(dest interface{}, query string, args ...interface{}) error
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+//Tx is a transaction begun on DB
+type Tx interface {
+	Queryer
+	Commit() error
+	Rollback() error
+}
+
+//DB is the mystack-controller db interface
+type DB interface {
+	Queryer
+	BeginTransaction(ctx context.Context) (Tx, error)
 }
 
 //Readiness is the interface that tell how much time to wait until
@@ -38,3 +56,9 @@ type DB interface {
 type Readiness interface {
 	WaitForCompletion(kubernetes.Interface, interface{}) error
 }
+
+//Tracker is notified when each step of a cluster pipeline starts and finishes
+type Tracker interface {
+	StartStep(step string) error
+	FinishStep(step string, err error) error
+}
@@ -15,6 +15,8 @@ import (
 
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/pkg/api"
+	k8serrors "k8s.io/client-go/pkg/api/errors"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
 	"k8s.io/client-go/pkg/apis/batch"
 	"k8s.io/client-go/pkg/apis/batch/v1"
 )
@@ -33,6 +35,10 @@ spec:
   template:
     metadata:
       name: {{.Name}}
+      labels:
+        app: {{.Name}}
+        mystack/owner: {{.Username}}
+        heritage: mystack
     spec:
       containers:
       - name: {{.Name}}
@@ -63,7 +69,7 @@ type Job struct {
 }
 
 //NewJob is the job ctor
-func NewJob(name, username string, setup *Setup, environment []*EnvVar) *Job {
+func NewJob(name, username, namespace string, setup *Setup, environment []*EnvVar) *Job {
 	if setup == nil || len(setup.Image) == 0 {
 		return nil
 	}
@@ -74,7 +80,6 @@ func NewJob(name, username string, setup *Setup, environment []*EnvVar) *Job {
 	for _, environmentEnv := range environment {
 		env = append(env, environmentEnv)
 	}
-	namespace := usernameToNamespace(username)
 	return &Job{
 		Name:        name,
 		Namespace:   namespace,
@@ -95,6 +100,51 @@ func (j *Job) Run(clientset kubernetes.Interface) (*v1.Job, error) {
 		return nil, errors.NewKubernetesError("create namespace error", err)
 	}
 
+	dst, err := j.build()
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := clientset.BatchV1().Jobs(j.Namespace).Create(dst)
+	if err != nil {
+		return nil, errors.NewKubernetesError("create job error", err)
+	}
+
+	return job, nil
+}
+
+//Ensure runs the job if it doesn't exist
+//An existing one is kept if it was built from the same config
+func (j *Job) Ensure(clientset kubernetes.Interface) (string, error) {
+	if j == nil {
+		return ChangeUnchanged, nil
+	}
+
+	existing, err := clientset.BatchV1().Jobs(j.Namespace).Get(j.Name)
+	if k8serrors.IsNotFound(err) {
+		_, err = j.Run(clientset)
+		if err != nil {
+			return "", err
+		}
+		return ChangeCreated, nil
+	}
+	if err != nil {
+		return "", errors.NewKubernetesError("create job error", err)
+	}
+
+	dst, err := j.build()
+	if err != nil {
+		return "", err
+	}
+
+	if configHash(existing.ObjectMeta) != configHash(dst.ObjectMeta) {
+		return "", differentConfigError("job", j.Name)
+	}
+
+	return ChangeUnchanged, nil
+}
+
+func (j *Job) build() (*v1.Job, error) {
 	tmpl, err := template.New("job").Parse(jobYaml)
 	if err != nil {
 		return nil, errors.NewYamlError("parse yaml error", err)
@@ -120,10 +170,23 @@ func (j *Job) Run(clientset kubernetes.Interface) (*v1.Job, error) {
 		return nil, errors.NewYamlError("parse yaml error", err)
 	}
 
-	job, err := clientset.BatchV1().Jobs(j.Namespace).Create(dst)
+	setConfigHash(&dst.ObjectMeta, buf.Bytes())
+
+	return dst, nil
+}
+
+//Delete deletes the job and its pods
+func (j *Job) Delete(clientset kubernetes.Interface) error {
+	if j == nil {
+		return nil
+	}
+
+	orphanDependents := false
+	deleteOptions := &apiv1.DeleteOptions{OrphanDependents: &orphanDependents}
+	err := clientset.BatchV1().Jobs(j.Namespace).Delete(j.Name, deleteOptions)
 	if err != nil {
-		return nil, errors.NewKubernetesError("create job error", err)
+		return errors.NewKubernetesError("delete job error", err)
 	}
 
-	return job, nil
+	return nil
 }
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"github.com/topfreegames/mystack-controller/errors"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/apis/batch/v1"
 	"time"
 )
 
@@ -54,29 +55,54 @@ func (jr *JobReadiness) WaitForCompletion(
 		return nil
 	}
 
-	k8sJob, err := clientset.BatchV1().Jobs(job.Namespace).Get(job.Name)
+	period, timeout := getJobTimes(job.Setup)
+	return pollJob(clientset, job.Namespace, job.Name, period, timeout)
+}
+
+//pollJob gets the job every period until it succeeds, fails or timeout is reached
+func pollJob(
+	clientset kubernetes.Interface,
+	namespace, name string,
+	period, timeout time.Duration,
+) error {
+	k8sJob, err := clientset.BatchV1().Jobs(namespace).Get(name)
 	if err != nil {
 		return errors.NewKubernetesError("setup error", err)
 	}
 
-	period, timeout := getJobTimes(job.Setup)
 	start := time.Now()
 
-	for k8sJob.Status.Succeeded == 0 {
-		if k8sJob.Status.Failed == 1 {
-			return errors.NewKubernetesError("setup error", fmt.Errorf("failed to run stup job"))
+	for {
+		finished, err := jobFinished(clientset, k8sJob)
+		if finished || err != nil {
+			return err
 		}
 
 		time.Sleep(period)
 		if time.Now().Sub(start) > timeout {
-			return errors.NewKubernetesError("setup error", fmt.Errorf("failed to run stup job due to timeout"))
+			return jobTimeoutError(clientset, namespace, name)
 		}
 
-		k8sJob, err = clientset.BatchV1().Jobs(job.Namespace).Get(job.Name)
+		k8sJob, err = clientset.BatchV1().Jobs(namespace).Get(name)
 		if err != nil {
 			return errors.NewKubernetesError("setup error", err)
 		}
 	}
+}
+
+//jobFinished is true when the job succeeded and returns an error when it failed
+func jobFinished(clientset kubernetes.Interface, k8sJob *v1.Job) (bool, error) {
+	if k8sJob.Status.Succeeded > 0 {
+		return true, nil
+	}
+
+	if k8sJob.Status.Failed == 1 {
+		return false, jobError(clientset, k8sJob.Namespace, k8sJob.Name, fmt.Errorf("failed to run stup job"))
+	}
+
+	return false, nil
+}
 
-	return nil
+func jobTimeoutError(clientset kubernetes.Interface, namespace, name string) error {
+	return jobError(clientset, namespace, name, fmt.Errorf("failed to run stup job due to timeout"))
 }
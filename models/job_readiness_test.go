@@ -11,8 +11,10 @@ package models_test
 import (
 	"fmt"
 
+	"github.com/topfreegames/mystack-controller/errors"
 	. "github.com/topfreegames/mystack-controller/models"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/pkg/api/v1"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -30,13 +32,13 @@ var _ = Describe("JobReadiness", func() {
 	})
 
 	It("should reach non default timeout", func() {
-		CreateNamespace(clientset, "user")
+		CreateNamespace(clientset, "user", "stack")
 		setup := &Setup{
 			Image:          "image",
 			PeriodSeconds:  1,
 			TimeoutSeconds: 2,
 		}
-		job := NewJob("user", setup, nil)
+		job := NewJob("setup", "user", "mystack-user-stack-7f46a74a4d", setup, nil)
 		_, err := job.Run(clientset)
 		Expect(err).NotTo(HaveOccurred())
 
@@ -46,13 +48,51 @@ var _ = Describe("JobReadiness", func() {
 		Expect(err.Error()).To(Equal("failed to run stup job due to timeout"))
 	})
 
+	It("should report the pods of a failed job", func() {
+		CreateNamespace(clientset, "user", "stack")
+		setup := &Setup{
+			Image:          "image",
+			PeriodSeconds:  1,
+			TimeoutSeconds: 2,
+		}
+		job := NewJob("setup", "user", "mystack-user-stack-7f46a74a4d", setup, nil)
+		k8sJob, err := job.Run(clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		k8sJob.Status.Failed = 1
+		_, err = clientset.BatchV1().Jobs("mystack-user-stack-7f46a74a4d").Update(k8sJob)
+		Expect(err).NotTo(HaveOccurred())
+
+		for name, phase := range map[string]v1.PodPhase{"setup-1": v1.PodFailed, "setup-2": v1.PodSucceeded} {
+			_, err = clientset.CoreV1().Pods("mystack-user-stack-7f46a74a4d").Create(&v1.Pod{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      name,
+					Namespace: "mystack-user-stack-7f46a74a4d",
+					Labels:    map[string]string{"app": "setup"},
+				},
+				Status: v1.PodStatus{Phase: phase},
+			})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		readiness := &JobReadiness{}
+		err = readiness.WaitForCompletion(clientset, job)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("failed to run stup job"))
+
+		diagnostics := err.(errors.DetailedError).Details().(*JobDiagnostics)
+		Expect(diagnostics.Job).To(Equal("setup"))
+		Expect(diagnostics.Pods).To(HaveLen(1))
+		Expect(diagnostics.Pods[0].Name).To(Equal("setup-1"))
+	})
+
 	It("should return error for non existing deployment", func() {
 		setup := &Setup{
 			Image:          "image",
 			PeriodSeconds:  1,
 			TimeoutSeconds: 1,
 		}
-		job := NewJob("user", setup, nil)
+		job := NewJob("setup", "user", "mystack-user-stack-7f46a74a4d", setup, nil)
 		readiness := &JobReadiness{}
 		err = readiness.WaitForCompletion(clientset, job)
 
@@ -22,7 +22,8 @@ var _ = Describe("Job", func() {
 		username  = "user"
 		image     = "setup-img"
 		clientset *fake.Clientset
-		namespace = "mystack-user"
+		namespace = "mystack-user-stack-7f46a74a4d"
+		stack     = "stack"
 	)
 
 	BeforeEach(func() {
@@ -31,11 +32,13 @@ var _ = Describe("Job", func() {
 
 	Describe("Run", func() {
 		It("should run job", func() {
-			err := CreateNamespace(clientset, username)
+			err := CreateNamespace(clientset, username, stack)
 			Expect(err).NotTo(HaveOccurred())
 
 			job := NewJob(
+				"setup",
 				username,
+				namespace,
 				&Setup{Image: image},
 				[]*EnvVar{
 					&EnvVar{Name: "DATABASE_URL", Value: "postgresql://derp"},
@@ -67,14 +70,14 @@ var _ = Describe("Job", func() {
 		})
 
 		It("should not run job without namespace", func() {
-			job := NewJob(username, &Setup{Image: image}, nil)
+			job := NewJob("setup", username, namespace, &Setup{Image: image}, nil)
 			_, err = job.Run(clientset)
 			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(Equal("namespace mystack-user not found"))
+			Expect(err.Error()).To(Equal("namespace mystack-user-stack-7f46a74a4d not found"))
 		})
 
 		It("should return nil job without setup", func() {
-			job := NewJob(username, nil, nil)
+			job := NewJob("setup", username, namespace, nil, nil)
 			Expect(job).To(BeNil())
 		})
 
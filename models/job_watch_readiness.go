@@ -0,0 +1,113 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"fmt"
+	"github.com/topfreegames/mystack-controller/errors"
+	"k8s.io/client-go/kubernetes"
+	apiv1 "k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/pkg/apis/batch/v1"
+	"k8s.io/client-go/pkg/fields"
+	"k8s.io/client-go/pkg/watch"
+	"time"
+)
+
+//JobWatchReadiness implements Readiness interface
+//watching the job instead of polling it.
+//If the watch breaks, it polls the job until the timeout.
+type JobWatchReadiness struct{}
+
+//WaitForCompletion waits until job has completed its task
+func (jr *JobWatchReadiness) WaitForCompletion(
+	clientset kubernetes.Interface,
+	j interface{},
+) error {
+	if j == nil {
+		return nil
+	}
+
+	job, ok := j.(*Job)
+	if !ok {
+		return errors.NewGenericError("wait for job completion error", fmt.Errorf("interface{} is not of type *models.Job"))
+	}
+
+	if job == nil {
+		return nil
+	}
+
+	period, timeout := getJobTimes(job.Setup)
+	start := time.Now()
+
+	watched, err := watchJob(clientset, job.Namespace, job.Name, timeout)
+	if err != nil || watched {
+		return err
+	}
+
+	remaining := timeout - time.Now().Sub(start)
+	if remaining <= 0 {
+		return jobTimeoutError(clientset, job.Namespace, job.Name)
+	}
+
+	return pollJob(clientset, job.Namespace, job.Name, period, remaining)
+}
+
+//watchJob returns true when the job succeeds
+//and false when the watch broke before that
+func watchJob(
+	clientset kubernetes.Interface,
+	namespace, name string,
+	timeout time.Duration,
+) (bool, error) {
+	k8sJob, err := clientset.BatchV1().Jobs(namespace).Get(name)
+	if err != nil {
+		return false, errors.NewKubernetesError("setup error", err)
+	}
+
+	finished, err := jobFinished(clientset, k8sJob)
+	if finished || err != nil {
+		return finished, err
+	}
+
+	watcher, err := clientset.BatchV1().Jobs(namespace).Watch(apiv1.ListOptions{
+		FieldSelector:   fields.OneTermEqualSelector("metadata.name", name).String(),
+		ResourceVersion: k8sJob.ResourceVersion,
+	})
+	if err != nil {
+		return false, nil
+	}
+	defer watcher.Stop()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case event, ok := <-watcher.ResultChan():
+			if !ok || event.Type == watch.Error {
+				return false, nil
+			}
+
+			if event.Type == watch.Deleted {
+				return false, errors.NewKubernetesError("setup error", fmt.Errorf("job %s was deleted", name))
+			}
+
+			k8sJob, ok := event.Object.(*v1.Job)
+			if !ok {
+				continue
+			}
+
+			finished, err := jobFinished(clientset, k8sJob)
+			if finished || err != nil {
+				return finished, err
+			}
+		case <-timer.C:
+			return false, jobTimeoutError(clientset, namespace, name)
+		}
+	}
+}
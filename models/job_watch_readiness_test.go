@@ -0,0 +1,108 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models_test
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/topfreegames/mystack-controller/models"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/pkg/watch"
+	k8stesting "k8s.io/client-go/testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JobWatchReadiness", func() {
+
+	var (
+		clientset *fake.Clientset
+		watcher   *watch.FakeWatcher
+		setup     *Setup
+		job       *Job
+		readiness *JobWatchReadiness
+	)
+
+	BeforeEach(func() {
+		clientset = fake.NewSimpleClientset()
+		watcher = watch.NewFake()
+		clientset.PrependWatchReactor("jobs", k8stesting.DefaultWatchReactor(watcher, nil))
+		setup = &Setup{
+			Image:          "image",
+			PeriodSeconds:  1,
+			TimeoutSeconds: 2,
+		}
+		readiness = &JobWatchReadiness{}
+		CreateNamespace(clientset, "user", "stack")
+		job = NewJob("setup", "user", "mystack-user-stack-7f46a74a4d", setup, nil)
+	})
+
+	It("should return when watch reports job succeeded", func() {
+		k8sJob, err := job.Run(clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		k8sJob.Status.Succeeded = 1
+		go watcher.Modify(k8sJob)
+
+		err = readiness.WaitForCompletion(clientset, job)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should return error when watch reports job failed", func() {
+		k8sJob, err := job.Run(clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		k8sJob.Status.Failed = 1
+		go watcher.Modify(k8sJob)
+
+		err = readiness.WaitForCompletion(clientset, job)
+		Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.KubernetesError"))
+		Expect(err.Error()).To(Equal("failed to run stup job"))
+	})
+
+	It("should reach non default timeout", func() {
+		_, err := job.Run(clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = readiness.WaitForCompletion(clientset, job)
+		Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.KubernetesError"))
+		Expect(err.Error()).To(Equal("failed to run stup job due to timeout"))
+	})
+
+	It("should fall back to polling if watch breaks", func() {
+		k8sJob, err := job.Run(clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		watcher.Stop()
+		go func() {
+			defer GinkgoRecover()
+			time.Sleep(500 * time.Millisecond)
+			k8sJob.Status.Succeeded = 1
+			_, err := clientset.BatchV1().Jobs(job.Namespace).Update(k8sJob)
+			Expect(err).NotTo(HaveOccurred())
+		}()
+
+		err = readiness.WaitForCompletion(clientset, job)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should return error for non existing job", func() {
+		err := readiness.WaitForCompletion(clientset, job)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Job.batch \"setup\" not found"))
+	})
+
+	It("should return error if interface{} is not job", func() {
+		err := readiness.WaitForCompletion(clientset, "not a job")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("interface{} is not of type *models.Job"))
+	})
+})
@@ -0,0 +1,209 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	uuid "github.com/satori/go.uuid"
+	"github.com/topfreegames/mystack-controller/errors"
+	"k8s.io/client-go/kubernetes"
+	k8serrors "k8s.io/client-go/pkg/api/errors"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+//The creation lease is the id saved on creatingAnnotation and the time
+//it was last renewed, saved on creatingRenewedAnnotation
+const (
+	creatingRenewedAnnotation = "mystack/creatingRenewedAt"
+	creationLeaseDuration     = time.Minute
+	creationLeaseRenewal      = 20 * time.Second
+)
+
+//Claim takes the creation lease of the cluster namespace, so only one creation
+//or resume runs on it at a time, creating the namespace if it doesn't exist
+//Leases not renewed for a minute, left by a controller that stopped, can be claimed again
+//The lease is written with the resourceVersion that was read, so concurrent claims conflict
+func (c *Cluster) Claim(clientset kubernetes.Interface) error {
+	if len(c.leaseID) > 0 {
+		return nil
+	}
+
+	leaseID := uuid.NewV4().String()
+	now := time.Now()
+
+	ns := newNamespace(c.Username, c.ClusterName)
+	setLease(ns, leaseID, now)
+	_, err := clientset.CoreV1().Namespaces().Create(ns)
+	if err == nil {
+		c.leaseID = leaseID
+		c.createdNamespace = true
+		return nil
+	}
+	if !k8serrors.IsAlreadyExists(err) {
+		return errors.NewKubernetesError("create namespace error", err)
+	}
+
+	ns, err = clientset.CoreV1().Namespaces().Get(c.Namespace)
+	if err != nil {
+		return errors.NewKubernetesError("claim namespace error", err)
+	}
+	if leaseHeld(ns, now) {
+		return c.beingCreatedError()
+	}
+
+	setLease(ns, leaseID, now)
+	_, err = clientset.CoreV1().Namespaces().Update(ns)
+	if k8serrors.IsConflict(err) {
+		return c.beingCreatedError()
+	}
+	if err != nil {
+		return errors.NewKubernetesError("claim namespace error", err)
+	}
+	c.leaseID = leaseID
+	c.createdNamespace = false
+
+	return nil
+}
+
+//ReleaseClaim gives up the lease of a creation that won't run
+//The namespace is deleted if Claim created it, since nothing was created on it yet
+func (c *Cluster) ReleaseClaim(clientset kubernetes.Interface) error {
+	if len(c.leaseID) == 0 {
+		return nil
+	}
+
+	if c.createdNamespace {
+		c.leaseID = ""
+		c.createdNamespace = false
+		return DeleteNamespace(clientset, c.Namespace)
+	}
+
+	return c.writeLease(clientset, nil)
+}
+
+//CreationRunning is true if a creation holds a lease on the namespace
+//that was renewed in the last minute
+func CreationRunning(clientset kubernetes.Interface, namespace string) (bool, error) {
+	ns, err := clientset.CoreV1().Namespaces().Get(namespace)
+	if err != nil {
+		return false, errors.NewKubernetesError("get namespace annotation error", err)
+	}
+
+	return leaseHeld(ns, time.Now()), nil
+}
+
+//withLease runs fn holding the lease of the cluster namespace, renewed until fn returns
+//Creations, resumes, updates, sleeps and wakes of a namespace don't run at the same time
+func (c *Cluster) withLease(
+	logger logrus.FieldLogger,
+	clientset kubernetes.Interface,
+	fn func() error,
+) error {
+	err := c.Claim(clientset)
+	if err != nil {
+		return err
+	}
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		c.holdLease(logger, clientset, stop)
+		close(stopped)
+	}()
+	defer func() {
+		close(stop)
+		<-stopped
+		c.releaseLease(logger, clientset)
+	}()
+
+	return fn()
+}
+
+//holdLease renews the lease of the cluster until stop is closed
+func (c *Cluster) holdLease(logger logrus.FieldLogger, clientset kubernetes.Interface, stop <-chan struct{}) {
+	ticker := time.NewTicker(creationLeaseRenewal)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			err := c.writeLease(clientset, &now)
+			if err != nil && logger != nil {
+				logger.WithError(err).Warn("failed to renew creation lease")
+			}
+		}
+	}
+}
+
+//writeLease renews the lease held by the cluster at renewedAt, or removes it if renewedAt is nil
+//It fails if the lease was claimed by someone else
+func (c *Cluster) writeLease(clientset kubernetes.Interface, renewedAt *time.Time) error {
+	ns, err := clientset.CoreV1().Namespaces().Get(c.Namespace)
+	if err != nil {
+		return errors.NewKubernetesError("creation lease error", err)
+	}
+
+	if ns.GetAnnotations()[creatingAnnotation] != c.leaseID {
+		return errors.NewKubernetesError(
+			"creation lease error",
+			fmt.Errorf("creation lease of namespace '%s' was claimed by another creation", c.Namespace),
+		)
+	}
+
+	if renewedAt != nil {
+		setLease(ns, c.leaseID, *renewedAt)
+	} else {
+		delete(ns.Annotations, creatingAnnotation)
+		delete(ns.Annotations, creatingRenewedAnnotation)
+	}
+
+	_, err = clientset.CoreV1().Namespaces().Update(ns)
+	if err != nil {
+		return errors.NewKubernetesError("creation lease error", err)
+	}
+	if renewedAt == nil {
+		c.leaseID = ""
+	}
+
+	return nil
+}
+
+func (c *Cluster) beingCreatedError() error {
+	return errors.NewKubernetesError(
+		"create cluster error",
+		fmt.Errorf("namespace for user '%s' already exists and is being created", c.Username),
+	)
+}
+
+func setLease(ns *v1.Namespace, leaseID string, renewedAt time.Time) {
+	if ns.Annotations == nil {
+		ns.Annotations = map[string]string{}
+	}
+	ns.Annotations[creatingAnnotation] = leaseID
+	ns.Annotations[creatingRenewedAnnotation] = renewedAt.UTC().Format(time.RFC3339)
+}
+
+func leaseHeld(ns *v1.Namespace, now time.Time) bool {
+	annotations := ns.GetAnnotations()
+	if len(annotations[creatingAnnotation]) == 0 {
+		return false
+	}
+
+	renewedAt, err := time.Parse(time.RFC3339, annotations[creatingRenewedAnnotation])
+	if err != nil {
+		return false
+	}
+
+	return now.Sub(renewedAt) < creationLeaseDuration
+}
@@ -0,0 +1,299 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/topfreegames/mystack-controller/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+//LogOptions filters the logs read from the pods of an app or job
+type LogOptions struct {
+	Follow       bool
+	TailLines    *int64
+	SinceSeconds *int64
+}
+
+func (o *LogOptions) podLogOptions(container string, follow bool) *v1.PodLogOptions {
+	options := &v1.PodLogOptions{
+		Container: container,
+		Follow:    follow,
+	}
+	if o != nil {
+		options.TailLines = o.TailLines
+		options.SinceSeconds = o.SinceSeconds
+	}
+	return options
+}
+
+//sortedPods returns the pods labelled app=name, oldest first
+func sortedPods(clientset kubernetes.Interface, namespace, name string) ([]v1.Pod, error) {
+	pods, err := appPods(clientset, namespace, name)
+	if err != nil {
+		return nil, errors.NewKubernetesError("get logs error", err)
+	}
+
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].CreationTimestamp.Time.Before(pods[j].CreationTimestamp.Time)
+	})
+
+	return pods, nil
+}
+
+//WriteJobLogs writes on w the logs of every pod the job ran, oldest first
+//With Follow set, only the newest pod is followed until ctx is done
+func (j *Job) WriteJobLogs(ctx context.Context, clientset kubernetes.Interface, options *LogOptions, w io.Writer) error {
+	pods, err := sortedPods(clientset, j.Namespace, j.Name)
+	if err != nil {
+		return err
+	}
+
+	if len(pods) == 0 {
+		return errors.NewKubernetesError(
+			"get logs error",
+			fmt.Errorf("pods of job '%s' not found", j.Name),
+		)
+	}
+
+	streams := openLogStreams(ctx)
+	defer streams.Close()
+
+	for i, pod := range pods {
+		follow := options != nil && options.Follow && i == len(pods)-1
+		fmt.Fprintf(w, "==> pod %s <==\n", pod.Name)
+		err := writePodLogs(clientset, streams, &pod, j.Name, options.podLogOptions(j.Name, follow), w)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//WriteLogs writes on w the logs of the app pods, each line prefixed by its pod
+//With Follow set, all pods are followed at the same time until ctx is done
+//or writing the logs of any of them fails
+func (d *Deployment) WriteLogs(ctx context.Context, clientset kubernetes.Interface, options *LogOptions, w io.Writer) error {
+	pods, err := sortedPods(clientset, d.Namespace, d.Name)
+	if err != nil {
+		return err
+	}
+
+	started := []v1.Pod{}
+	for _, pod := range pods {
+		if pod.Status.Phase != v1.PodPending {
+			started = append(started, pod)
+		}
+	}
+
+	if len(started) == 0 {
+		return errors.NewKubernetesError(
+			"get logs error",
+			fmt.Errorf("pods of app '%s' not found", d.Name),
+		)
+	}
+
+	streams := openLogStreams(ctx)
+	defer streams.Close()
+
+	out := &lockedWriter{w: w}
+	follow := options != nil && options.Follow
+	if !follow {
+		for _, pod := range started {
+			err := d.writePrefixedLogs(clientset, streams, &pod, options, out)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(started))
+	for _, pod := range started {
+		wg.Add(1)
+		go func(pod v1.Pod) {
+			defer wg.Done()
+			err := d.writePrefixedLogs(clientset, streams, &pod, options, out)
+			errs <- err
+			if err != nil {
+				streams.Close()
+			}
+		}(pod)
+	}
+	wg.Wait()
+	close(errs)
+
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	//the first error is the one that closed the other streams
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *Deployment) writePrefixedLogs(
+	clientset kubernetes.Interface,
+	streams *logStreams,
+	pod *v1.Pod,
+	options *LogOptions,
+	out *lockedWriter,
+) error {
+	prefixed := &prefixWriter{prefix: fmt.Sprintf("[%s] ", pod.Name), w: out}
+	follow := options != nil && options.Follow
+	err := writePodLogs(clientset, streams, pod, d.Name, options.podLogOptions(d.Name, follow), prefixed)
+	prefixed.Flush()
+	return err
+}
+
+//logStreams are the log streams being read for a request
+//Closing them stops the reads, which don't stop by themselves when following
+type logStreams struct {
+	mutex   sync.Mutex
+	closed  chan struct{}
+	streams []io.Closer
+}
+
+//openLogStreams returns the streams of a request, closed when ctx is done
+func openLogStreams(ctx context.Context) *logStreams {
+	streams := &logStreams{closed: make(chan struct{})}
+	go func() {
+		select {
+		case <-ctx.Done():
+			streams.Close()
+		case <-streams.closed:
+		}
+	}()
+	return streams
+}
+
+//add keeps stream to be closed with the others
+//It is false, and stream is closed, if the streams were already closed
+func (l *logStreams) add(stream io.Closer) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	select {
+	case <-l.closed:
+		stream.Close()
+		return false
+	default:
+	}
+	l.streams = append(l.streams, stream)
+	return true
+}
+
+//Close closes every stream, the ones added later are closed right away
+func (l *logStreams) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	select {
+	case <-l.closed:
+		return nil
+	default:
+	}
+	close(l.closed)
+	for _, stream := range l.streams {
+		stream.Close()
+	}
+	return nil
+}
+
+//lockedWriter serializes writes from the goroutines following each pod
+type lockedWriter struct {
+	mutex sync.Mutex
+	w     io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.w.Write(p)
+}
+
+//prefixWriter writes only whole lines, so lines of different pods don't mix
+type prefixWriter struct {
+	prefix string
+	w      io.Writer
+	buf    bytes.Buffer
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.buf.Write(data)
+
+	for {
+		idx := bytes.IndexByte(p.buf.Bytes(), '\n')
+		if idx < 0 {
+			break
+		}
+
+		line := p.buf.Next(idx + 1)
+		_, err := p.w.Write(append([]byte(p.prefix), line...))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(data), nil
+}
+
+//Flush writes the last line if it didn't end with a new line
+func (p *prefixWriter) Flush() {
+	if p.buf.Len() == 0 {
+		return
+	}
+
+	p.w.Write(append(append([]byte(p.prefix), p.buf.Bytes()...), '\n'))
+	p.buf.Reset()
+}
+
+func writePodLogs(
+	clientset kubernetes.Interface,
+	streams *logStreams,
+	pod *v1.Pod,
+	container string,
+	options *v1.PodLogOptions,
+	w io.Writer,
+) error {
+	stream, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, options).Stream()
+	if err != nil {
+		return errors.NewKubernetesError("get logs error", err)
+	}
+	if !streams.add(stream) {
+		return nil
+	}
+	defer stream.Close()
+
+	_, err = io.Copy(w, stream)
+	if err != nil {
+		return errors.NewKubernetesError("get logs error", err)
+	}
+
+	return nil
+}
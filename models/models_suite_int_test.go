@@ -22,7 +22,7 @@ import (
 )
 
 var conn *sqlx.DB
-var db *sqlx.Tx
+var db *oTesting.TxDB
 var err error
 var config *viper.Viper
 
@@ -43,8 +43,9 @@ var _ = BeforeSuite(func() {
 })
 
 var _ = BeforeEach(func() {
-	db, err = conn.Beginx()
+	tx, err := conn.Beginx()
 	Expect(err).NotTo(HaveOccurred())
+	db = &oTesting.TxDB{Tx: tx}
 })
 
 var _ = AfterEach(func() {
@@ -13,18 +13,15 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/spf13/viper"
 
-	"database/sql"
 	"testing"
 
-	"github.com/jmoiron/sqlx"
 	"gopkg.in/DATA-DOG/go-sqlmock.v1"
 
 	mTest "github.com/topfreegames/mystack-controller/testing"
 )
 
 var (
-	db     *sql.DB
-	sqlxDB *sqlx.DB
+	sqlxDB *mTest.MockDB
 	mock   sqlmock.Sqlmock
 	err    error
 	config *viper.Viper
@@ -41,13 +38,12 @@ var _ = BeforeSuite(func() {
 })
 
 var _ = BeforeEach(func() {
-	db, mock, err = sqlmock.New()
+	sqlxDB, mock, err = mTest.NewMockDB()
 	Expect(err).NotTo(HaveOccurred())
-	sqlxDB = sqlx.NewDb(db, "postgres")
 })
 
 var _ = AfterEach(func() {
-	defer db.Close()
+	defer sqlxDB.Close()
 	err = mock.ExpectationsWereMet()
 	Expect(err).NotTo(HaveOccurred())
 })
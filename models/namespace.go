@@ -8,13 +8,27 @@
 package models
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/topfreegames/mystack-controller/errors"
 	"k8s.io/client-go/kubernetes"
+	k8serrors "k8s.io/client-go/pkg/api/errors"
 	"k8s.io/client-go/pkg/api/v1"
 	"k8s.io/client-go/pkg/fields"
 	"k8s.io/client-go/pkg/labels"
 )
 
+//Annotations saved on the namespace of a cluster
+const (
+	expiresAtAnnotation  = "mystack/expiresAt"
+	sleepingAnnotation   = "mystack/sleeping"
+	scheduleAnnotation   = "mystack/scheduled"
+	failedStepAnnotation = "mystack/failedStep"
+	creatingAnnotation   = "mystack/creatingBy"
+)
+
 var (
 	labelMap    = labels.Set{"mystack/routable": "true"}
 	listOptions = v1.ListOptions{
@@ -23,29 +37,56 @@ var (
 	}
 )
 
-//CreateNamespace creates a namespace
-func CreateNamespace(clientset kubernetes.Interface, username string) error {
-	namespaceStr := usernameToNamespace(username)
-	namespace := &v1.Namespace{
+//CreateNamespace creates the namespace of the user stack created from clusterName
+func CreateNamespace(clientset kubernetes.Interface, username, clusterName string) error {
+	_, err := clientset.CoreV1().Namespaces().Create(newNamespace(username, clusterName))
+
+	if err != nil {
+		return errors.NewKubernetesError("create namespace error", err)
+	}
+
+	return nil
+}
+
+//newNamespace returns the namespace of the user stack created from clusterName
+func newNamespace(username, clusterName string) *v1.Namespace {
+	return &v1.Namespace{
 		ObjectMeta: v1.ObjectMeta{
-			Name: namespaceStr,
+			Name: ClusterNamespace(username, clusterName),
 			Labels: map[string]string{
 				"mystack/routable": "true",
+				"mystack/owner":    username,
+			},
+			Annotations: map[string]string{
+				"mystack/cluster": clusterName,
 			},
 		},
 	}
-	_, err := clientset.CoreV1().Namespaces().Create(namespace)
+}
 
+//CheckNamespaceOwner returns an AccessError if namespace wasn't created for clusterName of username
+//Namespaces that don't exist are accepted, acting on them fails as not found
+func CheckNamespaceOwner(clientset kubernetes.Interface, namespace, username, clusterName string) error {
+	ns, err := clientset.CoreV1().Namespaces().Get(namespace)
+	if k8serrors.IsNotFound(err) {
+		return nil
+	}
 	if err != nil {
-		return errors.NewKubernetesError("create namespace error", err)
+		return errors.NewKubernetesError("get namespace error", err)
+	}
+
+	if ns.GetLabels()["mystack/owner"] != username || ns.GetAnnotations()["mystack/cluster"] != clusterName {
+		return errors.NewAccessError(
+			"cluster access error",
+			fmt.Errorf("namespace '%s' doesn't belong to cluster '%s' of user '%s'", namespace, clusterName, username),
+		)
 	}
 
 	return nil
 }
 
 //DeleteNamespace delete the namespace
-func DeleteNamespace(clientset kubernetes.Interface, username string) error {
-	namespace := usernameToNamespace(username)
+func DeleteNamespace(clientset kubernetes.Interface, namespace string) error {
 	deleteOptions := &v1.DeleteOptions{}
 
 	err := clientset.CoreV1().Namespaces().Delete(namespace, deleteOptions)
@@ -70,3 +111,171 @@ func NamespaceExists(clientset kubernetes.Interface, namespace string) bool {
 	_, err := clientset.CoreV1().Namespaces().Get(namespace)
 	return err == nil
 }
+
+//ListClusters returns the names of the clusters the user is running
+func ListClusters(clientset kubernetes.Interface, username string) ([]string, error) {
+	labelMap := labels.Set{
+		"mystack/routable": "true",
+		"mystack/owner":    username,
+	}
+	listOptions := v1.ListOptions{
+		LabelSelector: labelMap.AsSelector().String(),
+		FieldSelector: fields.Everything().String(),
+	}
+
+	list, err := clientset.CoreV1().Namespaces().List(listOptions)
+	if err != nil {
+		return nil, errors.NewKubernetesError("list clusters error", err)
+	}
+
+	clusters := []string{}
+	for _, namespace := range list.Items {
+		clusters = append(clusters, namespace.GetAnnotations()["mystack/cluster"])
+	}
+
+	return clusters, nil
+}
+
+//SetClusterExpiration sets when the cluster on namespace must be deleted
+func SetClusterExpiration(clientset kubernetes.Interface, namespace string, expiresAt time.Time) error {
+	return annotateNamespace(clientset, namespace, expiresAtAnnotation, expiresAt.UTC().Format(time.RFC3339))
+}
+
+//annotateNamespace sets key on namespace annotations
+//An empty value removes the annotation
+func annotateNamespace(clientset kubernetes.Interface, namespace, key, value string) error {
+	ns, err := clientset.CoreV1().Namespaces().Get(namespace)
+	if err != nil {
+		return errors.NewKubernetesError("annotate namespace error", err)
+	}
+
+	if ns.Annotations == nil {
+		ns.Annotations = map[string]string{}
+	}
+	if len(value) == 0 {
+		delete(ns.Annotations, key)
+	} else {
+		ns.Annotations[key] = value
+	}
+
+	_, err = clientset.CoreV1().Namespaces().Update(ns)
+	if err != nil {
+		return errors.NewKubernetesError("annotate namespace error", err)
+	}
+
+	return nil
+}
+
+//namespaceAnnotation returns the value of key on namespace annotations
+func namespaceAnnotation(clientset kubernetes.Interface, namespace, key string) (string, error) {
+	ns, err := clientset.CoreV1().Namespaces().Get(namespace)
+	if err != nil {
+		return "", errors.NewKubernetesError("get namespace annotation error", err)
+	}
+
+	return ns.GetAnnotations()[key], nil
+}
+
+//FailedStep returns the creation step that failed on a cluster kept on failure
+//It is empty if the creation didn't fail
+func FailedStep(clientset kubernetes.Interface, namespace string) (string, error) {
+	return namespaceAnnotation(clientset, namespace, failedStepAnnotation)
+}
+
+//ClusterExpiration returns when the cluster on namespace will be deleted
+//It is nil if the cluster has no ttl
+func ClusterExpiration(clientset kubernetes.Interface, namespace string) (*time.Time, error) {
+	ns, err := clientset.CoreV1().Namespaces().Get(namespace)
+	if err != nil {
+		return nil, errors.NewKubernetesError("get cluster expiration error", err)
+	}
+
+	return namespaceExpiration(ns)
+}
+
+func namespaceExpiration(ns *v1.Namespace) (*time.Time, error) {
+	value, ok := ns.GetAnnotations()[expiresAtAnnotation]
+	if !ok {
+		return nil, nil
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, errors.NewGenericError(
+			"get cluster expiration error",
+			fmt.Errorf("invalid expiration on namespace %s: %s", ns.Name, value),
+		)
+	}
+
+	return &expiresAt, nil
+}
+
+//ExpiredCluster is a running cluster whose ttl has passed
+type ExpiredCluster struct {
+	Username    string
+	ClusterName string
+	Namespace   string
+	ExpiresAt   time.Time
+}
+
+//ExpiredClusters returns the mystack clusters that expired before now
+func ExpiredClusters(clientset kubernetes.Interface, now time.Time) ([]*ExpiredCluster, error) {
+	list, err := ListNamespaces(clientset)
+	if err != nil {
+		return nil, err
+	}
+
+	expired := []*ExpiredCluster{}
+	for i := range list.Items {
+		ns := &list.Items[i]
+		if !strings.HasPrefix(ns.Name, "mystack-") {
+			continue
+		}
+
+		expiresAt, err := namespaceExpiration(ns)
+		if err != nil || expiresAt == nil || expiresAt.After(now) {
+			continue
+		}
+
+		expired = append(expired, &ExpiredCluster{
+			Username:    ns.GetLabels()["mystack/owner"],
+			ClusterName: ns.GetAnnotations()["mystack/cluster"],
+			Namespace:   ns.Name,
+			ExpiresAt:   *expiresAt,
+		})
+	}
+
+	return expired, nil
+}
+
+//RunningCluster is a mystack cluster found on kubernetes
+type RunningCluster struct {
+	Username    string
+	ClusterName string
+	Namespace   string
+}
+
+//RunningClusters returns every mystack cluster running on kubernetes
+func RunningClusters(clientset kubernetes.Interface) ([]*RunningCluster, error) {
+	list, err := ListNamespaces(clientset)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := []*RunningCluster{}
+	for _, ns := range list.Items {
+		username := ns.GetLabels()["mystack/owner"]
+		clusterName := ns.GetAnnotations()["mystack/cluster"]
+		if !strings.HasPrefix(ns.Name, "mystack-") || len(username) == 0 || len(clusterName) == 0 {
+			continue
+		}
+
+		clusters = append(clusters, &RunningCluster{
+			Username:    username,
+			ClusterName: clusterName,
+			Namespace:   ns.Name,
+		})
+	}
+
+	return clusters, nil
+}
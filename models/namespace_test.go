@@ -12,6 +12,8 @@ import (
 	. "github.com/topfreegames/mystack-controller/models"
 
 	"fmt"
+	"time"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"k8s.io/client-go/kubernetes/fake"
@@ -21,7 +23,8 @@ var _ = Describe("Namespace", func() {
 	var (
 		clientset *fake.Clientset
 		username  = "user"
-		namespace = "mystack-user"
+		namespace = "mystack-user-stack-7f46a74a4d"
+		stack     = "stack"
 	)
 
 	BeforeEach(func() {
@@ -30,7 +33,7 @@ var _ = Describe("Namespace", func() {
 
 	Describe("CreateNamespace", func() {
 		It("should create a namespace", func() {
-			err := CreateNamespace(clientset, username)
+			err := CreateNamespace(clientset, username, stack)
 			Expect(err).NotTo(HaveOccurred())
 
 			ns, err := ListNamespaces(clientset)
@@ -39,12 +42,12 @@ var _ = Describe("Namespace", func() {
 		})
 
 		It("should return error when creating existing namespace", func() {
-			err := CreateNamespace(clientset, username)
+			err := CreateNamespace(clientset, username, stack)
 			Expect(err).NotTo(HaveOccurred())
 
-			err = CreateNamespace(clientset, username)
+			err = CreateNamespace(clientset, username, stack)
 			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(Equal("Namespace \"mystack-user\" already exists"))
+			Expect(err.Error()).To(Equal("Namespace \"mystack-user-stack-7f46a74a4d\" already exists"))
 			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.KubernetesError"))
 		})
 	})
@@ -56,7 +59,7 @@ var _ = Describe("Namespace", func() {
 		})
 
 		It("should return true after creating namespace", func() {
-			err := CreateNamespace(clientset, username)
+			err := CreateNamespace(clientset, username, stack)
 			Expect(err).NotTo(HaveOccurred())
 
 			exist := NamespaceExists(clientset, namespace)
@@ -66,19 +69,142 @@ var _ = Describe("Namespace", func() {
 
 	Describe("DeleteNamespace", func() {
 		It("should return error when deleting non-exiting namespace", func() {
-			err := DeleteNamespace(clientset, username)
+			err := DeleteNamespace(clientset, namespace)
 			Expect(err).To(HaveOccurred())
 		})
 
 		It("should delete namespace if exists", func() {
-			err := CreateNamespace(clientset, username)
+			err := CreateNamespace(clientset, username, stack)
 			Expect(err).NotTo(HaveOccurred())
 
-			err = DeleteNamespace(clientset, username)
+			err = DeleteNamespace(clientset, namespace)
 			Expect(err).NotTo(HaveOccurred())
 
 			exist := NamespaceExists(clientset, namespace)
 			Expect(exist).To(BeFalse())
 		})
 	})
+
+	Describe("CheckNamespaceOwner", func() {
+		It("should accept namespace of the user cluster", func() {
+			err := CreateNamespace(clientset, username, stack)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = CheckNamespaceOwner(clientset, namespace, username, stack)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should accept namespace that doesn't exist", func() {
+			err := CheckNamespaceOwner(clientset, namespace, username, stack)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should return error if namespace belongs to another user or cluster", func() {
+			err := CreateNamespace(clientset, username, stack)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = CheckNamespaceOwner(clientset, namespace, "other", stack)
+			Expect(err).To(HaveOccurred())
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.AccessError"))
+
+			err = CheckNamespaceOwner(clientset, namespace, username, "other")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("ListClusters", func() {
+		It("should return only the clusters of the user", func() {
+			err := CreateNamespace(clientset, username, "backend")
+			Expect(err).NotTo(HaveOccurred())
+			err = CreateNamespace(clientset, username, "Analytics")
+			Expect(err).NotTo(HaveOccurred())
+			err = CreateNamespace(clientset, "other", "backend")
+			Expect(err).NotTo(HaveOccurred())
+
+			clusters, err := ListClusters(clientset, username)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(clusters).To(ConsistOf("backend", "Analytics"))
+		})
+
+		It("should return empty list if user has no clusters", func() {
+			clusters, err := ListClusters(clientset, username)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(clusters).To(BeEmpty())
+		})
+	})
+
+	Describe("SetClusterExpiration", func() {
+		It("should save expiration on namespace", func() {
+			err := CreateNamespace(clientset, username, stack)
+			Expect(err).NotTo(HaveOccurred())
+
+			expiresAt := time.Date(2017, 5, 10, 12, 0, 0, 0, time.UTC)
+			err = SetClusterExpiration(clientset, namespace, expiresAt)
+			Expect(err).NotTo(HaveOccurred())
+
+			saved, err := ClusterExpiration(clientset, namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*saved).To(BeTemporally("==", expiresAt))
+		})
+
+		It("should return error if namespace doesn't exist", func() {
+			err := SetClusterExpiration(clientset, namespace, time.Now())
+			Expect(err).To(HaveOccurred())
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.KubernetesError"))
+		})
+
+		It("should return nil expiration if cluster has no ttl", func() {
+			err := CreateNamespace(clientset, username, stack)
+			Expect(err).NotTo(HaveOccurred())
+
+			saved, err := ClusterExpiration(clientset, namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(saved).To(BeNil())
+		})
+	})
+
+	Describe("ExpiredClusters", func() {
+		It("should return only expired clusters", func() {
+			now := time.Now()
+
+			err := CreateNamespace(clientset, username, "expired")
+			Expect(err).NotTo(HaveOccurred())
+			err = SetClusterExpiration(clientset, ClusterNamespace(username, "expired"), now.Add(-time.Hour))
+			Expect(err).NotTo(HaveOccurred())
+
+			err = CreateNamespace(clientset, username, "alive")
+			Expect(err).NotTo(HaveOccurred())
+			err = SetClusterExpiration(clientset, ClusterNamespace(username, "alive"), now.Add(time.Hour))
+			Expect(err).NotTo(HaveOccurred())
+
+			err = CreateNamespace(clientset, username, "forever")
+			Expect(err).NotTo(HaveOccurred())
+
+			expired, err := ExpiredClusters(clientset, now)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(expired).To(HaveLen(1))
+			Expect(expired[0].Username).To(Equal(username))
+			Expect(expired[0].ClusterName).To(Equal("expired"))
+			Expect(expired[0].Namespace).To(Equal("mystack-user-expired-d17abcdbde"))
+		})
+	})
+
+	Describe("ClusterNamespace", func() {
+		It("should key namespace by user and cluster", func() {
+			Expect(ClusterNamespace(username, stack)).To(Equal(namespace))
+			Expect(ClusterNamespace(username, "My_Custom.Apps")).To(Equal("mystack-user-my-custom-apps-350f4ddf73"))
+		})
+
+		It("should not share namespace between names that read the same", func() {
+			Expect(ClusterNamespace("john", "doe-x")).NotTo(Equal(ClusterNamespace("john-doe", "x")))
+			Expect(ClusterNamespace(username, "My_Apps")).NotTo(Equal(ClusterNamespace(username, "my-apps")))
+		})
+
+		It("should fit long names on a k8s namespace", func() {
+			clusterName := "a-very-long-cluster-name-that-does-not-fit-on-a-kubernetes-namespace"
+			ns := ClusterNamespace(username, clusterName)
+			Expect(len(ns)).To(BeNumerically("<=", 63))
+			Expect(ns).NotTo(Equal(ClusterNamespace(username, clusterName+"-2")))
+		})
+	})
 })
@@ -0,0 +1,233 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/topfreegames/mystack-controller/errors"
+)
+
+const (
+	//OperationCreate is the kind of the operation that creates a cluster
+	OperationCreate = "create"
+	//OperationReap is the kind of the operation that deletes an expired cluster
+	OperationReap = "reap"
+	//OperationResume is the kind of the operation that resumes a failed creation
+	OperationResume = "resume"
+	//OperationDelete is the kind of the operation that deletes a cluster on request
+	OperationDelete = "delete"
+
+	//OperationRunning is the status of an operation that hasn't finished yet
+	OperationRunning = "running"
+	//OperationSucceeded is the status of an operation that finished without errors
+	OperationSucceeded = "succeeded"
+	//OperationFailed is the status of an operation that finished with an error
+	OperationFailed = "failed"
+)
+
+//OperationStep has the timings of a single step of an operation
+type OperationStep struct {
+	Name       string     `json:"name"`
+	Status     string     `json:"status"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+//Operation is a long running task executed in background
+//It implements Tracker interface, saving on DB every step change
+type Operation struct {
+	ID          string           `db:"id" json:"id"`
+	Kind        string           `db:"kind" json:"kind"`
+	ClusterName string           `db:"cluster_name" json:"clusterName"`
+	Username    string           `db:"username" json:"username"`
+	Status      string           `db:"status" json:"status"`
+	Phase       string           `db:"phase" json:"phase"`
+	Error       string           `db:"error" json:"error,omitempty"`
+	StepsJSON   string           `db:"steps" json:"-"`
+	Steps       []*OperationStep `db:"-" json:"steps"`
+	CreatedAt   time.Time        `db:"created_at" json:"createdAt"`
+	UpdatedAt   time.Time        `db:"updated_at" json:"updatedAt"`
+	FinishedAt  *time.Time       `db:"finished_at" json:"finishedAt,omitempty"`
+
+	ErrorDetailsJSON string          `db:"error_details" json:"-"`
+	ErrorDetails     json.RawMessage `db:"-" json:"errorDetails,omitempty"`
+
+	db    DB
+	mutex sync.Mutex
+}
+
+//NewOperation creates a running operation and saves it on DB
+func NewOperation(db DB, kind, clusterName, username string) (*Operation, error) {
+	now := time.Now()
+	operation := &Operation{
+		ID:          uuid.NewV4().String(),
+		Kind:        kind,
+		ClusterName: clusterName,
+		Username:    username,
+		Status:      OperationRunning,
+		Steps:       []*OperationStep{},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		db:          db,
+	}
+
+	query := `INSERT INTO operations(id, kind, cluster_name, username, status, steps)
+	VALUES(:id, :kind, :cluster_name, :username, :status, :steps)`
+	values := map[string]interface{}{
+		"id":           operation.ID,
+		"kind":         operation.Kind,
+		"cluster_name": operation.ClusterName,
+		"username":     operation.Username,
+		"status":       operation.Status,
+		"steps":        "[]",
+	}
+	res, err := db.NamedExec(query, values)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, errors.NewDatabaseError(fmt.Errorf("couldn't insert on database"))
+	}
+
+	return operation, nil
+}
+
+//LoadOperation reads an operation from DB
+func LoadOperation(db DB, id string) (*Operation, error) {
+	if _, err := uuid.FromString(id); err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("sql: no rows in result set"))
+	}
+
+	operation := &Operation{}
+	query := `SELECT id, kind, cluster_name, username, status, phase, steps, error, error_details, created_at, updated_at, finished_at
+	FROM operations
+	WHERE id = $1`
+	err := db.Get(operation, query, id)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	err = json.Unmarshal([]byte(operation.StepsJSON), &operation.Steps)
+	if err != nil {
+		return nil, errors.NewGenericError("load operation error", err)
+	}
+
+	if operation.ErrorDetailsJSON != "" {
+		operation.ErrorDetails = json.RawMessage(operation.ErrorDetailsJSON)
+	}
+
+	operation.db = db
+	return operation, nil
+}
+
+//StartStep saves on DB that step has started
+func (o *Operation) StartStep(step string) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.Phase = step
+	o.Steps = append(o.Steps, &OperationStep{
+		Name:      step,
+		Status:    OperationRunning,
+		StartedAt: time.Now(),
+	})
+
+	return o.save()
+}
+
+//FinishStep saves on DB that step has finished, successfully or not
+func (o *Operation) FinishStep(step string, err error) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	now := time.Now()
+	for _, operationStep := range o.Steps {
+		if operationStep.Name != step || operationStep.FinishedAt != nil {
+			continue
+		}
+
+		operationStep.FinishedAt = &now
+		operationStep.Status = OperationSucceeded
+		if err != nil {
+			operationStep.Status = OperationFailed
+			operationStep.Error = err.Error()
+		}
+	}
+
+	return o.save()
+}
+
+//Finish saves on DB the operation final status
+//Errors with details, like the events of pods that failed, have them saved too
+func (o *Operation) Finish(err error) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	now := time.Now()
+	o.FinishedAt = &now
+	o.Status = OperationSucceeded
+	if err != nil {
+		o.Status = OperationFailed
+		o.Error = err.Error()
+
+		if detailed, ok := err.(errors.DetailedError); ok && detailed.Details() != nil {
+			details, jsonErr := json.Marshal(detailed.Details())
+			if jsonErr == nil {
+				o.ErrorDetails = details
+			}
+		}
+	}
+
+	return o.save()
+}
+
+func (o *Operation) save() error {
+	if o.db == nil {
+		return nil
+	}
+
+	steps, err := json.Marshal(o.Steps)
+	if err != nil {
+		return errors.NewGenericError("save operation error", err)
+	}
+	o.StepsJSON = string(steps)
+	o.ErrorDetailsJSON = string(o.ErrorDetails)
+	o.UpdatedAt = time.Now()
+
+	query := `UPDATE operations
+	SET status = :status,
+			phase = :phase,
+			steps = :steps,
+			error = :error,
+			error_details = :error_details,
+			updated_at = :updated_at,
+			finished_at = :finished_at
+	WHERE id = :id`
+	values := map[string]interface{}{
+		"id":            o.ID,
+		"status":        o.Status,
+		"phase":         o.Phase,
+		"steps":         o.StepsJSON,
+		"error":         o.Error,
+		"error_details": o.ErrorDetailsJSON,
+		"updated_at":    o.UpdatedAt,
+		"finished_at":   o.FinishedAt,
+	}
+	_, err = o.db.NamedExec(query, values)
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/topfreegames/mystack-controller/errors"
+	. "github.com/topfreegames/mystack-controller/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var _ = Describe("Operation", func() {
+	const (
+		clusterName = "myCustomApps"
+		username    = "user"
+		operationID = "5f6d9e5c-0d3b-4b8e-9d0a-1c1ac6c1d6f1"
+	)
+
+	Describe("NewOperation", func() {
+		It("should save running operation on DB", func() {
+			mock.
+				ExpectExec("^INSERT INTO operations(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			operation, err := NewOperation(sqlxDB, OperationCreate, clusterName, username)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(operation.ID).NotTo(BeEmpty())
+			Expect(operation.Status).To(Equal(OperationRunning))
+			Expect(operation.Steps).To(BeEmpty())
+		})
+
+		It("should return error if insert fails", func() {
+			mock.
+				ExpectExec("^INSERT INTO operations(.+)$").
+				WillReturnError(fmt.Errorf("connection refused"))
+
+			_, err := NewOperation(sqlxDB, OperationCreate, clusterName, username)
+			Expect(err).To(HaveOccurred())
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.DatabaseError"))
+		})
+	})
+
+	Describe("Steps", func() {
+		It("should save each step start and finish", func() {
+			mock.
+				ExpectExec("^INSERT INTO operations(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			for i := 0; i < 4; i++ {
+				mock.
+					ExpectExec("^UPDATE operations(.+)$").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			}
+
+			operation, err := NewOperation(sqlxDB, OperationCreate, clusterName, username)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(operation.StartStep(StepNamespace)).To(Succeed())
+			Expect(operation.FinishStep(StepNamespace, nil)).To(Succeed())
+			Expect(operation.StartStep(StepVolumes)).To(Succeed())
+			Expect(operation.FinishStep(StepVolumes, fmt.Errorf("failed to create PVC"))).To(Succeed())
+
+			Expect(operation.Phase).To(Equal(StepVolumes))
+			Expect(operation.Steps).To(HaveLen(2))
+			Expect(operation.Steps[0].Status).To(Equal(OperationSucceeded))
+			Expect(operation.Steps[0].FinishedAt).NotTo(BeNil())
+			Expect(operation.Steps[1].Status).To(Equal(OperationFailed))
+			Expect(operation.Steps[1].Error).To(Equal("failed to create PVC"))
+		})
+
+		It("should save final error", func() {
+			mock.
+				ExpectExec("^INSERT INTO operations(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.
+				ExpectExec("^UPDATE operations(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			operation, err := NewOperation(sqlxDB, OperationCreate, clusterName, username)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(operation.Finish(fmt.Errorf("wait for deployment completion error due to timeout"))).To(Succeed())
+			Expect(operation.Status).To(Equal(OperationFailed))
+			Expect(operation.Error).To(Equal("wait for deployment completion error due to timeout"))
+			Expect(operation.FinishedAt).NotTo(BeNil())
+		})
+		It("should save error details", func() {
+			mock.
+				ExpectExec("^INSERT INTO operations(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.
+				ExpectExec("^UPDATE operations(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			operation, err := NewOperation(sqlxDB, OperationCreate, clusterName, username)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = errors.NewKubernetesErrorWithDetails(
+				"wait for deployment completion error",
+				fmt.Errorf("deployment app failed: pod app-1 is ImagePullBackOff"),
+				map[string]string{"deployment": "app"},
+			)
+			Expect(operation.Finish(err)).To(Succeed())
+			Expect(operation.Status).To(Equal(OperationFailed))
+			Expect(operation.Error).To(Equal("deployment app failed: pod app-1 is ImagePullBackOff"))
+			Expect(string(operation.ErrorDetails)).To(Equal(`{"deployment":"app"}`))
+			Expect(operation.ErrorDetailsJSON).To(Equal(`{"deployment":"app"}`))
+		})
+	})
+
+	Describe("LoadOperation", func() {
+		It("should load operation from DB", func() {
+			now := time.Now()
+			mock.
+				ExpectQuery("^SELECT (.+) FROM operations WHERE id = (.+)$").
+				WithArgs(operationID).
+				WillReturnRows(sqlmock.NewRows([]string{
+					"id", "kind", "cluster_name", "username", "status", "phase",
+					"steps", "error", "created_at", "updated_at", "finished_at",
+				}).AddRow(
+					operationID, OperationCreate, clusterName, username, OperationRunning, StepSetup,
+					`[{"name": "namespace", "status": "succeeded"}, {"name": "setup", "status": "running"}]`,
+					"", now, now, nil,
+				))
+
+			operation, err := LoadOperation(sqlxDB, operationID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(operation.ID).To(Equal(operationID))
+			Expect(operation.Phase).To(Equal(StepSetup))
+			Expect(operation.Steps).To(HaveLen(2))
+			Expect(operation.Steps[1].Name).To(Equal(StepSetup))
+			Expect(operation.FinishedAt).To(BeNil())
+		})
+
+		It("should return not found error for invalid id", func() {
+			_, err := LoadOperation(sqlxDB, "invalid")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("sql: no rows in result set"))
+		})
+	})
+})
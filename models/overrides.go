@@ -0,0 +1,160 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/topfreegames/mystack-controller/errors"
+)
+
+//envNameRegex matches the env var names accepted by kubernetes
+var envNameRegex = regexp.MustCompile("^[-._a-zA-Z][-._a-zA-Z0-9]*$")
+
+//AppOverride changes the image and the environment of a single app of the cluster config
+type AppOverride struct {
+	Image string            `json:"image,omitempty"`
+	Env   map[string]string `json:"env,omitempty"`
+}
+
+//Overrides are the changes requested on creation for each app or service, by name
+type Overrides map[string]*AppOverride
+
+//ParseOverrides reads the overrides recorded with a stack
+func ParseOverrides(str string) (Overrides, error) {
+	if len(str) == 0 {
+		return nil, nil
+	}
+
+	overrides := Overrides{}
+	err := json.Unmarshal([]byte(str), &overrides)
+	if err != nil {
+		return nil, errors.NewGenericError("parse overrides error", err)
+	}
+
+	return overrides, nil
+}
+
+//String returns the JSON saved on DB, empty when there are no overrides
+func (o Overrides) String() string {
+	if len(o) == 0 {
+		return ""
+	}
+
+	bts, _ := json.Marshal(o)
+	return string(bts)
+}
+
+//appConfig returns the config of the app or service called name
+func appConfig(clusterConfig *ClusterConfig, name string) *ClusterAppConfig {
+	if config, ok := clusterConfig.Apps[name]; ok {
+		return config
+	}
+	return clusterConfig.Services[name]
+}
+
+//Validate checks that every override changes something of an app on the cluster config
+//Images and env vars are rendered on the deployment templates, so values that could change the yaml are refused
+func (o Overrides) Validate(clusterConfig *ClusterConfig) error {
+	names := make([]string, 0, len(o))
+	for name := range o {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		override := o[name]
+		if appConfig(clusterConfig, name) == nil {
+			return errors.NewGenericError(
+				"invalid overrides",
+				fmt.Errorf("app '%s' is not on cluster config", name),
+			)
+		}
+
+		if override == nil || (len(override.Image) == 0 && len(override.Env) == 0) {
+			return errors.NewGenericError(
+				"invalid overrides",
+				fmt.Errorf("override of app '%s' must have image or env", name),
+			)
+		}
+
+		if unsafeValueRegex.MatchString(override.Image) {
+			return errors.NewGenericError(
+				"invalid overrides",
+				fmt.Errorf("override of app '%s' has characters not allowed on image: %q", name, override.Image),
+			)
+		}
+
+		envNames := make([]string, 0, len(override.Env))
+		for envName := range override.Env {
+			envNames = append(envNames, envName)
+		}
+		sort.Strings(envNames)
+
+		for _, envName := range envNames {
+			if len(envName) == 0 {
+				return errors.NewGenericError(
+					"invalid overrides",
+					fmt.Errorf("override of app '%s' has env var without name", name),
+				)
+			}
+			if !envNameRegex.MatchString(envName) {
+				return errors.NewGenericError(
+					"invalid overrides",
+					fmt.Errorf("override of app '%s' has invalid env var name: %q", name, envName),
+				)
+			}
+			if unsafeValueRegex.MatchString(override.Env[envName]) {
+				return errors.NewGenericError(
+					"invalid overrides",
+					fmt.Errorf("override of app '%s' has characters not allowed on env var '%s': %q", name, envName, override.Env[envName]),
+				)
+			}
+		}
+	}
+
+	return nil
+}
+
+//apply merges the overrides into the app configs
+//Env vars replace the ones with the same name and the others are added in name order
+func (o Overrides) apply(clusterConfig *ClusterConfig) {
+	for name, override := range o {
+		config := appConfig(clusterConfig, name)
+		if config == nil || override == nil {
+			continue
+		}
+
+		if len(override.Image) > 0 {
+			config.Image = override.Image
+		}
+
+		envNames := make([]string, 0, len(override.Env))
+		for envName := range override.Env {
+			envNames = append(envNames, envName)
+		}
+		sort.Strings(envNames)
+
+		for _, envName := range envNames {
+			value := override.Env[envName]
+			replaced := false
+			for _, env := range config.Environment {
+				if env.Name == envName {
+					env.Value = value
+					replaced = true
+				}
+			}
+			if !replaced {
+				config.Environment = append(config.Environment, &EnvVar{Name: envName, Value: value})
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models_test
+
+import (
+	. "github.com/topfreegames/mystack-controller/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Overrides", func() {
+	const yamlStr = `
+services:
+  postgres:
+    image: postgres:1.0
+    env:
+      - name: POSTGRES_USER
+        value: user
+apps:
+  app1:
+    image: app1:v1
+    env:
+      - name: DEBUG
+        value: "false"
+`
+
+	build := func(overrides Overrides) (*Cluster, error) {
+		stack := &Stack{
+			Username:    "user",
+			ClusterName: "stack",
+			Yaml:        yamlStr,
+			Overrides:   overrides,
+		}
+		return NewClusterFromStack(stack, nil, nil, config)
+	}
+
+	deployment := func(cluster *Cluster, name string) *Deployment {
+		deployment, err := cluster.Deployment(name)
+		Expect(err).NotTo(HaveOccurred())
+		return deployment
+	}
+
+	It("should override image of app", func() {
+		cluster, err := build(Overrides{"app1": {Image: "app1:feature"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deployment(cluster, "app1").Image).To(Equal("app1:feature"))
+		Expect(deployment(cluster, "postgres").Image).To(Equal("postgres:1.0"))
+	})
+
+	It("should replace and add env vars of service", func() {
+		cluster, err := build(Overrides{"postgres": {Env: map[string]string{
+			"POSTGRES_USER": "admin",
+			"POSTGRES_DB":   "feature",
+		}}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deployment(cluster, "postgres").Environment).To(Equal([]*EnvVar{
+			{Name: "POSTGRES_USER", Value: "admin"},
+			{Name: "POSTGRES_DB", Value: "feature"},
+		}))
+	})
+
+	It("should return error if app is not on cluster config", func() {
+		_, err := build(Overrides{"unknown": {Image: "app"}})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("app 'unknown' is not on cluster config"))
+	})
+
+	It("should return error if override is empty", func() {
+		_, err := build(Overrides{"app1": {}})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("override of app 'app1' must have image or env"))
+	})
+
+	It("should return error if image has a quote", func() {
+		_, err := build(Overrides{"app1": {Image: "app1:v1\"\n    command: [\"sh\"]"}})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("override of app 'app1' has characters not allowed on image"))
+	})
+
+	It("should return error if env var value has a newline", func() {
+		_, err := build(Overrides{"postgres": {Env: map[string]string{
+			"POSTGRES_USER": "admin\n  - name: POSTGRES_PASSWORD",
+		}}})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal(`override of app 'postgres' has characters not allowed on env var 'POSTGRES_USER': "admin\n  - name: POSTGRES_PASSWORD"`))
+	})
+
+	It("should return error if env var value has a quote", func() {
+		_, err := build(Overrides{"postgres": {Env: map[string]string{
+			"POSTGRES_USER": `admin"`,
+		}}})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("has characters not allowed on env var 'POSTGRES_USER'"))
+	})
+
+	It("should return error if env var name is invalid", func() {
+		_, err := build(Overrides{"postgres": {Env: map[string]string{
+			"POSTGRES_USER\n  value: x": "admin",
+		}}})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("override of app 'postgres' has invalid env var name"))
+	})
+
+	It("should keep overrides on cluster to be saved with stack", func() {
+		overrides := Overrides{"app1": {Image: "app1:feature"}}
+		cluster, err := build(overrides)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cluster.Overrides).To(Equal(overrides))
+		Expect(cluster.Overrides.String()).To(Equal(`{"app1":{"image":"app1:feature"}}`))
+	})
+})
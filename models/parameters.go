@@ -0,0 +1,277 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"text/template"
+
+	"github.com/topfreegames/mystack-controller/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+//Types of a parameter
+const (
+	ParameterString = "string"
+	ParameterInt    = "int"
+	ParameterBool   = "bool"
+)
+
+var parameterNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+//unsafeValueRegex matches string values that could change the structure of the yaml
+//they are rendered into: line breaks, comments, flow collections, quotes, anchors,
+//tags, block indicators, mapping keys and leading or trailing spaces
+var unsafeValueRegex = regexp.MustCompile("[\n\r\t#{}\\[\\],&*!|>'\"%@`\\\\]|:( |$)|^[-?]( |$)|^ | $")
+
+//Parameter is a variable of the cluster config, referenced as {{ .name }} anywhere on it
+//Parameters without default must have a value on cluster creation
+//String values can't have yaml syntax, so they can't change the structure of the config
+type Parameter struct {
+	Type        string      `yaml:"type"`
+	Default     interface{} `yaml:"default"`
+	Description string      `yaml:"description"`
+}
+
+//ParameterValues are the values of the parameters given on cluster creation, by name
+type ParameterValues map[string]string
+
+//UnmarshalJSON accepts strings, numbers and booleans as values
+func (p *ParameterValues) UnmarshalJSON(data []byte) error {
+	raw := map[string]interface{}{}
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	values := ParameterValues{}
+	for name, value := range raw {
+		switch v := value.(type) {
+		case string:
+			values[name] = v
+		case float64:
+			values[name] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			values[name] = strconv.FormatBool(v)
+		default:
+			return fmt.Errorf("parameter '%s' must be a string, number or boolean", name)
+		}
+	}
+
+	*p = values
+	return nil
+}
+
+//ParseParameterValues reads the parameter values recorded with a stack
+func ParseParameterValues(str string) (ParameterValues, error) {
+	if len(str) == 0 {
+		return nil, nil
+	}
+
+	values := ParameterValues{}
+	err := json.Unmarshal([]byte(str), &values)
+	if err != nil {
+		return nil, errors.NewGenericError("parse parameters error", err)
+	}
+
+	return values, nil
+}
+
+//String returns the JSON saved on DB, empty when there are no values
+func (p ParameterValues) String() string {
+	if len(p) == 0 {
+		return ""
+	}
+
+	bts, _ := json.Marshal(p)
+	return string(bts)
+}
+
+//typed converts value to the type of the parameter
+func (p *Parameter) typed(name, value string) (interface{}, error) {
+	switch p.Type {
+	case "", ParameterString:
+		if unsafeValueRegex.MatchString(value) {
+			return nil, fmt.Errorf("parameter '%s' has characters not allowed on values: %q", name, value)
+		}
+		return value, nil
+	case ParameterInt:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("parameter '%s' must be an int: %s", name, value)
+		}
+		return n, nil
+	case ParameterBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("parameter '%s' must be a bool: %s", name, value)
+		}
+		return b, nil
+	}
+
+	return nil, fmt.Errorf("parameter '%s' has invalid type '%s'", name, p.Type)
+}
+
+//zero is used in place of required parameters when validating the config
+func (p *Parameter) zero() interface{} {
+	switch p.Type {
+	case ParameterInt:
+		return 0
+	case ParameterBool:
+		return false
+	}
+	return ""
+}
+
+//validateParameters checks names, types and defaults of the declared parameters
+func validateParameters(parameters map[string]*Parameter) error {
+	for _, name := range parameterNames(parameters) {
+		parameter := parameters[name]
+		if !parameterNameRegex.MatchString(name) {
+			return errors.NewYamlError(
+				"invalid parameters",
+				fmt.Errorf("parameter name '%s' must have only letters, digits and underscores", name),
+			)
+		}
+		if parameter == nil {
+			return errors.NewYamlError("invalid parameters", fmt.Errorf("parameter '%s' is empty", name))
+		}
+		switch parameter.Type {
+		case "", ParameterString, ParameterInt, ParameterBool:
+		default:
+			return errors.NewYamlError(
+				"invalid parameters",
+				fmt.Errorf("parameter '%s' has invalid type '%s'", name, parameter.Type),
+			)
+		}
+		if parameter.Default != nil {
+			if _, err := parameter.typed(name, fmt.Sprint(parameter.Default)); err != nil {
+				return errors.NewYamlError("invalid parameters", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func parameterNames(parameters map[string]*Parameter) []string {
+	names := make([]string, 0, len(parameters))
+	for name := range parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//parseParameters reads the parameters section of a config that wasn't rendered yet
+//References are rendered as placeholders first, so they don't break the yaml
+//Invalid yaml is left for ParseYaml to report
+func parseParameters(yamlStr string) map[string]*Parameter {
+	raw := yamlStr
+	tmpl, err := template.New("config").Parse(yamlStr)
+	if err == nil {
+		var buf bytes.Buffer
+		if tmpl.Execute(&buf, map[string]interface{}{}) == nil {
+			raw = buf.String()
+		}
+	}
+
+	section := struct {
+		Parameters map[string]*Parameter `yaml:"parameters"`
+	}{}
+	if yaml.Unmarshal([]byte(raw), &section) != nil {
+		return nil
+	}
+
+	return section.Parameters
+}
+
+//resolve returns the typed value of each parameter, from values or its default
+//Without strict, required parameters get the zero value of their type
+func resolve(
+	parameters map[string]*Parameter,
+	values ParameterValues,
+	strict bool,
+) (map[string]interface{}, error) {
+	for name := range values {
+		if _, ok := parameters[name]; !ok {
+			return nil, errors.NewGenericError(
+				"invalid parameters",
+				fmt.Errorf("parameter '%s' is not declared on cluster config", name),
+			)
+		}
+	}
+
+	resolved := map[string]interface{}{}
+	for _, name := range parameterNames(parameters) {
+		parameter := parameters[name]
+		value, ok := values[name]
+		if !ok && parameter.Default != nil {
+			value, ok = fmt.Sprint(parameter.Default), true
+		}
+
+		if !ok && strict {
+			return nil, errors.NewGenericError(
+				"invalid parameters",
+				fmt.Errorf("parameter '%s' is required", name),
+			)
+		} else if !ok {
+			resolved[name] = parameter.zero()
+			continue
+		}
+
+		typed, err := parameter.typed(name, value)
+		if err != nil {
+			return nil, errors.NewGenericError("invalid parameters", err)
+		}
+		resolved[name] = typed
+	}
+
+	return resolved, nil
+}
+
+//RenderClusterConfig replaces the parameter references of the config yaml by their values
+//Configs without parameters are returned as they are
+func RenderClusterConfig(yamlStr string, values ParameterValues) (string, error) {
+	return render(yamlStr, values, true)
+}
+
+func render(yamlStr string, values ParameterValues, strict bool) (string, error) {
+	parameters := parseParameters(yamlStr)
+	if len(parameters) == 0 && len(values) == 0 {
+		return yamlStr, nil
+	}
+
+	err := validateParameters(parameters)
+	if err != nil {
+		return "", err
+	}
+
+	resolved, err := resolve(parameters, values, strict)
+	if err != nil {
+		return "", err
+	}
+
+	tmpl, err := template.New("config").Option("missingkey=error").Parse(yamlStr)
+	if err != nil {
+		return "", errors.NewYamlError("render cluster config error", err)
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, resolved)
+	if err != nil {
+		return "", errors.NewYamlError("render cluster config error", err)
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,126 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models_test
+
+import (
+	"encoding/json"
+	"fmt"
+
+	. "github.com/topfreegames/mystack-controller/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Parameters", func() {
+	const yamlStr = `
+parameters:
+  tag:
+    type: string
+    default: v1
+  replicas:
+    type: int
+    default: 1
+  debug:
+    type: bool
+apps:
+  app1:
+    image: app1:{{ .tag }}
+    port: 5000
+    replicas: {{ .replicas }}
+    env:
+      - name: DEBUG
+        value: "{{ .debug }}"
+`
+
+	build := func(values ParameterValues) (*Cluster, error) {
+		stack := &Stack{
+			Username:    "user",
+			ClusterName: "stack",
+			Yaml:        yamlStr,
+			Parameters:  values,
+		}
+		return NewClusterFromStack(stack, nil, nil, config)
+	}
+
+	It("should render config with values and defaults", func() {
+		cluster, err := build(ParameterValues{"debug": "true", "replicas": "3"})
+		Expect(err).NotTo(HaveOccurred())
+
+		deployment, err := cluster.Deployment("app1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deployment.Image).To(Equal("app1:v1"))
+		Expect(deployment.Replicas).To(Equal(3))
+		Expect(deployment.Environment).To(ContainElement(&EnvVar{Name: "DEBUG", Value: "true"}))
+		Expect(cluster.Parameters).To(HaveKeyWithValue("debug", "true"))
+	})
+
+	It("should return error if required parameter has no value", func() {
+		_, err := build(nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("parameter 'debug' is required"))
+		Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.GenericError"))
+	})
+
+	It("should return error if value doesn't match parameter type", func() {
+		_, err := build(ParameterValues{"debug": "true", "replicas": "many"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("parameter 'replicas' must be an int: many"))
+	})
+
+	It("should return error if value could change the yaml structure", func() {
+		_, err := build(ParameterValues{"debug": "true", "tag": "v1\n    privileged: true"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal(`parameter 'tag' has characters not allowed on values: "v1\n    privileged: true"`))
+		Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.GenericError"))
+
+		_, err = build(ParameterValues{"debug": "true", "tag": "v1 # comment"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should accept values with yaml characters that are safe on plain scalars", func() {
+		cluster, err := build(ParameterValues{"debug": "true", "tag": "1.2.3-beta_1"})
+		Expect(err).NotTo(HaveOccurred())
+
+		deployment, err := cluster.Deployment("app1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deployment.Image).To(Equal("app1:1.2.3-beta_1"))
+	})
+
+	It("should return error if parameter is not declared", func() {
+		_, err := build(ParameterValues{"debug": "true", "version": "v2"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("parameter 'version' is not declared on cluster config"))
+	})
+
+	It("should return error if parameter has invalid type", func() {
+		_, err := RenderClusterConfig(`
+parameters:
+  tag:
+    type: float
+`, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("parameter 'tag' has invalid type 'float'"))
+		Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.YamlError"))
+	})
+
+	It("should return config without parameters as it is", func() {
+		config := "apps:\n  app1:\n    image: app1\n"
+		rendered, err := RenderClusterConfig(config, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rendered).To(Equal(config))
+	})
+
+	It("should read numbers and booleans as values", func() {
+		values := ParameterValues{}
+		err := json.Unmarshal([]byte(`{"tag": "v2", "replicas": 3, "debug": true}`), &values)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values).To(Equal(ParameterValues{"tag": "v2", "replicas": "3", "debug": "true"}))
+	})
+})
@@ -15,6 +15,7 @@ import (
 	"github.com/topfreegames/mystack-controller/errors"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/pkg/api"
+	k8serrors "k8s.io/client-go/pkg/api/errors"
 	"k8s.io/client-go/pkg/api/v1"
 )
 
@@ -45,10 +46,10 @@ type PersistentVolumeClaim struct {
 }
 
 //NewPVC is the PersistentVolumeClaim constructor
-func NewPVC(name, username, storage string) *PersistentVolumeClaim {
+func NewPVC(name, namespace, storage string) *PersistentVolumeClaim {
 	return &PersistentVolumeClaim{
 		Name:      name,
-		Namespace: usernameToNamespace(username),
+		Namespace: namespace,
 		Storage:   storage,
 	}
 }
@@ -60,6 +61,63 @@ func (p *PersistentVolumeClaim) Start(clientset kubernetes.Interface) (*v1.Persi
 		return nil, errors.NewKubernetesError("create namespace error", err)
 	}
 
+	dst, err := p.build()
+	if err != nil {
+		return nil, err
+	}
+
+	pvc, err := clientset.CoreV1().PersistentVolumeClaims(p.Namespace).Create(dst)
+	if err != nil {
+		return nil, errors.NewKubernetesError("create deployment error", err)
+	}
+
+	return pvc, nil
+}
+
+//Apply creates the PVC if it doesn't exist
+//A claim spec can't be changed after it is bound, so a running PVC
+//built from a different config is kept untouched and reported as skipped
+func (p *PersistentVolumeClaim) Apply(clientset kubernetes.Interface) (string, error) {
+	existing, err := clientset.CoreV1().PersistentVolumeClaims(p.Namespace).Get(p.Name)
+	if k8serrors.IsNotFound(err) {
+		_, err = p.Start(clientset)
+		if err != nil {
+			return "", err
+		}
+		return ChangeCreated, nil
+	}
+	if err != nil {
+		return "", errors.NewKubernetesError("update pvc error", err)
+	}
+
+	dst, err := p.build()
+	if err != nil {
+		return "", err
+	}
+
+	if configHash(existing.ObjectMeta) == configHash(dst.ObjectMeta) {
+		return ChangeUnchanged, nil
+	}
+
+	return ChangeSkipped, nil
+}
+
+//Ensure creates the PVC if it doesn't exist
+//An existing one is kept if it was built from the same config
+func (p *PersistentVolumeClaim) Ensure(clientset kubernetes.Interface) (string, error) {
+	change, err := p.Apply(clientset)
+	if err != nil {
+		return "", err
+	}
+
+	if change == ChangeSkipped {
+		return "", differentConfigError("pvc", p.Name)
+	}
+
+	return change, nil
+}
+
+func (p *PersistentVolumeClaim) build() (*v1.PersistentVolumeClaim, error) {
 	tmpl, err := template.New("pvc").Parse(persistentVolumeClaimYaml)
 	if err != nil {
 		return nil, errors.NewYamlError("parse yaml error", err)
@@ -85,12 +143,9 @@ func (p *PersistentVolumeClaim) Start(clientset kubernetes.Interface) (*v1.Persi
 		return nil, errors.NewYamlError("parse yaml error", err)
 	}
 
-	pvc, err := clientset.CoreV1().PersistentVolumeClaims(p.Namespace).Create(dst)
-	if err != nil {
-		return nil, errors.NewKubernetesError("create deployment error", err)
-	}
+	setConfigHash(&dst.ObjectMeta, buf.Bytes())
 
-	return pvc, nil
+	return dst, nil
 }
 
 //Delete deletes persistent volume cluster
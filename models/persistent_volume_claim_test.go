@@ -24,9 +24,10 @@ var _ = Describe("PersistentVolumeClaim", func() {
 		clientset   *fake.Clientset
 		name        = "pvc"
 		username    = "user"
-		namespace   = "mystack-user"
+		namespace   = "mystack-user-stack-7f46a74a4d"
+		stack       = "stack"
 		capacity    = "2Gi"
-		pvc         = NewPVC(name, username, capacity)
+		pvc         = NewPVC(name, namespace, capacity)
 		labelMap    = labels.Set{"mystack/routable": "true"}
 		listOptions = v1.ListOptions{
 			LabelSelector: labelMap.AsSelector().String(),
@@ -40,7 +41,7 @@ var _ = Describe("PersistentVolumeClaim", func() {
 
 	Describe("Start", func() {
 		It("should start pvc correctly", func() {
-			err := CreateNamespace(clientset, username)
+			err := CreateNamespace(clientset, username, stack)
 			Expect(err).NotTo(HaveOccurred())
 
 			k8sPVC, err := pvc.Start(clientset)
@@ -61,7 +62,7 @@ var _ = Describe("PersistentVolumeClaim", func() {
 
 	Describe("Start", func() {
 		It("should delete pvc correctly", func() {
-			err := CreateNamespace(clientset, username)
+			err := CreateNamespace(clientset, username, stack)
 			Expect(err).NotTo(HaveOccurred())
 
 			_, err = pvc.Start(clientset)
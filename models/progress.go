@@ -0,0 +1,268 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	//ProgressStarted is the status of a step or app that has started
+	ProgressStarted = "started"
+	//ProgressSucceeded is the status of a step or app that finished without errors
+	ProgressSucceeded = "succeeded"
+	//ProgressFailed is the status of a step or app that finished with an error
+	ProgressFailed = "failed"
+
+	progressBuffer = 100
+	//progressRetention is how long the events of a finished operation are kept
+	progressRetention = 5 * time.Minute
+)
+
+//ProgressEvent is a change on a cluster while it is created or deleted
+//Elapsed is the number of seconds since the operation started
+//The last event of an operation has Done set
+type ProgressEvent struct {
+	ID        int64   `json:"id"`
+	Operation string  `json:"operation"`
+	Step      string  `json:"step,omitempty"`
+	App       string  `json:"app,omitempty"`
+	Status    string  `json:"status"`
+	Elapsed   float64 `json:"elapsed"`
+	Error     string  `json:"error,omitempty"`
+	Done      bool    `json:"done,omitempty"`
+}
+
+type progressStream struct {
+	running bool
+	events  []*ProgressEvent
+}
+
+//ProgressHub sends the progress of the operations on each namespace to its subscribers
+//It keeps the events of the last operation, so subscribers that arrive late can catch up,
+//until some minutes after the operation is done
+//Event ids increase across all namespaces, so they are never reused
+type ProgressHub struct {
+	mutex       sync.Mutex
+	lastID      int64
+	streams     map[string]*progressStream
+	subscribers map[string]map[chan *ProgressEvent]bool
+}
+
+//NewProgressHub is the ProgressHub ctor
+func NewProgressHub() *ProgressHub {
+	return &ProgressHub{
+		streams:     map[string]*progressStream{},
+		subscribers: map[string]map[chan *ProgressEvent]bool{},
+	}
+}
+
+//Start begins the progress of an operation on namespace
+//Events of the previous operation are discarded
+func (h *ProgressHub) Start(namespace, operation string) *Progress {
+	if h == nil {
+		return nil
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.streams[namespace] = &progressStream{running: true, events: []*ProgressEvent{}}
+
+	return &Progress{
+		hub:       h,
+		namespace: namespace,
+		operation: operation,
+		start:     time.Now(),
+	}
+}
+
+//Subscribe returns the past events the subscriber missed and a channel with the next ones
+//With lastID zero, those are the events of the running operation, if any
+//Otherwise they are the events after lastID
+//The channel is closed if the subscriber doesn't keep up, the events it lost are
+//returned by Missed
+func (h *ProgressHub) Subscribe(namespace string, lastID int64) ([]*ProgressEvent, chan *ProgressEvent) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	events := make(chan *ProgressEvent, progressBuffer)
+	if _, ok := h.subscribers[namespace]; !ok {
+		h.subscribers[namespace] = map[chan *ProgressEvent]bool{}
+	}
+	h.subscribers[namespace][events] = true
+
+	return h.missed(namespace, lastID), events
+}
+
+//Missed returns the events of the last operation on namespace after lastID
+func (h *ProgressHub) Missed(namespace string, lastID int64) []*ProgressEvent {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.missed(namespace, lastID)
+}
+
+func (h *ProgressHub) missed(namespace string, lastID int64) []*ProgressEvent {
+	missed := []*ProgressEvent{}
+	stream, ok := h.streams[namespace]
+	if !ok {
+		return missed
+	}
+
+	for _, event := range stream.events {
+		if (lastID == 0 && stream.running) || (lastID > 0 && event.ID > lastID) {
+			missed = append(missed, event)
+		}
+	}
+
+	return missed
+}
+
+//Unsubscribe stops sending events to the channel
+func (h *ProgressHub) Unsubscribe(namespace string, events chan *ProgressEvent) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.unsubscribe(namespace, events)
+}
+
+func (h *ProgressHub) unsubscribe(namespace string, events chan *ProgressEvent) {
+	subscribers := h.subscribers[namespace]
+	delete(subscribers, events)
+	if len(subscribers) == 0 {
+		delete(h.subscribers, namespace)
+	}
+}
+
+//publish sends the event to the subscribers of namespace
+//Subscribers that don't keep up are unsubscribed and their channel is closed
+//instead of blocking the operation, so they can't miss the last event unnoticed
+func (h *ProgressHub) publish(namespace string, event *ProgressEvent) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.lastID++
+	event.ID = h.lastID
+
+	stream, ok := h.streams[namespace]
+	if ok {
+		stream.events = append(stream.events, event)
+		if event.Done {
+			stream.running = false
+			time.AfterFunc(progressRetention, func() {
+				h.evict(namespace, stream)
+			})
+		}
+	}
+
+	for subscriber := range h.subscribers[namespace] {
+		select {
+		case subscriber <- event:
+		default:
+			h.unsubscribe(namespace, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+//evict discards the events of a finished operation, unless another one started since
+func (h *ProgressHub) evict(namespace string, stream *progressStream) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.streams[namespace] == stream {
+		delete(h.streams, namespace)
+	}
+}
+
+//Progress publishes the steps of a single operation
+//A nil Progress publishes nothing
+type Progress struct {
+	hub       *ProgressHub
+	namespace string
+	operation string
+	start     time.Time
+
+	mutex sync.Mutex
+	step  string
+}
+
+func (p *Progress) publish(app, status string, err error, done bool) {
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	step := p.step
+	p.mutex.Unlock()
+
+	event := &ProgressEvent{
+		Operation: p.operation,
+		Step:      step,
+		App:       app,
+		Status:    status,
+		Elapsed:   time.Now().Sub(p.start).Seconds(),
+		Done:      done,
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	p.hub.publish(p.namespace, event)
+}
+
+//StartStep publishes that step has started
+//Apps started after it are reported as part of it
+func (p *Progress) StartStep(step string) {
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	p.step = step
+	p.mutex.Unlock()
+
+	p.publish("", ProgressStarted, nil, false)
+}
+
+//FinishStep publishes that the current step has finished
+func (p *Progress) FinishStep(err error) {
+	p.publish("", finishStatus(err), err, false)
+}
+
+//StartApp publishes that app has started on the current step
+func (p *Progress) StartApp(app string) {
+	p.publish(app, ProgressStarted, nil, false)
+}
+
+//FinishApp publishes that app has finished on the current step
+func (p *Progress) FinishApp(app string, err error) {
+	p.publish(app, finishStatus(err), err, false)
+}
+
+//Done publishes the last event of the operation
+func (p *Progress) Done(err error) {
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	p.step = ""
+	p.mutex.Unlock()
+
+	p.publish("", finishStatus(err), err, true)
+}
+
+func finishStatus(err error) string {
+	if err != nil {
+		return ProgressFailed
+	}
+	return ProgressSucceeded
+}
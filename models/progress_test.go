@@ -0,0 +1,144 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models_test
+
+import (
+	"fmt"
+
+	. "github.com/topfreegames/mystack-controller/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	mTest "github.com/topfreegames/mystack-controller/testing"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Progress", func() {
+	var (
+		hub       *ProgressHub
+		namespace = "mystack-user-stack-7f46a74a4d"
+	)
+
+	BeforeEach(func() {
+		hub = NewProgressHub()
+	})
+
+	It("should send events to subscribers", func() {
+		progress := hub.Start(namespace, OperationCreate)
+		_, events := hub.Subscribe(namespace, 0)
+
+		progress.StartStep(StepSetup)
+		progress.FinishStep(fmt.Errorf("failed to run stup job"))
+
+		event := <-events
+		Expect(event.ID).To(BeEquivalentTo(1))
+		Expect(event.Step).To(Equal(StepSetup))
+		Expect(event.Status).To(Equal(ProgressStarted))
+
+		event = <-events
+		Expect(event.Status).To(Equal(ProgressFailed))
+		Expect(event.Error).To(Equal("failed to run stup job"))
+	})
+
+	It("should replay events only while the operation is running", func() {
+		progress := hub.Start(namespace, OperationCreate)
+		progress.StartStep(StepNamespace)
+
+		missed, _ := hub.Subscribe(namespace, 0)
+		Expect(missed).To(HaveLen(1))
+
+		progress.Done(nil)
+		missed, _ = hub.Subscribe(namespace, 0)
+		Expect(missed).To(BeEmpty())
+
+		missed, _ = hub.Subscribe(namespace, 1)
+		Expect(missed).To(HaveLen(1))
+		Expect(missed[0].Done).To(BeTrue())
+	})
+
+	It("should not send events after unsubscribe", func() {
+		progress := hub.Start(namespace, OperationDelete)
+		_, events := hub.Subscribe(namespace, 0)
+		hub.Unsubscribe(namespace, events)
+
+		progress.Done(nil)
+		Expect(events).To(BeEmpty())
+	})
+
+	It("should close the channel of subscribers that don't keep up", func() {
+		_, events := hub.Subscribe(namespace, 0)
+		progress := hub.Start(namespace, OperationCreate)
+		for i := 0; i <= 100; i++ {
+			progress.StartApp(fmt.Sprintf("app%d", i))
+		}
+		progress.Done(nil)
+
+		received := 0
+		for range events {
+			received++
+		}
+		Expect(received).To(Equal(100))
+
+		missed := hub.Missed(namespace, 100)
+		Expect(missed).To(HaveLen(2))
+		Expect(missed[1].Done).To(BeTrue())
+	})
+
+	It("should keep event ids increasing across operations", func() {
+		progress := hub.Start(namespace, OperationCreate)
+		progress.Done(nil)
+
+		progress = hub.Start(namespace, OperationDelete)
+		progress.Done(nil)
+
+		missed := hub.Missed(namespace, 1)
+		Expect(missed).To(HaveLen(1))
+		Expect(missed[0].ID).To(BeEquivalentTo(2))
+		Expect(missed[0].Operation).To(Equal(OperationDelete))
+	})
+
+	It("should do nothing when progress is nil", func() {
+		var progress *Progress
+		Expect(func() {
+			progress.StartStep(StepNamespace)
+			progress.StartApp("app")
+			progress.Done(nil)
+		}).NotTo(Panic())
+	})
+
+	It("should publish steps and apps of cluster creation", func() {
+		clientset := fake.NewSimpleClientset()
+		deployment := NewDeployment("app", "user", namespace, "image", []int{5000}, nil, nil, nil, nil, nil, config)
+		cluster := &Cluster{
+			Username:       "user",
+			ClusterName:    "stack",
+			Namespace:      namespace,
+			AppDeployments: []*Deployment{deployment},
+			K8sServices: map[*Deployment]*Service{
+				deployment: NewService("app", namespace, []*PortMap{{Port: 5000, TargetPort: 5000}}, false, false),
+			},
+			DeploymentReadiness: &mTest.MockReadiness{},
+			JobReadiness:        &mTest.MockReadiness{},
+			Progress:            hub.Start(namespace, OperationCreate),
+		}
+
+		err := cluster.Create(nil, clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		missed, _ := hub.Subscribe(namespace, 0)
+		apps := []string{}
+		for _, event := range missed {
+			if event.App != "" {
+				Expect(event.Step).To(Equal(StepAppDeployments))
+				apps = append(apps, fmt.Sprintf("%s %s", event.App, event.Status))
+			}
+		}
+		Expect(apps).To(Equal([]string{"app started", "app succeeded"}))
+	})
+})
@@ -0,0 +1,39 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"github.com/topfreegames/mystack-controller/errors"
+)
+
+const (
+	//PollReadiness gets the resources periodically until they are ready
+	PollReadiness = "poll"
+	//WatchReadiness watches the resources until they are ready
+	WatchReadiness = "watch"
+)
+
+//NewReadiness returns the deployment and job readiness
+//chosen on kubernetes.readiness config, poll by default
+func NewReadiness(config *viper.Viper) (Readiness, Readiness, error) {
+	strategy := config.GetString("kubernetes.readiness")
+
+	switch strategy {
+	case "", PollReadiness:
+		return &DeploymentReadiness{}, &JobReadiness{}, nil
+	case WatchReadiness:
+		return &DeploymentWatchReadiness{}, &JobWatchReadiness{}, nil
+	}
+
+	return nil, nil, errors.NewGenericError(
+		"readiness error",
+		fmt.Errorf("invalid readiness '%s', use %s or %s", strategy, PollReadiness, WatchReadiness),
+	)
+}
@@ -0,0 +1,45 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models_test
+
+import (
+	"github.com/spf13/viper"
+	. "github.com/topfreegames/mystack-controller/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Readiness", func() {
+	Describe("NewReadiness", func() {
+		It("should poll by default", func() {
+			deploymentReadiness, jobReadiness, err := NewReadiness(viper.New())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deploymentReadiness).To(BeAssignableToTypeOf(&DeploymentReadiness{}))
+			Expect(jobReadiness).To(BeAssignableToTypeOf(&JobReadiness{}))
+		})
+
+		It("should watch if configured", func() {
+			config := viper.New()
+			config.Set("kubernetes.readiness", "watch")
+			deploymentReadiness, jobReadiness, err := NewReadiness(config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deploymentReadiness).To(BeAssignableToTypeOf(&DeploymentWatchReadiness{}))
+			Expect(jobReadiness).To(BeAssignableToTypeOf(&JobWatchReadiness{}))
+		})
+
+		It("should return error if readiness is invalid", func() {
+			config := viper.New()
+			config.Set("kubernetes.readiness", "invalid")
+			_, _, err := NewReadiness(config)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("invalid readiness 'invalid', use poll or watch"))
+		})
+	})
+})
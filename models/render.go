@@ -0,0 +1,94 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/ghodss/yaml"
+	"github.com/topfreegames/mystack-controller/errors"
+)
+
+//Render returns the Kubernetes objects Create submits, as a multi-document yaml
+//Objects come in the order of the creation steps, deployments of the same
+//link layer sorted by name and each one followed by its service
+func (c *Cluster) Render() ([]byte, error) {
+	objects := []interface{}{}
+
+	namespace := newNamespace(c.Username, c.ClusterName)
+	namespace.Kind, namespace.APIVersion = "Namespace", "v1"
+	objects = append(objects, namespace)
+
+	for _, pvc := range c.PersistentVolumeClaims {
+		dst, err := pvc.build()
+		if err != nil {
+			return nil, err
+		}
+		dst.Kind, dst.APIVersion = "PersistentVolumeClaim", "v1"
+		objects = append(objects, dst)
+	}
+
+	for _, step := range []struct {
+		deployments []*Deployment
+		job         *Job
+	}{{c.SvcDeployments, c.Job}, {c.AppDeployments, c.PostJob}} {
+		deploymentObjects, err := c.renderDeployments(step.deployments)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, deploymentObjects...)
+
+		if step.job == nil {
+			continue
+		}
+		dst, err := step.job.build()
+		if err != nil {
+			return nil, err
+		}
+		dst.Kind, dst.APIVersion = "Job", "batch/v1"
+		objects = append(objects, dst)
+	}
+
+	buf := new(bytes.Buffer)
+	for _, object := range objects {
+		bts, err := yaml.Marshal(object)
+		if err != nil {
+			return nil, errors.NewYamlError("render cluster error", err)
+		}
+		buf.WriteString("---\n")
+		buf.Write(bts)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (c *Cluster) renderDeployments(deployments []*Deployment) ([]interface{}, error) {
+	objects := []interface{}{}
+	for _, layer := range linkLayers(deployments) {
+		sort.Slice(layer, func(i, j int) bool { return layer[i].Name < layer[j].Name })
+
+		for _, deployment := range layer {
+			dst, err := deployment.build()
+			if err != nil {
+				return nil, err
+			}
+			dst.Kind, dst.APIVersion = "Deployment", "extensions/v1beta1"
+			objects = append(objects, dst)
+
+			service, err := c.K8sServices[deployment].build()
+			if err != nil {
+				return nil, err
+			}
+			service.Kind, service.APIVersion = "Service", "v1"
+			objects = append(objects, service)
+		}
+	}
+
+	return objects, nil
+}
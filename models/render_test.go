@@ -0,0 +1,122 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models_test
+
+import (
+	"strings"
+
+	. "github.com/topfreegames/mystack-controller/models"
+
+	"github.com/ghodss/yaml"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Render", func() {
+	var renderYaml = `
+volumes:
+  - name: postgres-volume
+    storage: 1Gi
+services:
+  postgres:
+    image: postgres:1.0
+    ports:
+      - 8585:5432
+    volumeMount:
+      name: postgres-volume
+      mountPath: /var/lib/postgresql/data
+setup:
+  image: setup-img
+apps:
+  app2:
+    image: app2
+    ports:
+      - 5000
+    links:
+      - app1
+  app1:
+    image: app1
+    ports:
+      - 5000:5001
+    resources:
+      limits:
+        cpu: 20m
+`
+
+	render := func(yamlStr string) []map[string]interface{} {
+		cluster, err := NewClusterFromYaml(yamlStr, "user", "myCustomApps", nil, nil, config)
+		Expect(err).NotTo(HaveOccurred())
+
+		manifests, err := cluster.Render()
+		Expect(err).NotTo(HaveOccurred())
+
+		objects := []map[string]interface{}{}
+		for _, document := range strings.Split(string(manifests), "---\n")[1:] {
+			object := map[string]interface{}{}
+			err := yaml.Unmarshal([]byte(document), &object)
+			Expect(err).NotTo(HaveOccurred())
+			objects = append(objects, object)
+		}
+		return objects
+	}
+
+	name := func(object map[string]interface{}) string {
+		return object["kind"].(string) + "/" + object["metadata"].(map[string]interface{})["name"].(string)
+	}
+
+	It("should render objects in the order they are created", func() {
+		objects := render(renderYaml)
+
+		names := make([]string, len(objects))
+		for i, object := range objects {
+			names[i] = name(object)
+		}
+		Expect(names).To(Equal([]string{
+			"Namespace/mystack-user-mycustomapps-a62c7ed6aa",
+			"PersistentVolumeClaim/postgres-volume",
+			"Deployment/postgres",
+			"Service/postgres",
+			"Job/setup",
+			"Deployment/app1",
+			"Service/app1",
+			"Deployment/app2",
+			"Service/app2",
+		}))
+
+		for _, object := range objects[1:] {
+			metadata := object["metadata"].(map[string]interface{})
+			Expect(metadata["namespace"]).To(Equal("mystack-user-mycustomapps-a62c7ed6aa"))
+		}
+	})
+
+	It("should render deployments with default resources from config", func() {
+		objects := render(renderYaml)
+
+		deployment := objects[5]
+		Expect(name(deployment)).To(Equal("Deployment/app1"))
+		Expect(deployment["apiVersion"]).To(Equal("extensions/v1beta1"))
+
+		spec := deployment["spec"].(map[string]interface{})
+		template := spec["template"].(map[string]interface{})["spec"].(map[string]interface{})
+		container := template["containers"].([]interface{})[0].(map[string]interface{})
+		Expect(container["image"]).To(Equal("app1"))
+		Expect(container["resources"]).To(Equal(map[string]interface{}{
+			"limits":   map[string]interface{}{"cpu": "20m", "memory": "300Mi"},
+			"requests": map[string]interface{}{"cpu": "5m", "memory": "100Mi"},
+		}))
+	})
+
+	It("should render only namespace for empty cluster", func() {
+		objects := render("apps: {}\n")
+
+		Expect(objects).To(HaveLen(1))
+		Expect(objects[0]["kind"]).To(Equal("Namespace"))
+		Expect(objects[0]["apiVersion"]).To(Equal("v1"))
+	})
+})
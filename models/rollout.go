@@ -0,0 +1,175 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"fmt"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/topfreegames/mystack-controller/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+type rolloutResult struct {
+	deployment *Deployment
+	err        error
+}
+
+//rollout starts the deployments and their services following the link graph
+//Every deployment starts as soon as its own links are ready, with at most
+//c.RolloutWorkers of them starting at the same time
+//It returns on the first error, deployments already starting are left to the rollback
+func (c *Cluster) rollout(
+	logger logrus.FieldLogger,
+	clientset kubernetes.Interface,
+	deployments []*Deployment,
+) error {
+	if len(deployments) == 0 {
+		return nil
+	}
+
+	pendingLinks, dependents := linkGraph(deployments)
+
+	workers := c.RolloutWorkers
+	if workers <= 0 || workers > len(deployments) {
+		workers = len(deployments)
+	}
+
+	queue := make(chan *Deployment, len(deployments))
+	results := make(chan *rolloutResult, len(deployments))
+	done := make(chan struct{})
+	defer close(queue)
+	defer close(done)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for deployment := range queue {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				err := c.startDeploymentAndItsService(logger, clientset, deployment)
+				results <- &rolloutResult{deployment: deployment, err: err}
+			}
+		}()
+	}
+
+	running, started := 0, 0
+	for _, deployment := range deployments {
+		if pendingLinks[deployment] == 0 {
+			queue <- deployment
+			running++
+		}
+	}
+
+	for running > 0 {
+		result := <-results
+		running--
+		started++
+
+		if result.err != nil {
+			return result.err
+		}
+
+		for _, dependent := range dependents[result.deployment] {
+			pendingLinks[dependent]--
+			if pendingLinks[dependent] == 0 {
+				queue <- dependent
+				running++
+			}
+		}
+	}
+
+	if started < len(deployments) {
+		return errors.NewGenericError(
+			"create cluster error",
+			fmt.Errorf("links between deployments form a cycle"),
+		)
+	}
+
+	return nil
+}
+
+//linkGraph returns how many links of each deployment are not ready yet
+//and which deployments are waiting for each one
+//Links outside deployments are considered ready
+func linkGraph(deployments []*Deployment) (map[*Deployment]int, map[*Deployment][]*Deployment) {
+	inGraph := make(map[*Deployment]bool)
+	for _, deployment := range deployments {
+		inGraph[deployment] = true
+	}
+
+	pendingLinks := make(map[*Deployment]int)
+	dependents := make(map[*Deployment][]*Deployment)
+	for _, deployment := range deployments {
+		seen := make(map[*Deployment]bool)
+		for _, link := range deployment.Links {
+			if !inGraph[link] || seen[link] {
+				continue
+			}
+			seen[link] = true
+
+			pendingLinks[deployment]++
+			dependents[link] = append(dependents[link], deployment)
+		}
+	}
+
+	return pendingLinks, dependents
+}
+
+func (c *Cluster) startDeploymentAndItsService(
+	logger logrus.FieldLogger,
+	clientset kubernetes.Interface,
+	deployment *Deployment,
+) error {
+	c.Progress.StartApp(deployment.Name)
+	err := c.ensureDeploymentAndItsService(logger, clientset, deployment)
+	c.Progress.FinishApp(deployment.Name, err)
+
+	return err
+}
+
+func (c *Cluster) ensureDeploymentAndItsService(
+	logger logrus.FieldLogger,
+	clientset kubernetes.Interface,
+	deployment *Deployment,
+) error {
+	l := logger
+	if logger != nil {
+		l = logger.WithField("deployment", deployment.Name)
+	}
+
+	log(l, "creating deployment")
+	_, err := deployment.Ensure(clientset)
+	if err != nil {
+		if l != nil {
+			l.WithError(err).Error("failed to create deployment")
+		}
+		return err
+	}
+
+	log(l, "waiting deployment completion")
+	err = c.DeploymentReadiness.WaitForCompletion(clientset, []*Deployment{deployment})
+	if err != nil {
+		return err
+	}
+
+	log(l, "creating service")
+	_, err = c.K8sServices[deployment].Ensure(clientset)
+	if err != nil {
+		if l != nil {
+			l.WithError(err).Error("failed to create service")
+		}
+		return err
+	}
+	log(l, "done creating deployment and service")
+
+	return nil
+}
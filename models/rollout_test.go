@@ -0,0 +1,144 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models_test
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/topfreegames/mystack-controller/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	mTest "github.com/topfreegames/mystack-controller/testing"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+//recordReadiness records the order deployments got ready
+type recordReadiness struct {
+	mutex   sync.Mutex
+	ready   []string
+	running int
+	maxRun  int
+	fail    string
+}
+
+func (r *recordReadiness) WaitForCompletion(clientset kubernetes.Interface, d interface{}) error {
+	deployment := d.([]*Deployment)[0]
+
+	r.mutex.Lock()
+	r.running++
+	if r.running > r.maxRun {
+		r.maxRun = r.running
+	}
+	r.mutex.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.running--
+	if deployment.Name == r.fail {
+		return fmt.Errorf("deployment %s failed", deployment.Name)
+	}
+	r.ready = append(r.ready, deployment.Name)
+	return nil
+}
+
+func (r *recordReadiness) index(name string) int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i, ready := range r.ready {
+		if ready == name {
+			return i
+		}
+	}
+	return -1
+}
+
+var _ = Describe("Rollout", func() {
+	var (
+		clientset   *fake.Clientset
+		readiness   *recordReadiness
+		username    = "user"
+		clusterName = "rollout"
+		namespace   = "mystack-user-rollout"
+	)
+
+	//app1 and app2 have no links, app3 waits app1 and app4 waits app3
+	buildCluster := func(workers int) *Cluster {
+		deployments := map[string]*Deployment{}
+		for _, name := range []string{"app1", "app2", "app3", "app4"} {
+			deployments[name] = NewDeployment(name, username, namespace, name, []int{5000}, nil, nil, nil, nil, nil, config)
+		}
+		deployments["app3"].Links = []*Deployment{deployments["app1"]}
+		deployments["app4"].Links = []*Deployment{deployments["app3"]}
+
+		services := map[*Deployment]*Service{}
+		for name, deployment := range deployments {
+			services[deployment] = NewService(name, namespace, []*PortMap{&PortMap{Port: 5000, TargetPort: 5000}}, false, false)
+		}
+
+		return &Cluster{
+			Username:    username,
+			ClusterName: clusterName,
+			Namespace:   namespace,
+			AppDeployments: []*Deployment{
+				deployments["app4"], deployments["app3"], deployments["app2"], deployments["app1"],
+			},
+			K8sServices:         services,
+			DeploymentReadiness: readiness,
+			JobReadiness:        &mTest.MockReadiness{},
+			RolloutWorkers:      workers,
+		}
+	}
+
+	BeforeEach(func() {
+		clientset = fake.NewSimpleClientset()
+		readiness = &recordReadiness{}
+	})
+
+	It("should start each deployment after its links are ready", func() {
+		cluster := buildCluster(0)
+		err := cluster.Create(nil, clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(readiness.ready).To(HaveLen(4))
+		Expect(readiness.index("app1")).To(BeNumerically("<", readiness.index("app3")))
+		Expect(readiness.index("app3")).To(BeNumerically("<", readiness.index("app4")))
+		Expect(readiness.maxRun).To(Equal(2))
+
+		services, err := clientset.CoreV1().Services(namespace).List(v1.ListOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(services.Items).To(HaveLen(4))
+	})
+
+	It("should limit deployments starting at the same time", func() {
+		cluster := buildCluster(1)
+		err := cluster.Create(nil, clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(readiness.ready).To(HaveLen(4))
+		Expect(readiness.maxRun).To(Equal(1))
+	})
+
+	It("should rollback if a deployment fails", func() {
+		readiness.fail = "app3"
+		cluster := buildCluster(0)
+		err := cluster.Create(nil, clientset)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("deployment app3 failed"))
+
+		Expect(readiness.index("app4")).To(Equal(-1))
+		Expect(NamespaceExists(clientset, namespace)).To(BeFalse())
+	})
+})
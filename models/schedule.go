@@ -0,0 +1,111 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/topfreegames/mystack-controller/errors"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+//Schedule defines when a cluster must be awake
+//Outside of it the cluster sleeps, e.g. weekdays from 08:00 to 20:00
+type Schedule struct {
+	Days     []string `yaml:"days"`
+	From     string   `yaml:"from"`
+	To       string   `yaml:"to"`
+	Timezone string   `yaml:"timezone"`
+}
+
+//Validate returns error if the schedule can't be evaluated
+func (s *Schedule) Validate() error {
+	_, err := s.Awake(time.Now())
+	return err
+}
+
+//Awake returns true if the cluster must be awake at t
+//No days means every day
+func (s *Schedule) Awake(t time.Time) (bool, error) {
+	location, err := s.location()
+	if err != nil {
+		return false, err
+	}
+
+	from, err := minuteOfDay(s.From)
+	if err != nil {
+		return false, err
+	}
+
+	to, err := minuteOfDay(s.To)
+	if err != nil {
+		return false, err
+	}
+
+	if from >= to {
+		return false, errors.NewYamlError(
+			"invalid schedule",
+			fmt.Errorf("schedule must start before it ends: %s-%s", s.From, s.To),
+		)
+	}
+
+	t = t.In(location)
+	onDay := len(s.Days) == 0
+	for _, day := range s.Days {
+		weekday, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return false, errors.NewYamlError(
+				"invalid schedule",
+				fmt.Errorf("invalid schedule day: %s", day),
+			)
+		}
+		if weekday == t.Weekday() {
+			onDay = true
+		}
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	return onDay && minute >= from && minute < to, nil
+}
+
+func (s *Schedule) location() (*time.Location, error) {
+	if len(s.Timezone) == 0 {
+		return time.UTC, nil
+	}
+
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, errors.NewYamlError("invalid schedule", err)
+	}
+
+	return location, nil
+}
+
+//minuteOfDay converts HH:MM to minutes after midnight
+func minuteOfDay(clock string) (int, error) {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, errors.NewYamlError(
+			"invalid schedule",
+			fmt.Errorf("invalid schedule time, use HH:MM: %s", clock),
+		)
+	}
+
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
@@ -0,0 +1,80 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models_test
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/topfreegames/mystack-controller/models"
+)
+
+var _ = Describe("Schedule", func() {
+	var (
+		monday9am   = time.Date(2017, 5, 8, 9, 0, 0, 0, time.UTC)
+		monday10pm  = time.Date(2017, 5, 8, 22, 0, 0, 0, time.UTC)
+		saturday9am = time.Date(2017, 5, 13, 9, 0, 0, 0, time.UTC)
+		weekdays    = &Schedule{
+			Days: []string{"mon", "tue", "wed", "thu", "fri"},
+			From: "08:00",
+			To:   "20:00",
+		}
+	)
+
+	Describe("Awake", func() {
+		It("should be awake inside schedule", func() {
+			Expect(weekdays.Awake(monday9am)).To(BeTrue())
+		})
+
+		It("should sleep outside schedule hours", func() {
+			Expect(weekdays.Awake(monday10pm)).To(BeFalse())
+		})
+
+		It("should sleep outside schedule days", func() {
+			Expect(weekdays.Awake(saturday9am)).To(BeFalse())
+		})
+
+		It("should be awake every day if days are not informed", func() {
+			schedule := &Schedule{From: "08:00", To: "20:00"}
+			Expect(schedule.Awake(saturday9am)).To(BeTrue())
+		})
+
+		It("should use schedule timezone", func() {
+			schedule := &Schedule{From: "08:00", To: "20:00", Timezone: "America/Sao_Paulo"}
+			Expect(schedule.Awake(monday9am)).To(BeFalse())
+			Expect(schedule.Awake(monday10pm)).To(BeTrue())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("should return error with invalid time", func() {
+			schedule := &Schedule{From: "8am", To: "20:00"}
+			err := schedule.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("invalid schedule time, use HH:MM: 8am"))
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.YamlError"))
+		})
+
+		It("should return error with invalid day", func() {
+			schedule := &Schedule{Days: []string{"monday"}, From: "08:00", To: "20:00"}
+			err := schedule.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("invalid schedule day: monday"))
+		})
+
+		It("should return error if schedule ends before it starts", func() {
+			schedule := &Schedule{From: "20:00", To: "08:00"}
+			err := schedule.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("schedule must start before it ends: 20:00-08:00"))
+		})
+	})
+})
@@ -18,6 +18,7 @@ import (
 	"github.com/topfreegames/mystack-controller/errors"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/pkg/api"
+	k8serrors "k8s.io/client-go/pkg/api/errors"
 	"k8s.io/client-go/pkg/api/v1"
 )
 
@@ -63,8 +64,7 @@ type Service struct {
 }
 
 //NewService is the service ctor
-func NewService(name, username string, ports []*PortMap, isMystackSvc, isSocket bool) *Service {
-	namespace := usernameToNamespace(username)
+func NewService(name, namespace string, ports []*PortMap, isMystackSvc, isSocket bool) *Service {
 	for i, port := range ports {
 		if len(port.Name) == 0 {
 			port.Name = fmt.Sprintf("port-%d", i)
@@ -93,6 +93,91 @@ func NewService(name, username string, ports []*PortMap, isMystackSvc, isSocket
 
 //Expose exposes a deployment
 func (s *Service) Expose(clientset kubernetes.Interface) (*v1.Service, error) {
+	dst, err := s.build()
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := clientset.CoreV1().Services(s.Namespace).Create(dst)
+
+	if err != nil {
+		return nil, errors.NewKubernetesError("create service error", err)
+	}
+
+	return service, nil
+}
+
+//Apply creates the service if it doesn't exist or updates it
+//if the running one was built from a different config
+func (s *Service) Apply(clientset kubernetes.Interface) (string, error) {
+	existing, err := clientset.CoreV1().Services(s.Namespace).Get(s.Name)
+	if k8serrors.IsNotFound(err) {
+		_, err = s.Expose(clientset)
+		if err != nil {
+			return "", err
+		}
+		return ChangeCreated, nil
+	}
+	if err != nil {
+		return "", errors.NewKubernetesError("update service error", err)
+	}
+
+	//Socket ports are random, keep the ones users are already connected to
+	if socketPorts := existing.GetLabels()["mystack/socketPorts"]; s.IsSocket && socketPorts != "" {
+		s.SocketPorts = socketPorts
+	}
+
+	dst, err := s.build()
+	if err != nil {
+		return "", err
+	}
+
+	if configHash(existing.ObjectMeta) == configHash(dst.ObjectMeta) {
+		return ChangeUnchanged, nil
+	}
+
+	dst.ResourceVersion = existing.ResourceVersion
+	dst.Spec.ClusterIP = existing.Spec.ClusterIP
+	_, err = clientset.CoreV1().Services(s.Namespace).Update(dst)
+	if err != nil {
+		return "", errors.NewKubernetesError("update service error", err)
+	}
+
+	return ChangeUpdated, nil
+}
+
+//Ensure creates the service if it doesn't exist
+//An existing one is kept if it was built from the same config
+func (s *Service) Ensure(clientset kubernetes.Interface) (string, error) {
+	existing, err := clientset.CoreV1().Services(s.Namespace).Get(s.Name)
+	if k8serrors.IsNotFound(err) {
+		_, err = s.Expose(clientset)
+		if err != nil {
+			return "", err
+		}
+		return ChangeCreated, nil
+	}
+	if err != nil {
+		return "", errors.NewKubernetesError("create service error", err)
+	}
+
+	if socketPorts := existing.GetLabels()["mystack/socketPorts"]; s.IsSocket && socketPorts != "" {
+		s.SocketPorts = socketPorts
+	}
+
+	dst, err := s.build()
+	if err != nil {
+		return "", err
+	}
+
+	if configHash(existing.ObjectMeta) != configHash(dst.ObjectMeta) {
+		return "", differentConfigError("service", s.Name)
+	}
+
+	return ChangeUnchanged, nil
+}
+
+func (s *Service) build() (*v1.Service, error) {
 	tmpl, err := template.New("expose").Parse(serviceYaml)
 	if err != nil {
 		return nil, errors.NewYamlError("parse yaml error", err)
@@ -118,13 +203,9 @@ func (s *Service) Expose(clientset kubernetes.Interface) (*v1.Service, error) {
 		return nil, errors.NewYamlError("parse yaml error", err)
 	}
 
-	service, err := clientset.CoreV1().Services(s.Namespace).Create(dst)
-
-	if err != nil {
-		return nil, errors.NewKubernetesError("create service error", err)
-	}
+	setConfigHash(&dst.ObjectMeta, buf.Bytes())
 
-	return service, nil
+	return dst, nil
 }
 
 //Delete deletes service
@@ -140,8 +221,7 @@ func (s *Service) Delete(clientset kubernetes.Interface) error {
 }
 
 // ServicePort ...
-func ServicePort(clientset kubernetes.Interface, name, username string) (int, error) {
-	namespace := usernameToNamespace(username)
+func ServicePort(clientset kubernetes.Interface, name, namespace string) (int, error) {
 	service, err := clientset.CoreV1().Services(namespace).Get(name)
 	if err != nil {
 		return 0, err
@@ -24,7 +24,8 @@ var _ = Describe("Service", func() {
 	var (
 		clientset *fake.Clientset
 		name      = "test"
-		namespace = "mystack-user"
+		namespace = "mystack-user-stack-7f46a74a4d"
+		stack     = "stack"
 		username  = "user"
 		portMaps  = []*PortMap{
 			&PortMap{Port: 80, TargetPort: 5000},
@@ -42,7 +43,7 @@ var _ = Describe("Service", func() {
 
 	Describe("Expose", func() {
 		It("should expose a new Service", func() {
-			service := NewService(name, username, portMaps, false)
+			service := NewService(name, namespace, portMaps, false, false)
 			Expect(service.Namespace).To(Equal(namespace))
 
 			servicev1, err := service.Expose(clientset)
@@ -57,7 +58,7 @@ var _ = Describe("Service", func() {
 		})
 
 		It("should expose a new Service that is not mystack service", func() {
-			service := NewService(name, username, portMaps, true)
+			service := NewService(name, namespace, portMaps, true, false)
 			Expect(service.Namespace).To(Equal(namespace))
 
 			servicev1, err := service.Expose(clientset)
@@ -72,7 +73,7 @@ var _ = Describe("Service", func() {
 		})
 
 		It("should return error when creating same service twice", func() {
-			service := NewService(name, username, portMaps, false)
+			service := NewService(name, namespace, portMaps, false, false)
 			Expect(service.Namespace).To(Equal(namespace))
 
 			_, err := service.Expose(clientset)
@@ -87,13 +88,13 @@ var _ = Describe("Service", func() {
 
 	Describe("Delete", func() {
 		It("should return error if trying to delete unexposed service", func() {
-			service := NewService(name, username, portMaps, false)
+			service := NewService(name, namespace, portMaps, false, false)
 			err := service.Delete(clientset)
 			Expect(err).To(HaveOccurred())
 		})
 
 		It("should delete service", func() {
-			service := NewService(name, username, portMaps, false)
+			service := NewService(name, namespace, portMaps, false, false)
 			_, err := service.Expose(clientset)
 			Expect(err).NotTo(HaveOccurred())
 
@@ -106,11 +107,11 @@ var _ = Describe("Service", func() {
 		})
 
 		It("should not delete all services", func() {
-			service := NewService(name, username, portMaps, false)
+			service := NewService(name, namespace, portMaps, false, false)
 			_, err := service.Expose(clientset)
 			Expect(err).NotTo(HaveOccurred())
 
-			service2 := NewService("test2", username, portMaps, false)
+			service2 := NewService("test2", namespace, portMaps, false, false)
 			_, err = service2.Expose(clientset)
 			Expect(err).NotTo(HaveOccurred())
 
@@ -125,29 +126,29 @@ var _ = Describe("Service", func() {
 
 	Describe("ServicePort", func() {
 		It("should return error if namespace doesn't exist", func() {
-			_, err := ServicePort(clientset, name, username)
+			_, err := ServicePort(clientset, name, namespace)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(Equal(`Service "test" not found`))
 		})
 
 		It("should return error if service doesn't exist", func() {
-			err := CreateNamespace(clientset, username)
+			err := CreateNamespace(clientset, username, stack)
 			Expect(err).NotTo(HaveOccurred())
 
-			_, err = ServicePort(clientset, name, username)
+			_, err = ServicePort(clientset, name, namespace)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(Equal(`Service "test" not found`))
 		})
 
 		It("should return correct port", func() {
-			err := CreateNamespace(clientset, username)
+			err := CreateNamespace(clientset, username, stack)
 			Expect(err).NotTo(HaveOccurred())
 
-			service := NewService(name, username, portMaps, true)
+			service := NewService(name, namespace, portMaps, true, false)
 			_, err = service.Expose(clientset)
 			Expect(err).NotTo(HaveOccurred())
 
-			port, err := ServicePort(clientset, name, username)
+			port, err := ServicePort(clientset, name, namespace)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(port).To(Equal(80))
 		})
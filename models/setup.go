@@ -9,10 +9,11 @@ package models
 
 //Setup has the job config to run and configure services
 type Setup struct {
-	Image           string    `yaml:"image"`
-	ImagePullPolicy string    `yaml:"imagePullPolicy"`
-	Command         []string  `yaml:"command"`
-	PeriodSeconds   int       `yaml:"periodSeconds"`
-	Environment     []*EnvVar `yaml:"env"`
-	TimeoutSeconds  int       `yaml:"timeoutSeconds"`
+	Image             string    `yaml:"image"`
+	ImagePullPolicy   string    `yaml:"imagePullPolicy"`
+	Command           []string  `yaml:"command"`
+	PeriodSeconds     int       `yaml:"periodSeconds"`
+	Environment       []*EnvVar `yaml:"env"`
+	TimeoutSeconds    int       `yaml:"timeoutSeconds"`
+	WaitForCompletion bool      `yaml:"waitForCompletion"`
 }
@@ -0,0 +1,167 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/topfreegames/mystack-controller/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+//States the schedule puts a cluster in
+const (
+	ScheduleAwake  = "awake"
+	ScheduleAsleep = "asleep"
+)
+
+//deploymentLayers returns the cluster deployments in the order they start
+func (c *Cluster) deploymentLayers() [][]*Deployment {
+	layers := [][]*Deployment{}
+	for _, deployments := range [][]*Deployment{c.SvcDeployments, c.AppDeployments} {
+		layers = append(layers, linkLayers(deployments)...)
+	}
+
+	return layers
+}
+
+//Sleep scales every deployment of the cluster to zero, dependents first
+//Volumes are kept and setup jobs are not run again on Wake
+//It fails if a creation or another change of the cluster is running
+func (c *Cluster) Sleep(logger logrus.FieldLogger, clientset kubernetes.Interface) error {
+	if !NamespaceExists(clientset, c.Namespace) {
+		return errors.NewKubernetesError(
+			"sleep cluster error",
+			fmt.Errorf("namespace for user '%s' not found", c.Username),
+		)
+	}
+
+	return c.withLease(logger, clientset, func() error {
+		layers := c.deploymentLayers()
+		for i := len(layers) - 1; i >= 0; i-- {
+			for _, deployment := range layers[i] {
+				_, err := deployment.Sleep(clientset)
+				if err != nil {
+					if logger != nil {
+						logger.WithError(err).Errorf("failed to sleep deployment: %s", deployment.Name)
+					}
+					return err
+				}
+			}
+		}
+
+		err := annotateNamespace(clientset, c.Namespace, sleepingAnnotation, "true")
+		if err != nil {
+			return err
+		}
+		log(logger, "cluster is sleeping")
+
+		return nil
+	})
+}
+
+//Wake scales the deployments of the cluster back in link order
+//Each layer waits its links to be ready before starting
+//It fails if a creation or another change of the cluster is running
+func (c *Cluster) Wake(logger logrus.FieldLogger, clientset kubernetes.Interface) error {
+	if !NamespaceExists(clientset, c.Namespace) {
+		return errors.NewKubernetesError(
+			"wake cluster error",
+			fmt.Errorf("namespace for user '%s' not found", c.Username),
+		)
+	}
+
+	return c.withLease(logger, clientset, func() error {
+		for _, layer := range c.deploymentLayers() {
+			for _, deployment := range layer {
+				_, err := deployment.Wake(clientset)
+				if err != nil {
+					if logger != nil {
+						logger.WithError(err).Errorf("failed to wake deployment: %s", deployment.Name)
+					}
+					return err
+				}
+			}
+
+			if c.DeploymentReadiness != nil {
+				err := c.DeploymentReadiness.WaitForCompletion(clientset, layer)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		err := annotateNamespace(clientset, c.Namespace, sleepingAnnotation, "")
+		if err != nil {
+			return err
+		}
+		log(logger, "cluster is awake")
+
+		return nil
+	})
+}
+
+//IsSleeping returns true if the cluster was put to sleep and not woken yet
+func (c *Cluster) IsSleeping(clientset kubernetes.Interface) (bool, error) {
+	value, err := namespaceAnnotation(clientset, c.Namespace, sleepingAnnotation)
+	if err != nil {
+		return false, err
+	}
+
+	return value == "true", nil
+}
+
+//ApplySchedule sleeps or wakes the cluster when now crosses its schedule
+//Only changes of the scheduled state are applied, so a cluster woken or put
+//to sleep by hand stays that way until the next boundary
+//Returns the state applied or empty if nothing was done
+func (c *Cluster) ApplySchedule(
+	logger logrus.FieldLogger,
+	clientset kubernetes.Interface,
+	now time.Time,
+) (string, error) {
+	if c.Schedule == nil {
+		return "", nil
+	}
+
+	awake, err := c.Schedule.Awake(now)
+	if err != nil {
+		return "", err
+	}
+
+	state := ScheduleAsleep
+	if awake {
+		state = ScheduleAwake
+	}
+
+	lastState, err := namespaceAnnotation(clientset, c.Namespace, scheduleAnnotation)
+	if err != nil {
+		return "", err
+	}
+	if lastState == state {
+		return "", nil
+	}
+
+	if awake {
+		err = c.Wake(logger, clientset)
+	} else {
+		err = c.Sleep(logger, clientset)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	err = annotateNamespace(clientset, c.Namespace, scheduleAnnotation, state)
+	if err != nil {
+		return "", err
+	}
+
+	return state, nil
+}
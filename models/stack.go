@@ -0,0 +1,256 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/spf13/viper"
+	"github.com/topfreegames/mystack-controller/errors"
+)
+
+//States of a stack
+const (
+	StackCreating = "creating"
+	StackRunning  = "running"
+	StackFailed   = "failed"
+	StackDeleted  = "deleted"
+)
+
+//Stack is a cluster config deployed for a user
+//It keeps the exact yaml used, so later changes on the config don't affect it,
+//and the options requested on its creation
+type Stack struct {
+	ID          string    `db:"id" json:"id"`
+	OwnerEmail  string    `db:"owner_email" json:"ownerEmail"`
+	Username    string    `db:"username" json:"username"`
+	Namespace   string    `db:"namespace" json:"namespace"`
+	ClusterName string    `db:"cluster_name" json:"clusterName"`
+	Yaml        string    `db:"yaml" json:"yaml"`
+	State       string    `db:"state" json:"state"`
+	CreatedAt   time.Time `db:"created_at" json:"createdAt"`
+	UpdatedAt   time.Time `db:"updated_at" json:"updatedAt"`
+
+	OverridesJSON  string          `db:"overrides" json:"-"`
+	Overrides      Overrides       `db:"-" json:"overrides,omitempty"`
+	ParametersJSON string          `db:"parameters" json:"-"`
+	Parameters     ParameterValues `db:"-" json:"parameters,omitempty"`
+
+	db DB
+}
+
+//NewStack saves on DB that cluster is being created by ownerEmail
+func NewStack(db DB, ownerEmail string, cluster *Cluster) (*Stack, error) {
+	now := time.Now()
+	stack := &Stack{
+		ID:          uuid.NewV4().String(),
+		OwnerEmail:  ownerEmail,
+		Username:    cluster.Username,
+		Namespace:   cluster.Namespace,
+		ClusterName: cluster.ClusterName,
+		Yaml:        cluster.ConfigYaml,
+		State:       StackCreating,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Overrides:   cluster.Overrides,
+		Parameters:  cluster.Parameters,
+		db:          db,
+	}
+
+	query := `INSERT INTO stacks(id, owner_email, username, namespace, cluster_name, yaml, state, overrides, parameters)
+	VALUES(:id, :owner_email, :username, :namespace, :cluster_name, :yaml, :state, :overrides, :parameters)`
+	values := map[string]interface{}{
+		"id":           stack.ID,
+		"owner_email":  stack.OwnerEmail,
+		"username":     stack.Username,
+		"namespace":    stack.Namespace,
+		"cluster_name": stack.ClusterName,
+		"yaml":         stack.Yaml,
+		"state":        stack.State,
+		"overrides":    stack.Overrides.String(),
+		"parameters":   stack.Parameters.String(),
+	}
+	res, err := db.NamedExec(query, values)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, errors.NewDatabaseError(fmt.Errorf("couldn't insert on database"))
+	}
+
+	return stack, nil
+}
+
+//LoadStack reads from DB the stack running on namespace
+func LoadStack(db DB, namespace string) (*Stack, error) {
+	stack := &Stack{}
+	query := `SELECT id, owner_email, username, namespace, cluster_name, yaml, state, overrides, parameters, created_at, updated_at
+	FROM stacks
+	WHERE namespace = $1 AND state <> 'deleted'
+	ORDER BY created_at DESC
+	LIMIT 1`
+	err := db.Get(stack, query, namespace)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	err = stack.load(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return stack, nil
+}
+
+//StackFilter selects the stacks ListStacks returns
+//Empty fields match any stack, except deleted stacks that are only listed by state
+type StackFilter struct {
+	OwnerEmail  string
+	ClusterName string
+	State       string
+}
+
+//ListStacks reads from DB the stacks that match filter, newest first
+func ListStacks(db DB, filter *StackFilter) ([]*Stack, error) {
+	stacks := []*Stack{}
+	query := `SELECT id, owner_email, username, namespace, cluster_name, yaml, state, overrides, parameters, created_at, updated_at
+	FROM stacks
+	WHERE ($1 = '' OR owner_email = $1)
+	AND ($2 = '' OR cluster_name = $2)
+	AND (($3 = '' AND state <> 'deleted') OR state = $3)
+	ORDER BY created_at DESC`
+	err := db.Select(&stacks, query, filter.OwnerEmail, filter.ClusterName, filter.State)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	for _, stack := range stacks {
+		err = stack.load(db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return stacks, nil
+}
+
+//load parses the options of a stack read from DB
+func (s *Stack) load(db DB) error {
+	var err error
+	s.Overrides, err = ParseOverrides(s.OverridesJSON)
+	if err != nil {
+		return err
+	}
+
+	s.Parameters, err = ParseParameterValues(s.ParametersJSON)
+	if err != nil {
+		return err
+	}
+
+	s.db = db
+	return nil
+}
+
+//Options returns the options the stack was created with, nil for a nil stack
+func (s *Stack) Options() *ClusterOptions {
+	if s == nil {
+		return nil
+	}
+
+	return &ClusterOptions{
+		Overrides:  s.Overrides,
+		Parameters: s.Parameters,
+	}
+}
+
+//CheckOwner returns an AccessError if the stack wasn't created by ownerEmail
+//A nil stack has no owner to check
+func (s *Stack) CheckOwner(ownerEmail string) error {
+	if s == nil || s.OwnerEmail == ownerEmail {
+		return nil
+	}
+
+	return errors.NewAccessError(
+		"cluster access error",
+		fmt.Errorf("stack on namespace '%s' doesn't belong to %s", s.Namespace, ownerEmail),
+	)
+}
+
+//SetState saves on DB the new state of the stack
+func (s *Stack) SetState(state string) error {
+	s.State = state
+	return s.save()
+}
+
+//SetYaml saves on DB the config yaml the stack was updated to
+func (s *Stack) SetYaml(yamlStr string) error {
+	s.Yaml = yamlStr
+	return s.save()
+}
+
+func (s *Stack) save() error {
+	s.UpdatedAt = time.Now()
+
+	query := `UPDATE stacks
+	SET yaml = :yaml,
+			state = :state,
+			updated_at = :updated_at
+	WHERE id = :id`
+	values := map[string]interface{}{
+		"id":         s.ID,
+		"yaml":       s.Yaml,
+		"state":      s.State,
+		"updated_at": s.UpdatedAt,
+	}
+	_, err := s.db.NamedExec(query, values)
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+//RemoveStack saves on DB that the stack running on namespace was deleted
+func RemoveStack(db DB, namespace string) error {
+	query := `UPDATE stacks
+	SET state = :state,
+			updated_at = :updated_at
+	WHERE namespace = :namespace AND state <> 'deleted'`
+	values := map[string]interface{}{
+		"namespace":  namespace,
+		"state":      StackDeleted,
+		"updated_at": time.Now(),
+	}
+	_, err := db.NamedExec(query, values)
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+//NewDeployedCluster returns the cluster built from the yaml saved with its stack
+//Clusters created before stacks were saved use the current cluster config
+func NewDeployedCluster(
+	db DB,
+	username, clusterName string,
+	deploymentReadiness, jobReadiness Readiness,
+	config *viper.Viper,
+) (*Cluster, error) {
+	stack, err := LoadStack(db, ClusterNamespace(username, clusterName))
+	if err != nil && strings.Contains(err.Error(), "no rows in result set") {
+		return NewCluster(db, username, clusterName, deploymentReadiness, jobReadiness, config)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return NewClusterFromStack(stack, deploymentReadiness, jobReadiness, config)
+}
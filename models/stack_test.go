@@ -0,0 +1,205 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models_test
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/topfreegames/mystack-controller/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var _ = Describe("Stack", func() {
+	const (
+		clusterName = "myCustomApps"
+		username    = "user"
+		namespace   = "mystack-user-mycustomapps-a62c7ed6aa"
+		stackYaml   = `
+apps:
+  test1:
+    image: app1
+    port: 5000
+`
+	)
+
+	stackRows := func(yamlStr string) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{
+			"id", "owner_email", "username", "namespace", "cluster_name", "yaml", "state", "created_at", "updated_at",
+		}).AddRow(
+			"0b1a4c1e-6c4e-4f4b-a6c7-6f0e8d3b2a10", "user@example.com", username, namespace,
+			clusterName, yamlStr, StackRunning, time.Now(), time.Now(),
+		)
+	}
+
+	Describe("NewStack", func() {
+		It("should save stack with the cluster config yaml", func() {
+			mock.
+				ExpectExec("^INSERT INTO stacks(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			cluster := &Cluster{
+				Username:    username,
+				ClusterName: clusterName,
+				Namespace:   namespace,
+				ConfigYaml:  stackYaml,
+			}
+			stack, err := NewStack(sqlxDB, "user@example.com", cluster)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stack.ID).NotTo(BeEmpty())
+			Expect(stack.State).To(Equal(StackCreating))
+			Expect(stack.Yaml).To(Equal(stackYaml))
+			Expect(stack.OwnerEmail).To(Equal("user@example.com"))
+		})
+
+		It("should return error if insert fails", func() {
+			mock.
+				ExpectExec("^INSERT INTO stacks(.+)$").
+				WillReturnError(fmt.Errorf("connection refused"))
+
+			_, err := NewStack(sqlxDB, "user@example.com", &Cluster{})
+			Expect(err).To(HaveOccurred())
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.DatabaseError"))
+		})
+	})
+
+	Describe("LoadStack", func() {
+		It("should load running stack of namespace", func() {
+			mock.
+				ExpectQuery("^SELECT (.+) FROM stacks WHERE (.+)$").
+				WithArgs(namespace).
+				WillReturnRows(stackRows(stackYaml))
+
+			stack, err := LoadStack(sqlxDB, namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stack.ClusterName).To(Equal(clusterName))
+			Expect(stack.Yaml).To(Equal(stackYaml))
+		})
+
+		It("should check stack owner", func() {
+			mock.
+				ExpectQuery("^SELECT (.+) FROM stacks WHERE (.+)$").
+				WithArgs(namespace).
+				WillReturnRows(stackRows(stackYaml))
+
+			stack, err := LoadStack(sqlxDB, namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stack.CheckOwner("user@example.com")).To(Succeed())
+
+			err = stack.CheckOwner("user@other.com")
+			Expect(err).To(HaveOccurred())
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.AccessError"))
+		})
+
+		It("should update stack state", func() {
+			mock.
+				ExpectQuery("^SELECT (.+) FROM stacks WHERE (.+)$").
+				WithArgs(namespace).
+				WillReturnRows(stackRows(stackYaml))
+			mock.
+				ExpectExec("^UPDATE stacks(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			stack, err := LoadStack(sqlxDB, namespace)
+			Expect(err).NotTo(HaveOccurred())
+			err = stack.SetState(StackFailed)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stack.State).To(Equal(StackFailed))
+		})
+	})
+
+	Describe("Overrides", func() {
+		It("should build deployed cluster with the overrides saved on stack", func() {
+			mock.
+				ExpectQuery("^SELECT (.+) FROM stacks WHERE (.+)$").
+				WithArgs(namespace).
+				WillReturnRows(sqlmock.NewRows([]string{
+					"id", "owner_email", "username", "namespace", "cluster_name", "yaml", "state", "overrides", "created_at", "updated_at",
+				}).AddRow(
+					"0b1a4c1e-6c4e-4f4b-a6c7-6f0e8d3b2a10", "user@example.com", username, namespace,
+					clusterName, stackYaml, StackRunning, `{"test1":{"image":"app1:feature"}}`, time.Now(), time.Now(),
+				))
+
+			cluster, err := NewDeployedCluster(sqlxDB, username, clusterName, nil, nil, config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cluster.AppDeployments[0].Image).To(Equal("app1:feature"))
+			Expect(cluster.Overrides).To(HaveKey("test1"))
+		})
+	})
+
+	Describe("ListStacks", func() {
+		It("should list stacks matching filter", func() {
+			mock.
+				ExpectQuery("^SELECT (.+) FROM stacks WHERE (.+) ORDER BY created_at DESC$").
+				WithArgs("user@example.com", "", "").
+				WillReturnRows(stackRows(stackYaml))
+
+			stacks, err := ListStacks(sqlxDB, &StackFilter{OwnerEmail: "user@example.com"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stacks).To(HaveLen(1))
+			Expect(stacks[0].Namespace).To(Equal(namespace))
+			Expect(stacks[0].Yaml).To(Equal(stackYaml))
+		})
+
+		It("should return error if select fails", func() {
+			mock.
+				ExpectQuery("^SELECT (.+) FROM stacks WHERE (.+)$").
+				WithArgs("", clusterName, StackFailed).
+				WillReturnError(fmt.Errorf("connection refused"))
+
+			_, err := ListStacks(sqlxDB, &StackFilter{ClusterName: clusterName, State: StackFailed})
+			Expect(err).To(HaveOccurred())
+			Expect(fmt.Sprintf("%T", err)).To(Equal("*errors.DatabaseError"))
+		})
+	})
+
+	Describe("RemoveStack", func() {
+		It("should mark stack as deleted", func() {
+			mock.
+				ExpectExec("^UPDATE stacks(.+)$").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			err := RemoveStack(sqlxDB, namespace)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("NewDeployedCluster", func() {
+		It("should build cluster from the yaml saved on stack", func() {
+			mock.
+				ExpectQuery("^SELECT (.+) FROM stacks WHERE (.+)$").
+				WithArgs(namespace).
+				WillReturnRows(stackRows(stackYaml))
+
+			cluster, err := NewDeployedCluster(sqlxDB, username, clusterName, nil, nil, config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cluster.AppDeployments).To(HaveLen(1))
+			Expect(cluster.AppDeployments[0].Name).To(Equal("test1"))
+			Expect(cluster.ConfigYaml).To(Equal(stackYaml))
+		})
+
+		It("should use cluster config if there is no stack", func() {
+			mock.
+				ExpectQuery("^SELECT (.+) FROM stacks WHERE (.+)$").
+				WithArgs(namespace).
+				WillReturnError(fmt.Errorf("sql: no rows in result set"))
+			mock.
+				ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+				WithArgs(clusterName).
+				WillReturnRows(sqlmock.NewRows([]string{"yaml"}).AddRow(stackYaml))
+
+			cluster, err := NewDeployedCluster(sqlxDB, username, clusterName, nil, nil, config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cluster.AppDeployments).To(HaveLen(1))
+		})
+	})
+})
@@ -0,0 +1,285 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/topfreegames/mystack-controller/errors"
+	"k8s.io/client-go/kubernetes"
+	k8serrors "k8s.io/client-go/pkg/api/errors"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/pkg/fields"
+	"k8s.io/client-go/pkg/labels"
+)
+
+//States reported for apps, services and jobs
+const (
+	StateMissing     = "missing"
+	StateReady       = "ready"
+	StateProgressing = "progressing"
+	StateFailing     = "failing"
+	StatePending     = "pending"
+	StateRunning     = "running"
+	StateSucceeded   = "succeeded"
+	StateFailed      = "failed"
+)
+
+//failingReasons are container waiting reasons that won't recover by themselves
+var failingReasons = map[string]bool{
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CrashLoopBackOff":           true,
+	"CreateContainerConfigError": true,
+}
+
+//ContainerStatus reports the state of a container inside a pod
+type ContainerStatus struct {
+	Name           string `json:"name"`
+	Ready          bool   `json:"ready"`
+	RestartCount   int32  `json:"restartCount"`
+	WaitingReason  string `json:"waitingReason,omitempty"`
+	WaitingMessage string `json:"waitingMessage,omitempty"`
+}
+
+//PodStatus reports the state of a pod of an app, service or job
+type PodStatus struct {
+	Name       string             `json:"name"`
+	Phase      string             `json:"phase"`
+	Restarts   int32              `json:"restarts"`
+	Containers []*ContainerStatus `json:"containers"`
+}
+
+//AppStatus reports the state of the deployment of an app or service
+type AppStatus struct {
+	State             string       `json:"state"`
+	DesiredReplicas   int32        `json:"desiredReplicas"`
+	AvailableReplicas int32        `json:"availableReplicas"`
+	Pods              []*PodStatus `json:"pods"`
+}
+
+//JobStatus reports the state of the setup and post-setup jobs
+type JobStatus struct {
+	State     string       `json:"state"`
+	Active    int32        `json:"active"`
+	Succeeded int32        `json:"succeeded"`
+	Failed    int32        `json:"failed"`
+	Pods      []*PodStatus `json:"pods"`
+}
+
+//ClusterStatus reports the state of every app, service and job of a cluster
+type ClusterStatus struct {
+	Apps       map[string]*AppStatus `json:"apps"`
+	Services   map[string]*AppStatus `json:"services"`
+	Jobs       map[string]*JobStatus `json:"jobs"`
+	ExpiresAt  *time.Time            `json:"expiresAt,omitempty"`
+	Sleeping   bool                  `json:"sleeping"`
+	FailedStep string                `json:"failedStep,omitempty"`
+}
+
+//Status returns the live status of the cluster apps, services and jobs
+func (c *Cluster) Status(clientset kubernetes.Interface) (*ClusterStatus, error) {
+	if !NamespaceExists(clientset, c.Namespace) {
+		return nil, errors.NewKubernetesError(
+			"get status error",
+			fmt.Errorf("namespace for user '%s' not found", c.Username),
+		)
+	}
+
+	pods, err := c.podsByApp(clientset)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt, err := ClusterExpiration(clientset, c.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	sleeping, err := c.IsSleeping(clientset)
+	if err != nil {
+		return nil, err
+	}
+
+	failedStep, err := FailedStep(clientset, c.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &ClusterStatus{
+		Apps:       make(map[string]*AppStatus),
+		Services:   make(map[string]*AppStatus),
+		Jobs:       make(map[string]*JobStatus),
+		ExpiresAt:  expiresAt,
+		Sleeping:   sleeping,
+		FailedStep: failedStep,
+	}
+
+	for _, deployment := range c.AppDeployments {
+		status.Apps[deployment.Name], err = deploymentStatus(clientset, deployment, pods[deployment.Name])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, deployment := range c.SvcDeployments {
+		status.Services[deployment.Name], err = deploymentStatus(clientset, deployment, pods[deployment.Name])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, job := range []*Job{c.Job, c.PostJob} {
+		if job == nil {
+			continue
+		}
+
+		status.Jobs[job.Name], err = jobStatus(clientset, job, pods[job.Name])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return status, nil
+}
+
+//podsByApp groups the pods owned by the cluster user by its app label
+func (c *Cluster) podsByApp(clientset kubernetes.Interface) (map[string][]*PodStatus, error) {
+	labelMap := labels.Set{"mystack/owner": c.Username}
+	listOptions := v1.ListOptions{
+		LabelSelector: labelMap.AsSelector().String(),
+		FieldSelector: fields.Everything().String(),
+	}
+
+	pods, err := clientset.CoreV1().Pods(c.Namespace).List(listOptions)
+	if err != nil {
+		return nil, errors.NewKubernetesError("get status error", err)
+	}
+
+	podsByApp := make(map[string][]*PodStatus)
+	for _, pod := range pods.Items {
+		app := pod.GetLabels()["app"]
+		podsByApp[app] = append(podsByApp[app], newPodStatus(&pod))
+	}
+
+	return podsByApp, nil
+}
+
+func newPodStatus(pod *v1.Pod) *PodStatus {
+	podStatus := &PodStatus{
+		Name:       pod.Name,
+		Phase:      string(pod.Status.Phase),
+		Containers: []*ContainerStatus{},
+	}
+
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		container := &ContainerStatus{
+			Name:         containerStatus.Name,
+			Ready:        containerStatus.Ready,
+			RestartCount: containerStatus.RestartCount,
+		}
+		if waiting := containerStatus.State.Waiting; waiting != nil {
+			container.WaitingReason = waiting.Reason
+			container.WaitingMessage = waiting.Message
+		}
+
+		podStatus.Restarts += containerStatus.RestartCount
+		podStatus.Containers = append(podStatus.Containers, container)
+	}
+
+	return podStatus
+}
+
+func isFailing(pods []*PodStatus) bool {
+	for _, pod := range pods {
+		for _, container := range pod.Containers {
+			if failingReasons[container.WaitingReason] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func deploymentStatus(
+	clientset kubernetes.Interface,
+	deployment *Deployment,
+	pods []*PodStatus,
+) (*AppStatus, error) {
+	if pods == nil {
+		pods = []*PodStatus{}
+	}
+
+	k8sDeploy, err := clientset.ExtensionsV1beta1().Deployments(deployment.Namespace).Get(deployment.Name)
+	if k8serrors.IsNotFound(err) {
+		return &AppStatus{State: StateMissing, Pods: pods}, nil
+	}
+	if err != nil {
+		return nil, errors.NewKubernetesError("get status error", err)
+	}
+
+	appStatus := &AppStatus{
+		AvailableReplicas: k8sDeploy.Status.AvailableReplicas,
+		Pods:              pods,
+	}
+	if k8sDeploy.Spec.Replicas != nil {
+		appStatus.DesiredReplicas = *k8sDeploy.Spec.Replicas
+	}
+
+	switch {
+	case isFailing(pods):
+		appStatus.State = StateFailing
+	case appStatus.AvailableReplicas >= appStatus.DesiredReplicas:
+		appStatus.State = StateReady
+	default:
+		appStatus.State = StateProgressing
+	}
+
+	return appStatus, nil
+}
+
+func jobStatus(
+	clientset kubernetes.Interface,
+	job *Job,
+	pods []*PodStatus,
+) (*JobStatus, error) {
+	if pods == nil {
+		pods = []*PodStatus{}
+	}
+
+	k8sJob, err := clientset.BatchV1().Jobs(job.Namespace).Get(job.Name)
+	if k8serrors.IsNotFound(err) {
+		return &JobStatus{State: StateMissing, Pods: pods}, nil
+	}
+	if err != nil {
+		return nil, errors.NewKubernetesError("get status error", err)
+	}
+
+	status := &JobStatus{
+		Active:    k8sJob.Status.Active,
+		Succeeded: k8sJob.Status.Succeeded,
+		Failed:    k8sJob.Status.Failed,
+		Pods:      pods,
+	}
+
+	switch {
+	case status.Succeeded > 0:
+		status.State = StateSucceeded
+	case status.Failed > 0 && status.Active == 0, isFailing(pods):
+		status.State = StateFailed
+	case status.Active > 0:
+		status.State = StateRunning
+	default:
+		status.State = StatePending
+	}
+
+	return status, nil
+}
@@ -0,0 +1,118 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models_test
+
+import (
+	. "github.com/topfreegames/mystack-controller/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mTest "github.com/topfreegames/mystack-controller/testing"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+var _ = Describe("Status", func() {
+	var (
+		username  = "user"
+		namespace = "mystack-user-stack-7f46a74a4d"
+		stack     = "stack"
+		clientset *fake.Clientset
+		cluster   *Cluster
+	)
+
+	newPod := func(name, app string, waitingReason string, restarts int32) *v1.Pod {
+		state := v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+		if waitingReason != "" {
+			state = v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: waitingReason}}
+		}
+
+		return &v1.Pod{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels: map[string]string{
+					"app":           app,
+					"mystack/owner": username,
+				},
+			},
+			Status: v1.PodStatus{
+				Phase: v1.PodRunning,
+				ContainerStatuses: []v1.ContainerStatus{
+					{Name: app, State: state, RestartCount: restarts},
+				},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		clientset = fake.NewSimpleClientset()
+
+		app := NewDeployment("app1", username, namespace, "app1", []int{5000}, nil, nil, nil, nil, nil, config)
+		svc := NewDeployment("svc1", username, namespace, "svc1", []int{5000}, nil, nil, nil, nil, nil, config)
+		cluster = &Cluster{
+			Username:       username,
+			ClusterName:    stack,
+			Namespace:      namespace,
+			AppDeployments: []*Deployment{app},
+			SvcDeployments: []*Deployment{svc},
+			K8sServices: map[*Deployment]*Service{
+				app: NewService("app1", namespace, []*PortMap{{Port: 5000, TargetPort: 5000}}, false, false),
+				svc: NewService("svc1", namespace, []*PortMap{{Port: 5000, TargetPort: 5000}}, true, false),
+			},
+			Job:                 NewJob("setup", username, namespace, &Setup{Image: "setup-img"}, []*EnvVar{}),
+			DeploymentReadiness: &mTest.MockReadiness{},
+			JobReadiness:        &mTest.MockReadiness{},
+		}
+	})
+
+	It("should report apps, services and jobs", func() {
+		err := cluster.Create(nil, clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = clientset.CoreV1().Pods(namespace).Create(newPod("app1-1234", "app1", "CrashLoopBackOff", 3))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = clientset.CoreV1().Pods(namespace).Create(newPod("svc1-1234", "svc1", "", 0))
+		Expect(err).NotTo(HaveOccurred())
+
+		status, err := cluster.Status(clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(status.Apps).To(HaveKey("app1"))
+		Expect(status.Apps["app1"].State).To(Equal(StateFailing))
+		Expect(status.Apps["app1"].DesiredReplicas).To(BeEquivalentTo(1))
+		Expect(status.Apps["app1"].Pods).To(HaveLen(1))
+		Expect(status.Apps["app1"].Pods[0].Restarts).To(BeEquivalentTo(3))
+		Expect(status.Apps["app1"].Pods[0].Containers[0].WaitingReason).To(Equal("CrashLoopBackOff"))
+
+		Expect(status.Services).To(HaveKey("svc1"))
+		Expect(status.Services["svc1"].State).To(Equal(StateProgressing))
+		Expect(status.Services["svc1"].Pods[0].Phase).To(Equal("Running"))
+
+		Expect(status.Jobs).To(HaveKey("setup"))
+		Expect(status.Jobs["setup"].State).To(Equal(StatePending))
+	})
+
+	It("should report missing deployments", func() {
+		err := CreateNamespace(clientset, username, stack)
+		Expect(err).NotTo(HaveOccurred())
+
+		status, err := cluster.Status(clientset)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Apps["app1"].State).To(Equal(StateMissing))
+		Expect(status.Jobs["setup"].State).To(Equal(StateMissing))
+	})
+
+	It("should return error if cluster is not running", func() {
+		_, err := cluster.Status(clientset)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("namespace for user 'user' not found"))
+	})
+})
@@ -31,4 +31,5 @@ type ClusterAppConfig struct {
 	Links           []string     `yaml:"links"`
 	Resources       *Resources   `yaml:"resources"`
 	IsSocket        bool         `yaml:"isSocket"`
+	Replicas        int          `yaml:"replicas"`
 }
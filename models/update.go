@@ -0,0 +1,253 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/topfreegames/mystack-controller/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+//Changes applied to a k8s object when updating a cluster
+const (
+	ChangeCreated   = "created"
+	ChangeUpdated   = "updated"
+	ChangeUnchanged = "unchanged"
+	ChangeSkipped   = "skipped"
+	ChangeDeleted   = "deleted"
+)
+
+const configHashAnnotation = "mystack/config-hash"
+
+//ClusterUpdate lists the k8s objects changed by an update, as kind/name
+type ClusterUpdate struct {
+	Created   []string `json:"created"`
+	Updated   []string `json:"updated"`
+	Unchanged []string `json:"unchanged"`
+	Skipped   []string `json:"skipped"`
+	Deleted   []string `json:"deleted"`
+}
+
+func newClusterUpdate() *ClusterUpdate {
+	return &ClusterUpdate{
+		Created:   []string{},
+		Updated:   []string{},
+		Unchanged: []string{},
+		Skipped:   []string{},
+		Deleted:   []string{},
+	}
+}
+
+func (u *ClusterUpdate) add(kind, name, change string) {
+	object := fmt.Sprintf("%s/%s", kind, name)
+	switch change {
+	case ChangeCreated:
+		u.Created = append(u.Created, object)
+	case ChangeUpdated:
+		u.Updated = append(u.Updated, object)
+	case ChangeUnchanged:
+		u.Unchanged = append(u.Unchanged, object)
+	case ChangeSkipped:
+		u.Skipped = append(u.Skipped, object)
+	case ChangeDeleted:
+		u.Deleted = append(u.Deleted, object)
+	}
+}
+
+//setConfigHash annotates the object with the hash of the yaml it was built from
+func setConfigHash(meta *v1.ObjectMeta, yaml []byte) {
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[configHashAnnotation] = fmt.Sprintf("%x", sha256.Sum256(yaml))
+}
+
+func configHash(meta v1.ObjectMeta) string {
+	return meta.Annotations[configHashAnnotation]
+}
+
+//differentConfigError is returned when creating an object that exists
+//but was built from another config
+func differentConfigError(kind, name string) error {
+	return errors.NewKubernetesError(
+		"create cluster error",
+		fmt.Errorf("%s '%s' already exists with a different config", kind, name),
+	)
+}
+
+//Update changes the running cluster to match its config
+//Only the objects that differ are created, updated or deleted,
+//setup jobs are not run again and volumes are kept
+//It fails if a creation or another change of the cluster is running
+func (c *Cluster) Update(logger logrus.FieldLogger, clientset kubernetes.Interface) (*ClusterUpdate, error) {
+	if !NamespaceExists(clientset, c.Namespace) {
+		return nil, errors.NewKubernetesError(
+			"update cluster error",
+			fmt.Errorf("namespace for user '%s' not found", c.Username),
+		)
+	}
+
+	var update *ClusterUpdate
+	err := c.withLease(logger, clientset, func() error {
+		var err error
+		update, err = c.update(logger, clientset)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return update, nil
+}
+
+func (c *Cluster) update(logger logrus.FieldLogger, clientset kubernetes.Interface) (*ClusterUpdate, error) {
+	update := newClusterUpdate()
+
+	log(logger, "updating volumes")
+	for _, pvc := range c.PersistentVolumeClaims {
+		change, err := pvc.Apply(clientset)
+		if err != nil {
+			return nil, err
+		}
+		update.add("pvc", pvc.Name, change)
+	}
+
+	for _, deployments := range [][]*Deployment{c.SvcDeployments, c.AppDeployments} {
+		for _, layer := range linkLayers(deployments) {
+			err := c.applyDeploymentsAndItsServices(logger, clientset, layer, update)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err := c.deleteRemoved(logger, clientset, update)
+	if err != nil {
+		return nil, err
+	}
+
+	return update, nil
+}
+
+func (c *Cluster) applyDeploymentsAndItsServices(
+	logger logrus.FieldLogger,
+	clientset kubernetes.Interface,
+	deployments []*Deployment,
+	update *ClusterUpdate,
+) error {
+	log(logger, "updating deployments")
+	changed := []*Deployment{}
+	for _, deployment := range deployments {
+		change, err := deployment.Apply(clientset)
+		if err != nil {
+			if logger != nil {
+				logger.WithError(err).Errorf("failed to update deployment: %s", deployment.Name)
+			}
+			return err
+		}
+		update.add("deployment", deployment.Name, change)
+
+		if change != ChangeUnchanged {
+			changed = append(changed, deployment)
+		}
+	}
+
+	if len(changed) > 0 {
+		log(logger, "waiting deployment completion")
+		err := c.DeploymentReadiness.WaitForCompletion(clientset, changed)
+		if err != nil {
+			return err
+		}
+	}
+
+	log(logger, "updating services")
+	for _, deployment := range deployments {
+		service := c.K8sServices[deployment]
+		change, err := service.Apply(clientset)
+		if err != nil {
+			if logger != nil {
+				logger.WithError(err).Errorf("failed to update service: %s", deployment.Name)
+			}
+			return err
+		}
+		update.add("service", service.Name, change)
+	}
+
+	return nil
+}
+
+//deleteRemoved deletes the objects on namespace that are no longer on cluster config
+func (c *Cluster) deleteRemoved(
+	logger logrus.FieldLogger,
+	clientset kubernetes.Interface,
+	update *ClusterUpdate,
+) error {
+	log(logger, "deleting removed objects")
+
+	wanted := make(map[string]bool)
+	for _, deployments := range [][]*Deployment{c.SvcDeployments, c.AppDeployments} {
+		for _, deployment := range deployments {
+			wanted[deployment.Name] = true
+		}
+	}
+	wantedVolumes := make(map[string]bool)
+	for _, pvc := range c.PersistentVolumeClaims {
+		wantedVolumes[pvc.Name] = true
+	}
+
+	services, err := clientset.CoreV1().Services(c.Namespace).List(listOptions)
+	if err != nil {
+		return errors.NewKubernetesError("update cluster error", err)
+	}
+	for _, service := range services.Items {
+		if wanted[service.Name] {
+			continue
+		}
+		s := &Service{Name: service.Name, Namespace: c.Namespace}
+		if err := s.Delete(clientset); err != nil {
+			return err
+		}
+		update.add("service", service.Name, ChangeDeleted)
+	}
+
+	deployments, err := clientset.ExtensionsV1beta1().Deployments(c.Namespace).List(listOptions)
+	if err != nil {
+		return errors.NewKubernetesError("update cluster error", err)
+	}
+	for _, deployment := range deployments.Items {
+		if wanted[deployment.Name] {
+			continue
+		}
+		d := &Deployment{Name: deployment.Name, Namespace: c.Namespace}
+		if err := d.Delete(clientset); err != nil {
+			return err
+		}
+		update.add("deployment", deployment.Name, ChangeDeleted)
+	}
+
+	pvcs, err := clientset.CoreV1().PersistentVolumeClaims(c.Namespace).List(listOptions)
+	if err != nil {
+		return errors.NewKubernetesError("update cluster error", err)
+	}
+	for _, pvc := range pvcs.Items {
+		if wantedVolumes[pvc.Name] {
+			continue
+		}
+		p := &PersistentVolumeClaim{Name: pvc.Name, Namespace: c.Namespace}
+		if err := p.Delete(clientset); err != nil {
+			return err
+		}
+		update.add("pvc", pvc.Name, ChangeDeleted)
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models_test
+
+import (
+	. "github.com/topfreegames/mystack-controller/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mTest "github.com/topfreegames/mystack-controller/testing"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/pkg/fields"
+	"k8s.io/client-go/pkg/labels"
+)
+
+var _ = Describe("Update", func() {
+	var (
+		username    = "user"
+		namespace   = "mystack-user-stack-7f46a74a4d"
+		stack       = "stack"
+		clientset   *fake.Clientset
+		labelMap    = labels.Set{"mystack/routable": "true"}
+		listOptions = v1.ListOptions{
+			LabelSelector: labelMap.AsSelector().String(),
+			FieldSelector: fields.Everything().String(),
+		}
+	)
+
+	newCluster := func(apps map[string]string) *Cluster {
+		svc := NewDeployment("svc1", username, namespace, "svc1", []int{5000}, nil, nil, &VolumeMount{Name: "svc-volume", MountPath: "/data"}, nil, nil, config)
+		cluster := &Cluster{
+			Username:    username,
+			ClusterName: stack,
+			Namespace:   namespace,
+			PersistentVolumeClaims: []*PersistentVolumeClaim{
+				NewPVC("svc-volume", namespace, "1Gi"),
+			},
+			SvcDeployments: []*Deployment{svc},
+			K8sServices: map[*Deployment]*Service{
+				svc: NewService("svc1", namespace, []*PortMap{{Port: 5000, TargetPort: 5000}}, true, false),
+			},
+			DeploymentReadiness: &mTest.MockReadiness{},
+			JobReadiness:        &mTest.MockReadiness{},
+		}
+
+		for name, image := range apps {
+			app := NewDeployment(name, username, namespace, image, []int{5000}, nil, nil, nil, nil, nil, config)
+			app.Links = []*Deployment{}
+			cluster.AppDeployments = append(cluster.AppDeployments, app)
+			cluster.K8sServices[app] = NewService(name, namespace, []*PortMap{{Port: 5000, TargetPort: 5000}}, false, false)
+		}
+
+		return cluster
+	}
+
+	BeforeEach(func() {
+		clientset = fake.NewSimpleClientset()
+	})
+
+	It("should not change anything if config is the same", func() {
+		err := newCluster(map[string]string{"app1": "app1"}).Create(nil, clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		update, err := newCluster(map[string]string{"app1": "app1"}).Update(nil, clientset)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(update.Created).To(BeEmpty())
+		Expect(update.Updated).To(BeEmpty())
+		Expect(update.Deleted).To(BeEmpty())
+		Expect(update.Unchanged).To(ConsistOf(
+			"pvc/svc-volume",
+			"deployment/svc1", "service/svc1",
+			"deployment/app1", "service/app1",
+		))
+	})
+
+	It("should create, update and delete only what changed", func() {
+		err := newCluster(map[string]string{"app1": "app1", "app2": "app2"}).Create(nil, clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		update, err := newCluster(map[string]string{"app1": "app1:v2", "app3": "app3"}).Update(nil, clientset)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(update.Created).To(ConsistOf("deployment/app3", "service/app3"))
+		Expect(update.Updated).To(ConsistOf("deployment/app1"))
+		Expect(update.Deleted).To(ConsistOf("deployment/app2", "service/app2"))
+
+		deploys, err := clientset.ExtensionsV1beta1().Deployments(namespace).List(listOptions)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deploys.Items).To(HaveLen(3))
+
+		deploy, err := clientset.ExtensionsV1beta1().Deployments(namespace).Get("app1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deploy.Spec.Template.Spec.Containers[0].Image).To(Equal("app1:v2"))
+
+		volumes, err := clientset.CoreV1().PersistentVolumeClaims(namespace).List(listOptions)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(volumes.Items).To(HaveLen(1))
+	})
+
+	It("should keep replicas of scaled deployments", func() {
+		cluster := newCluster(map[string]string{"app1": "app1"})
+		err := cluster.Create(nil, clientset)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = cluster.AppDeployments[0].Scale(clientset, 3)
+		Expect(err).NotTo(HaveOccurred())
+
+		update, err := newCluster(map[string]string{"app1": "app1:v2"}).Update(nil, clientset)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(update.Updated).To(ConsistOf("deployment/app1"))
+
+		deploy, err := clientset.ExtensionsV1beta1().Deployments(namespace).Get("app1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deploy.Spec.Template.Spec.Containers[0].Image).To(Equal("app1:v2"))
+		Expect(*deploy.Spec.Replicas).To(BeEquivalentTo(3))
+	})
+
+	It("should keep sleeping deployments asleep", func() {
+		cluster := newCluster(map[string]string{"app1": "app1"})
+		err := cluster.Create(nil, clientset)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = cluster.AppDeployments[0].Scale(clientset, 2)
+		Expect(err).NotTo(HaveOccurred())
+		err = cluster.Sleep(nil, clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = newCluster(map[string]string{"app1": "app1:v2"}).Update(nil, clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		deploy, err := clientset.ExtensionsV1beta1().Deployments(namespace).Get("app1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*deploy.Spec.Replicas).To(BeEquivalentTo(0))
+		Expect(deploy.Annotations).To(HaveKeyWithValue("mystack/replicas", "2"))
+
+		err = cluster.Wake(nil, clientset)
+		Expect(err).NotTo(HaveOccurred())
+		deploy, err = clientset.ExtensionsV1beta1().Deployments(namespace).Get("app1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*deploy.Spec.Replicas).To(BeEquivalentTo(2))
+	})
+
+	It("should return error if cluster is being created", func() {
+		err := newCluster(map[string]string{"app1": "app1"}).Create(nil, clientset)
+		Expect(err).NotTo(HaveOccurred())
+		err = newCluster(nil).Claim(clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = newCluster(map[string]string{"app1": "app1:v2"}).Update(nil, clientset)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("namespace for user 'user' already exists and is being created"))
+
+		deploy, err := clientset.ExtensionsV1beta1().Deployments(namespace).Get("app1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deploy.Spec.Template.Spec.Containers[0].Image).To(Equal("app1"))
+	})
+
+	It("should release the lease when it finishes", func() {
+		err := newCluster(nil).Create(nil, clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = newCluster(nil).Update(nil, clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		running, err := CreationRunning(clientset, namespace)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(running).To(BeFalse())
+	})
+
+	It("should keep volumes whose config changed", func() {
+		err := newCluster(nil).Create(nil, clientset)
+		Expect(err).NotTo(HaveOccurred())
+
+		cluster := newCluster(nil)
+		cluster.PersistentVolumeClaims[0].Storage = "2Gi"
+		update, err := cluster.Update(nil, clientset)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(update.Skipped).To(ConsistOf("pvc/svc-volume"))
+	})
+
+	It("should return error if cluster is not running", func() {
+		_, err := newCluster(nil).Update(nil, clientset)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("namespace for user 'user' not found"))
+	})
+})
@@ -0,0 +1,299 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+	"github.com/topfreegames/mystack-controller/errors"
+	"k8s.io/client-go/pkg/api/resource"
+)
+
+//validationName is the fake user cluster configs are built for when validated,
+//also used as the name of configs validated before they are saved
+const validationName = "validation"
+
+//ConfigProblem is something wrong found on a cluster config
+//Path is where it is on the yaml, like apps.app1.ports[0], empty if on the whole config
+type ConfigProblem struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+	err     error
+}
+
+func newConfigProblem(path string, err error) *ConfigProblem {
+	return &ConfigProblem{
+		Path:    path,
+		Message: err.Error(),
+		err:     err,
+	}
+}
+
+func (p *ConfigProblem) String() string {
+	if len(p.Path) == 0 {
+		return p.Message
+	}
+	return fmt.Sprintf("%s: %s", p.Path, p.Message)
+}
+
+//configProblemsError returns the problems as a single error, nil if there is none
+//A single problem keeps the error that found it
+func configProblemsError(problems []*ConfigProblem) error {
+	if len(problems) == 0 {
+		return nil
+	}
+
+	if len(problems) == 1 {
+		if _, ok := problems[0].err.(errors.SerializableError); ok {
+			return problems[0].err
+		}
+	}
+
+	messages := make([]string, len(problems))
+	for i, problem := range problems {
+		messages[i] = problem.String()
+	}
+
+	return errors.NewYamlErrorWithDetails(
+		"invalid cluster config",
+		fmt.Errorf("%s", strings.Join(messages, "; ")),
+		problems,
+	)
+}
+
+//ValidateClusterConfig builds yamlStr for a fake user as NewCluster does,
+//rendering and decoding every Kubernetes object, and returns all problems found
+//Parameters without values are rendered as their zero values
+//The error is only returned if the configs yamlStr extends can't be read
+func ValidateClusterConfig(
+	ctx context.Context,
+	db Queryer,
+	clusterName, yamlStr string,
+	config *viper.Viper,
+) ([]*ConfigProblem, error) {
+	if len(clusterName) == 0 {
+		clusterName = validationName
+	}
+
+	if len(yamlStr) == 0 {
+		err := errors.NewYamlError("write cluster config error", fmt.Errorf("invalid empty config"))
+		return []*ConfigProblem{newConfigProblem("", err)}, nil
+	}
+
+	resolved, err := resolveExtends(ctx, db, yamlStr, []string{clusterName})
+	if _, ok := err.(*errors.DatabaseError); ok {
+		return nil, err
+	} else if err != nil {
+		return []*ConfigProblem{newConfigProblem("extends", err)}, nil
+	}
+
+	rendered, err := render(resolved, nil, false)
+	if err != nil {
+		return []*ConfigProblem{newConfigProblem("parameters", err)}, nil
+	}
+
+	clusterConfig, err := ParseYaml(rendered)
+	if err != nil {
+		return []*ConfigProblem{newConfigProblem("", err)}, nil
+	}
+
+	v := &configValidator{clusterConfig: clusterConfig, problems: []*ConfigProblem{}}
+	v.validateApps("services", clusterConfig.Services)
+	v.validateApps("apps", clusterConfig.Apps)
+	v.validateSetup("setup", clusterConfig.Setup)
+	v.validateSetup("postSetup", clusterConfig.PostSetup)
+	v.validateVolumes()
+
+	if _, err := clusterConfig.GetTTL(nil); err != nil {
+		v.add("ttl", err)
+	}
+	if clusterConfig.Schedule != nil {
+		if err := clusterConfig.Schedule.Validate(); err != nil {
+			v.add("schedule", err)
+		}
+	}
+
+	if len(v.problems) > 0 {
+		return v.problems, nil
+	}
+
+	err = clusterConfig.ValidateGraph()
+	if err != nil {
+		return []*ConfigProblem{newConfigProblem("", err)}, nil
+	}
+
+	v.validateObjects(resolved, clusterName, config)
+
+	return v.problems, nil
+}
+
+type configValidator struct {
+	clusterConfig *ClusterConfig
+	problems      []*ConfigProblem
+}
+
+func (v *configValidator) add(path string, err error) {
+	v.problems = append(v.problems, newConfigProblem(path, err))
+}
+
+//validateApps checks the apps or services on section
+func (v *configValidator) validateApps(section string, configs map[string]*ClusterAppConfig) {
+	names := []string{}
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := fmt.Sprintf("%s.%s", section, name)
+		appConfig := configs[name]
+		if appConfig == nil {
+			v.add(path, fmt.Errorf("'%s' has no config", name))
+			continue
+		}
+
+		if len(appConfig.Image) == 0 {
+			v.add(path+".image", fmt.Errorf("image is required"))
+		}
+
+		for i, port := range appConfig.Ports {
+			_, err := getPorts(name, []string{port}, map[string][]*PortMap{})
+			if err != nil || len(strings.Split(port, ":")) > 2 {
+				v.add(fmt.Sprintf("%s.ports[%d]", path, i), fmt.Errorf("invalid port '%s'", port))
+			}
+		}
+
+		for i, link := range appConfig.Links {
+			linkPath := fmt.Sprintf("%s.links[%d]", path, i)
+			switch v.clusterConfig.checkLink(section == "services", link) {
+			case linkToApp:
+				v.add(linkPath, fmt.Errorf("services can't link to app '%s'", link))
+			case linkUnknown:
+				v.add(linkPath, fmt.Errorf("unknown link '%s'", link))
+			}
+		}
+
+		if appConfig.VolumeMount != nil {
+			if !v.hasVolume(appConfig.VolumeMount.Name) {
+				v.add(path+".volumeMount.name", fmt.Errorf("unknown volume '%s'", appConfig.VolumeMount.Name))
+			}
+			if len(appConfig.VolumeMount.MountPath) == 0 {
+				v.add(path+".volumeMount.mountPath", fmt.Errorf("mountPath is required"))
+			}
+		}
+
+		if appConfig.Resources != nil {
+			v.validateResource(path+".resources.limits", appConfig.Resources.Limits)
+			v.validateResource(path+".resources.requests", appConfig.Resources.Requests)
+		}
+	}
+}
+
+func (v *configValidator) hasVolume(name string) bool {
+	for _, volume := range v.clusterConfig.Volumes {
+		if volume != nil && volume.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *configValidator) validateResource(path string, r *MemoryAndCPUResource) {
+	if r == nil {
+		return
+	}
+
+	v.validateQuantity(path+".cpu", r.CPU)
+	v.validateQuantity(path+".memory", r.Memory)
+}
+
+func (v *configValidator) validateQuantity(path, quantity string) {
+	if len(quantity) == 0 {
+		return
+	}
+
+	if _, err := resource.ParseQuantity(quantity); err != nil {
+		v.add(path, fmt.Errorf("invalid quantity '%s'", quantity))
+	}
+}
+
+func (v *configValidator) validateSetup(section string, setup *Setup) {
+	if setup != nil && len(setup.Image) == 0 {
+		v.add(section+".image", fmt.Errorf("image is required"))
+	}
+}
+
+func (v *configValidator) validateVolumes() {
+	for i, volume := range v.clusterConfig.Volumes {
+		path := fmt.Sprintf("volumes[%d]", i)
+		if volume == nil {
+			v.add(path, fmt.Errorf("volume has no config"))
+			continue
+		}
+
+		if len(volume.Name) == 0 {
+			v.add(path+".name", fmt.Errorf("name is required"))
+		}
+
+		if len(volume.Storage) == 0 {
+			v.add(path+".storage", fmt.Errorf("storage is required"))
+		} else {
+			v.validateQuantity(path+".storage", volume.Storage)
+		}
+	}
+}
+
+//validateObjects builds the cluster for the fake user
+//and decodes the Kubernetes objects it would create
+func (v *configValidator) validateObjects(yamlStr, clusterName string, config *viper.Viper) {
+	cluster, err := newClusterFromYaml(yamlStr, nil, false, validationName, clusterName, nil, nil, config)
+	if err != nil {
+		v.add("", err)
+		return
+	}
+
+	for _, kind := range []struct {
+		section     string
+		deployments []*Deployment
+	}{{"services", cluster.SvcDeployments}, {"apps", cluster.AppDeployments}} {
+		deployments := append([]*Deployment{}, kind.deployments...)
+		sort.Slice(deployments, func(i, j int) bool { return deployments[i].Name < deployments[j].Name })
+
+		for _, deployment := range deployments {
+			path := fmt.Sprintf("%s.%s", kind.section, deployment.Name)
+			if _, err := deployment.build(); err != nil {
+				v.add(path, err)
+			}
+			if _, err := cluster.K8sServices[deployment].build(); err != nil {
+				v.add(path+".ports", err)
+			}
+		}
+	}
+
+	for _, job := range []struct {
+		section string
+		job     *Job
+	}{{"setup", cluster.Job}, {"postSetup", cluster.PostJob}} {
+		if job.job == nil {
+			continue
+		}
+		if _, err := job.job.build(); err != nil {
+			v.add(job.section, err)
+		}
+	}
+
+	for i, pvc := range cluster.PersistentVolumeClaims {
+		if _, err := pvc.build(); err != nil {
+			v.add(fmt.Sprintf("volumes[%d]", i), err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// mystack-controller api
+// +build unit
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package models_test
+
+import (
+	"context"
+
+	. "github.com/topfreegames/mystack-controller/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var _ = Describe("ValidateClusterConfig", func() {
+	validate := func(yamlStr string) []string {
+		problems, err := ValidateClusterConfig(context.Background(), sqlxDB, "", yamlStr, config)
+		Expect(err).NotTo(HaveOccurred())
+
+		found := make([]string, len(problems))
+		for i, problem := range problems {
+			found[i] = problem.String()
+		}
+		return found
+	}
+
+	It("should return no problem for valid config", func() {
+		Expect(validate(yamlWithVolume)).To(BeEmpty())
+	})
+
+	It("should render required parameters as their zero values", func() {
+		Expect(validate(`
+parameters:
+  replicas:
+    type: int
+apps:
+  app1:
+    image: app1
+    replicas: {{ .replicas }}
+`)).To(BeEmpty())
+	})
+
+	It("should return every problem with its yaml path", func() {
+		problems := validate(`
+volumes:
+  - name: data
+    storage: lots
+services:
+  postgres:
+    image: postgres
+    links:
+      - app1
+    volumeMount:
+      name: postgres-data
+      mountPath: /data
+apps:
+  app1:
+    image: app1
+    ports:
+      - 5000:5001:5002
+    resources:
+      limits:
+        cpu: fast
+`)
+		Expect(problems).To(Equal([]string{
+			"services.postgres.links[0]: services can't link to app 'app1'",
+			"services.postgres.volumeMount.name: unknown volume 'postgres-data'",
+			"apps.app1.ports[0]: invalid port '5000:5001:5002'",
+			"apps.app1.resources.limits.cpu: invalid quantity 'fast'",
+			"volumes[0].storage: invalid quantity 'lots'",
+		}))
+	})
+
+	It("should return problem decoding Kubernetes objects", func() {
+		problems := validate(`
+apps:
+  app1:
+    image: app1
+    env:
+      - name: OBJ
+        value: "{\"key\": \"value\"}"
+`)
+		Expect(problems).To(HaveLen(1))
+		Expect(problems[0]).To(HavePrefix("apps.app1: "))
+	})
+
+	It("should return invalid yaml as problem of whole config", func() {
+		problems := validate("apps: [")
+		Expect(problems).To(HaveLen(1))
+		Expect(problems[0]).To(HavePrefix("yaml: line 1"))
+	})
+
+	It("should return problem if extended config doesn't exist", func() {
+		mock.
+			ExpectQuery("^SELECT yaml FROM clusters WHERE name = (.+)$").
+			WithArgs("base").
+			WillReturnRows(sqlmock.NewRows([]string{"yaml"}))
+
+		problems := validate("extends: base\n")
+		Expect(problems).To(Equal([]string{
+			"extends: cluster config 'base' extended by 'validation' not found",
+		}))
+	})
+})
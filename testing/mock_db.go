@@ -0,0 +1,69 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package testing
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/topfreegames/mystack-controller/models"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+const savepoint = "mystack_transaction"
+
+//MockDB implements models.DB over a sqlmock connection
+//Transactions are mocked with ExpectBegin, ExpectCommit and ExpectRollback
+type MockDB struct {
+	*sqlx.DB
+}
+
+//NewMockDB returns a MockDB and the sqlmock to set its expected queries
+func NewMockDB() (*MockDB, sqlmock.Sqlmock, error) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &MockDB{sqlx.NewDb(db, "postgres")}, mock, nil
+}
+
+//BeginTransaction begins a transaction on the mocked connection
+func (m *MockDB) BeginTransaction(ctx context.Context) (models.Tx, error) {
+	return m.BeginTxx(ctx, nil)
+}
+
+//TxDB implements models.DB inside a transaction that is rolled back after each test
+//Transactions begun on it are savepoints of the outer transaction
+type TxDB struct {
+	*sqlx.Tx
+}
+
+//BeginTransaction creates a savepoint on the outer transaction
+func (t *TxDB) BeginTransaction(ctx context.Context) (models.Tx, error) {
+	_, err := t.ExecContext(ctx, "SAVEPOINT "+savepoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &txSavepoint{t.Tx}, nil
+}
+
+type txSavepoint struct {
+	*sqlx.Tx
+}
+
+func (s *txSavepoint) Commit() error {
+	_, err := s.Exec("RELEASE SAVEPOINT " + savepoint)
+	return err
+}
+
+func (s *txSavepoint) Rollback() error {
+	_, err := s.Exec("ROLLBACK TO SAVEPOINT " + savepoint)
+	return err
+}
@@ -0,0 +1,60 @@
+// mystack-controller api
+// https://github.com/topfreegames/mystack-controller
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2017 Top Free Games <backend@tfgco.com>
+
+package testing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/topfreegames/mystack-controller/models"
+)
+
+//MockExecutor implements Executor interface
+//Exec connects to a local websocket server that runs Handler instead of the pod command
+//The server is started on the first Exec and stopped by Close
+type MockExecutor struct {
+	Handler func(conn *websocket.Conn)
+	Pod     string
+	Options *models.ExecOptions
+
+	server *httptest.Server
+}
+
+//Exec records the pod and options and dials the local server
+func (m *MockExecutor) Exec(namespace, pod string, options *models.ExecOptions) (*websocket.Conn, error) {
+	m.Pod = pod
+	m.Options = options
+
+	if m.server == nil {
+		upgrader := websocket.Upgrader{}
+		m.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			m.Handler(conn)
+		}))
+	}
+
+	url := "ws" + strings.TrimPrefix(m.server.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	return conn, err
+}
+
+//Close stops the local server, if Exec started it
+func (m *MockExecutor) Close() {
+	if m.server == nil {
+		return
+	}
+
+	m.server.Close()
+	m.server = nil
+}